@@ -6,12 +6,14 @@
 package govcloudair
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
 
 	types "github.com/vmware/govcloudair/types/v56"
 )
@@ -74,3 +76,59 @@ func checkResp(resp *http.Response, err error) (*http.Response, error) {
 		return nil, fmt.Errorf("unhandled API response, please report this issue, status code: %s", resp.Status)
 	}
 }
+
+// executeTaskRequest sends payload, marshaled as XML with the given content
+// type, to the URL and decodes the Task returned by the API. A nil payload
+// sends a request without a body.
+func executeTaskRequest(c Client, method string, u *url.URL, contentType string, payload interface{}) (Task, error) {
+
+	task := NewTask(c)
+
+	if err := executeRequest(c, method, u, contentType, payload, task.Task); err != nil {
+		return Task{}, err
+	}
+
+	return *task, nil
+}
+
+// executeRequest sends payload, marshaled as XML with the given content type,
+// to the URL and decodes the response into out. A nil payload sends a request
+// without a body, a nil out ignores the body of the response.
+func executeRequest(c Client, method string, u *url.URL, contentType string, payload, out interface{}) error {
+
+	var body io.Reader
+	if payload != nil {
+		output, err := xml.MarshalIndent(payload, "  ", "    ")
+		if err != nil {
+			return fmt.Errorf("error marshaling request: %s", err)
+		}
+
+		if os.Getenv("GOVCLOUDAIR_DEBUG") == "true" {
+			fmt.Printf("\n\nXML DEBUG: %s\n\n", string(output))
+		}
+
+		body = bytes.NewBufferString(xml.Header + string(output))
+	}
+
+	req := c.NewRequest(map[string]string{}, method, u, body)
+
+	if contentType != "" {
+		req.Header.Add("Content-Type", contentType)
+	}
+
+	resp, err := checkResp(c.DoHTTP(req))
+	if err != nil {
+		return err
+	}
+
+	if out == nil {
+		resp.Body.Close()
+		return nil
+	}
+
+	if err = decodeBody(resp, out); err != nil {
+		return fmt.Errorf("error decoding response: %s", err)
+	}
+
+	return nil
+}
@@ -8,6 +8,7 @@
 package govcloudair
 
 import (
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -60,6 +61,18 @@ func testHandler(responses map[string]testResponse, callCount *callCounter) http
 	})
 }
 
+// testMethodHandler serves the responses of the request method by path and,
+// when submitted isn't nil, keeps the body of every request but GETs by
+// method and path
+func testMethodHandler(responses map[string]map[string]testResponse, submitted map[string][]byte, callCount *callCounter) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if submitted != nil && r.Method != "GET" {
+			submitted[r.Method+" "+r.URL.Path], _ = ioutil.ReadAll(r.Body)
+		}
+		testHandler(responses[r.Method], callCount).ServeHTTP(rw, r)
+	})
+}
+
 var authRequests = map[string]testResponse{
 	"/api/vchs/sessions":                                                                                            {201, authheader, vaauthorization},
 	"/api/vchs/services":                                                                                            {200, nil, vaservices},
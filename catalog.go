@@ -7,6 +7,10 @@ package govcloudair
 import (
 	"fmt"
 	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	types "github.com/vmware/govcloudair/types/v56"
 )
@@ -58,3 +62,275 @@ func (c *Catalog) FindCatalogItem(catalogitem string) (CatalogItem, error) {
 
 	return CatalogItem{}, fmt.Errorf("can't find catalog item: %s", catalogitem)
 }
+
+// catalogItems refreshes this catalog and retrieves its items whose name
+// matches
+func (c *Catalog) catalogItems(match func(name string) bool) ([]CatalogItem, error) {
+
+	if err := c.Refresh(); err != nil {
+		return nil, err
+	}
+
+	var items []CatalogItem
+	for _, cis := range c.Catalog.CatalogItems {
+		for _, ref := range cis.CatalogItem {
+			if ref.Type != types.MimeCatalogItem || !match(ref.Name) {
+				continue
+			}
+
+			item := NewCatalogItem(c.c)
+			item.CatalogItem.HREF = ref.HREF
+			if err := item.Refresh(); err != nil {
+				return nil, err
+			}
+
+			items = append(items, *item)
+		}
+	}
+
+	return items, nil
+}
+
+// ListCatalogItems refreshes this catalog and retrieves all its items, along
+// with their creation date, version number and size
+func (c *Catalog) ListCatalogItems() ([]CatalogItem, error) {
+	return c.catalogItems(func(string) bool { return true })
+}
+
+// latestCatalogItem returns the most recently created of items, items created
+// at the same time are ordered by name
+func latestCatalogItem(items []CatalogItem) (CatalogItem, error) {
+
+	created := make(map[string]time.Time, len(items))
+	for _, item := range items {
+		t, err := time.Parse(time.RFC3339, item.CatalogItem.DateCreated)
+		if err != nil {
+			return CatalogItem{}, fmt.Errorf("error parsing creation date of catalog item %s: %s", item.CatalogItem.Name, err)
+		}
+		created[item.CatalogItem.HREF] = t
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		ti, tj := created[items[i].CatalogItem.HREF], created[items[j].CatalogItem.HREF]
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return items[i].CatalogItem.Name < items[j].CatalogItem.Name
+	})
+
+	return items[len(items)-1], nil
+}
+
+// FindLatestCatalogItem retrieves the most recently created item of this
+// catalog whose name matches pattern. Among items created at the same time
+// the one with the greatest name wins.
+func (c *Catalog) FindLatestCatalogItem(pattern *regexp.Regexp) (CatalogItem, error) {
+
+	items, err := c.catalogItems(pattern.MatchString)
+	if err != nil {
+		return CatalogItem{}, err
+	}
+
+	if len(items) == 0 {
+		return CatalogItem{}, fmt.Errorf("can't find catalog item matching: %s", pattern)
+	}
+
+	return latestCatalogItem(items)
+}
+
+// FindLatestCatalogItemByPrefix retrieves the most recently created item of
+// this catalog whose name starts with prefix
+func (c *Catalog) FindLatestCatalogItemByPrefix(prefix string) (CatalogItem, error) {
+	return c.FindLatestCatalogItem(regexp.MustCompile("^" + regexp.QuoteMeta(prefix)))
+}
+
+// FindCatalogItemByMetadata retrieves the most recently created item of this
+// catalog with the metadata entry key set to value
+func (c *Catalog) FindCatalogItemByMetadata(key, value string) (CatalogItem, error) {
+
+	items, err := c.ListCatalogItems()
+	if err != nil {
+		return CatalogItem{}, err
+	}
+
+	var matching []CatalogItem
+	for _, item := range items {
+		metadata, err := item.GetMetadata()
+		if err != nil {
+			return CatalogItem{}, err
+		}
+
+		if v, ok := metadataValue(metadata, key); ok && v == value {
+			matching = append(matching, item)
+		}
+	}
+
+	if len(matching) == 0 {
+		return CatalogItem{}, fmt.Errorf("can't find catalog item with metadata: %s=%s", key, value)
+	}
+
+	return latestCatalogItem(matching)
+}
+
+// Refresh retrieves this catalog again
+func (c *Catalog) Refresh() error {
+
+	if c.Catalog.HREF == "" {
+		return fmt.Errorf("cannot refresh, Object is empty")
+	}
+
+	u, _ := url.ParseRequestURI(c.Catalog.HREF)
+
+	req := c.c.NewRequest(map[string]string{}, types.HTTPGet, u, nil)
+
+	resp, err := checkResp(c.c.DoHTTP(req))
+	if err != nil {
+		return fmt.Errorf("error retrieving catalog: %s", err)
+	}
+
+	// Empty struct before a new unmarshal, otherwise we end up with duplicate
+	// elements in slices.
+	c.Catalog = &types.Catalog{}
+
+	if err = decodeBody(resp, c.Catalog); err != nil {
+		return fmt.Errorf("error decoding catalog response: %s", err)
+	}
+
+	// The request was successful
+	return nil
+}
+
+// adminURL returns the URL of the link of this catalog with the given rel,
+// or the admin URL of the catalog when there is no such link
+func (c *Catalog) adminURL(rel string) (*url.URL, error) {
+
+	if l := c.Catalog.Link.Find(func(l *types.Link) bool { return l.Rel == rel }); l != nil {
+		return url.ParseRequestURI(l.HREF)
+	}
+
+	s, err := url.ParseRequestURI(c.Catalog.HREF)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing catalog href: %s", err)
+	}
+	s.Path = strings.Replace(s.Path, "/api/catalog/", "/api/admin/catalog/", 1)
+
+	return s, nil
+}
+
+// Update changes the name and description of this catalog
+func (c *Catalog) Update(name, description string) error {
+
+	if name == "" {
+		return fmt.Errorf("catalog needs a name")
+	}
+
+	s, err := c.adminURL(types.RelEdit)
+	if err != nil {
+		return err
+	}
+
+	params := &types.AdminCatalog{
+		Xmlns:       types.NsVCloud,
+		Name:        name,
+		Description: description,
+		IsPublished: c.Catalog.IsPublished,
+	}
+
+	updated := new(types.AdminCatalog)
+
+	if err = executeRequest(c.c, types.HTTPPut, s, types.MimeAdminCatalog, params, updated); err != nil {
+		return fmt.Errorf("error updating catalog: %s", err)
+	}
+
+	c.Catalog.Name = updated.Name
+	c.Catalog.Description = updated.Description
+
+	// The request was successful
+	return nil
+}
+
+// Delete deletes this catalog, which must be empty
+func (c *Catalog) Delete() error {
+
+	s, err := c.adminURL(types.RelRemove)
+	if err != nil {
+		return err
+	}
+
+	if err = executeRequest(c.c, types.HTTPDelete, s, "", nil, nil); err != nil {
+		return fmt.Errorf("error deleting catalog: %s", err)
+	}
+
+	// The request was successful
+	return nil
+}
+
+// Publish makes this catalog available to all the organizations of the cloud
+// when published is true, and only to its own organization otherwise
+func (c *Catalog) Publish(published bool) error {
+
+	s, err := c.adminURL(types.RelPublish)
+	if err != nil {
+		return err
+	}
+	if !strings.HasSuffix(s.Path, "/action/publish") {
+		s.Path += "/action/publish"
+	}
+
+	params := &types.PublishCatalogParams{
+		Xmlns:       types.NsVCloud,
+		IsPublished: published,
+	}
+
+	if err = executeRequest(c.c, types.HTTPPost, s, types.MimePublishCatalogParams, params, nil); err != nil {
+		return fmt.Errorf("error publishing catalog: %s", err)
+	}
+
+	c.Catalog.IsPublished = published
+
+	// The request was successful
+	return nil
+}
+
+// IsPublished tells whether this catalog is available to all the
+// organizations of the cloud, as of the last retrieval
+func (c *Catalog) IsPublished() bool {
+	return c.Catalog.IsPublished
+}
+
+// Owner returns the user owning this catalog. The owner is retrieved when the
+// catalog doesn't include it.
+func (c *Catalog) Owner() (*types.Reference, error) {
+
+	if c.Catalog.Owner != nil && c.Catalog.Owner.User != nil {
+		return c.Catalog.Owner.User, nil
+	}
+
+	s, err := url.ParseRequestURI(c.Catalog.HREF)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing catalog href: %s", err)
+	}
+	s.Path += "/owner"
+
+	req := c.c.NewRequest(map[string]string{}, types.HTTPGet, s, nil)
+
+	resp, err := checkResp(c.c.DoHTTP(req))
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving catalog owner: %s", err)
+	}
+
+	owner := new(types.Owner)
+
+	if err = decodeBody(resp, owner); err != nil {
+		return nil, fmt.Errorf("error decoding catalog owner response: %s", err)
+	}
+
+	if owner.User == nil {
+		return nil, fmt.Errorf("can't find the owner of catalog: %s", c.Catalog.Name)
+	}
+
+	c.Catalog.Owner = owner
+
+	// The request was successful
+	return owner.User, nil
+}
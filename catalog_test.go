@@ -5,9 +5,13 @@
 package govcloudair
 
 import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
 )
 
 var catalogResponses = map[string]testResponse{
@@ -40,6 +44,194 @@ func Test_FindCatalogItem(t *testing.T) {
 	}
 }
 
+// catalogAdminHandler serves the catalog management requests and keeps the
+// body of each request by method and path
+func catalogAdminHandler(submitted map[string][]byte, cc *callCounter) http.Handler {
+	responses := map[string]testResponse{
+		"/api/org/11111111-1111-1111-1111-111111111111":                                                                   {200, nil, orgExample},
+		"/api/catalog/e8a20fdf-8a78-440c-ac71-0420db59f854":                                                               {200, nil, catalogExample},
+		"/api/catalog/e8a20fdf-8a78-440c-ac71-0420db59f854/owner":                                                         {200, nil, catalogOwnerExample},
+		"/api/admin/org/23bd2339-c55f-403c-baf3-13109e8c8d57/catalogs":                                                    {201, nil, adminCatalogCreatedExample},
+		"/api/admin/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4":                                                         {200, nil, adminCatalogExample},
+		"/api/admin/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4/action/publish":                                          {204, nil, ""},
+		"/api/org/23bd2339-c55f-403c-baf3-13109e8c8d57/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4/action/controlAccess": {200, nil, controlAccessExample},
+		"/api/org/23bd2339-c55f-403c-baf3-13109e8c8d57/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4/controlAccess/":       {200, nil, controlAccessExample},
+	}
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			testHandler(map[string]testResponse{
+				"/api/admin/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4": {204, nil, ""},
+			}, cc).ServeHTTP(rw, r)
+			return
+		}
+		if r.Method != "GET" {
+			submitted[r.Method+" "+r.URL.Path], _ = ioutil.ReadAll(r.Body)
+		}
+		testHandler(responses, cc).ServeHTTP(rw, r)
+	})
+}
+
+func Test_CatalogLifecycle(t *testing.T) {
+	cc := new(callCounter)
+	submitted := make(map[string][]byte)
+
+	ctx, err := setupTestContext(authHandler(catalogAdminHandler(submitted, cc)))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	org, err := ctx.VDC.GetVDCOrg()
+	if !assert.NoError(t, err) || !assert.Equal(t, 1, cc.Pop()) {
+		return
+	}
+
+	_, _, err = org.CreateCatalog("", "no name")
+	assert.Error(t, err)
+
+	cat, task, err := org.CreateCatalog("Golden", "Curated images")
+	if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+		params := new(types.AdminCatalog)
+		if assert.NoError(t, xml.Unmarshal(submitted["POST /api/admin/org/23bd2339-c55f-403c-baf3-13109e8c8d57/catalogs"], params)) {
+			assert.Equal(t, "Golden", params.Name)
+			assert.Equal(t, "Curated images", params.Description)
+		}
+		assert.Equal(t, ctx.Server.URL+"/api/admin/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4", cat.Catalog.HREF)
+		assert.Equal(t, ctx.Server.URL+"/api/task/1b8f926c-eff5-4bea-9b13-4e49bdd50c05", task.Task.HREF)
+		assert.False(t, cat.IsPublished())
+
+		owner, err := cat.Owner()
+		if assert.NoError(t, err) && assert.Equal(t, 0, cc.Pop()) {
+			assert.Equal(t, "admin", owner.Name)
+		}
+	}
+
+	if assert.NoError(t, cat.Update("Golden Images", "Curated golden images")) && assert.Equal(t, 1, cc.Pop()) {
+		params := new(types.AdminCatalog)
+		if assert.NoError(t, xml.Unmarshal(submitted["PUT /api/admin/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4"], params)) {
+			assert.Equal(t, "Golden Images", params.Name)
+			assert.Equal(t, "Curated golden images", params.Description)
+		}
+		assert.Equal(t, "Golden Images", cat.Catalog.Name)
+	}
+
+	if assert.NoError(t, cat.Publish(true)) && assert.Equal(t, 1, cc.Pop()) {
+		params := new(types.PublishCatalogParams)
+		if assert.NoError(t, xml.Unmarshal(submitted["POST /api/admin/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4/action/publish"], params)) {
+			assert.True(t, params.IsPublished)
+		}
+		assert.True(t, cat.IsPublished())
+	}
+
+	assert.Error(t, org.ShareCatalog(cat, "Everything"))
+	access, err := org.GetCatalogAccess(cat)
+	if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) && assert.NotNil(t, access.AccessSettings) {
+		assert.Equal(t, "jdoe", access.AccessSettings.AccessSetting[0].Subject.Name)
+	}
+
+	// the access of individual users is kept
+	if assert.NoError(t, org.ShareCatalog(cat, types.AccessLevelChange)) && assert.Equal(t, 2, cc.Pop()) {
+		params := new(types.ControlAccessParams)
+		if assert.NoError(t, xml.Unmarshal(submitted["POST /api/org/23bd2339-c55f-403c-baf3-13109e8c8d57/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4/action/controlAccess"], params)) {
+			assert.True(t, params.IsSharedToEveryone)
+			assert.Equal(t, types.AccessLevelChange, params.EveryoneAccessLevel)
+			if assert.NotNil(t, params.AccessSettings) && assert.Len(t, params.AccessSettings.AccessSetting, 1) {
+				assert.Equal(t, "jdoe", params.AccessSettings.AccessSetting[0].Subject.Name)
+				assert.Equal(t, types.AccessLevelFullControl, params.AccessSettings.AccessSetting[0].AccessLevel)
+			}
+		}
+	}
+
+	assert.Error(t, org.SetCatalogAccess(cat, &types.ControlAccessParams{
+		AccessSettings: &types.AccessSettings{AccessSetting: []*types.AccessSetting{{AccessLevel: "Everything"}}},
+	}))
+	if assert.NoError(t, org.SetCatalogAccess(cat, &types.ControlAccessParams{})) && assert.Equal(t, 1, cc.Pop()) {
+		params := new(types.ControlAccessParams)
+		if assert.NoError(t, xml.Unmarshal(submitted["POST /api/org/23bd2339-c55f-403c-baf3-13109e8c8d57/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4/action/controlAccess"], params)) {
+			assert.False(t, params.IsSharedToEveryone)
+			assert.Nil(t, params.AccessSettings)
+		}
+	}
+
+	assert.NoError(t, cat.Delete())
+	assert.Equal(t, 1, cc.Pop())
+
+	public, err := org.FindCatalog("Public Catalog")
+	if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+		public.Catalog.Description = ""
+		if assert.NoError(t, public.Refresh()) && assert.Equal(t, 1, cc.Pop()) {
+			assert.Equal(t, "vCHS service catalog", public.Catalog.Description)
+			assert.True(t, public.IsPublished())
+		}
+
+		owner, err := public.Owner()
+		if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+			assert.Equal(t, "system", owner.Name)
+		}
+	}
+}
+
+var adminCatalogCreatedExample = `
+	<?xml version="1.0" encoding="UTF-8"?>
+	<AdminCatalog xmlns="http://www.vmware.com/vcloud/v1.5" name="Golden" id="urn:vcloud:catalog:7212e451-76e1-4631-b2de-ba1dfd8080e4" type="application/vnd.vmware.admin.catalog+xml" href="http://localhost:4444/api/admin/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4">
+		<Link rel="up" type="application/vnd.vmware.admin.organization+xml" href="http://localhost:4444/api/admin/org/23bd2339-c55f-403c-baf3-13109e8c8d57"/>
+		<Link rel="alternate" type="application/vnd.vmware.vcloud.catalog+xml" href="http://localhost:4444/api/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4"/>
+		<Description>Curated images</Description>
+		<Tasks>
+			<Task cancelRequested="false" expiryTime="2014-09-14T12:40:23.353-07:00" operation="Creating Catalog Golden(7212e451-76e1-4631-b2de-ba1dfd8080e4)" operationName="catalogCreateCatalog" serviceNamespace="com.vmware.vcloud" startTime="2014-06-16T12:40:23.353-07:00" status="running" name="task" id="urn:vcloud:task:1b8f926c-eff5-4bea-9b13-4e49bdd50c05" type="application/vnd.vmware.vcloud.task+xml" href="http://localhost:4444/api/task/1b8f926c-eff5-4bea-9b13-4e49bdd50c05">
+				<Owner type="application/vnd.vmware.admin.catalog+xml" name="Golden" href="http://localhost:4444/api/admin/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4"/>
+			</Task>
+		</Tasks>
+		<Owner type="application/vnd.vmware.vcloud.owner+xml">
+			<User type="application/vnd.vmware.admin.user+xml" name="admin" href="http://localhost:4444/api/admin/user/d8ac278a-5b49-4c85-9a81-468838e89eb9"/>
+		</Owner>
+		<CatalogItems/>
+		<IsPublished>false</IsPublished>
+		<DateCreated>2014-06-16T12:40:23.343-07:00</DateCreated>
+		<VersionNumber>0</VersionNumber>
+	</AdminCatalog>
+`
+
+var adminCatalogExample = `
+	<?xml version="1.0" encoding="UTF-8"?>
+	<AdminCatalog xmlns="http://www.vmware.com/vcloud/v1.5" name="Golden Images" id="urn:vcloud:catalog:7212e451-76e1-4631-b2de-ba1dfd8080e4" type="application/vnd.vmware.admin.catalog+xml" href="http://localhost:4444/api/admin/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4">
+		<Link rel="up" type="application/vnd.vmware.admin.organization+xml" href="http://localhost:4444/api/admin/org/23bd2339-c55f-403c-baf3-13109e8c8d57"/>
+		<Link rel="edit" type="application/vnd.vmware.admin.catalog+xml" href="http://localhost:4444/api/admin/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4"/>
+		<Link rel="remove" href="http://localhost:4444/api/admin/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4"/>
+		<Link rel="publish" type="application/vnd.vmware.admin.publishCatalogParams+xml" href="http://localhost:4444/api/admin/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4/action/publish"/>
+		<Description>Curated golden images</Description>
+		<Owner type="application/vnd.vmware.vcloud.owner+xml">
+			<User type="application/vnd.vmware.admin.user+xml" name="admin" href="http://localhost:4444/api/admin/user/d8ac278a-5b49-4c85-9a81-468838e89eb9"/>
+		</Owner>
+		<CatalogItems/>
+		<IsPublished>false</IsPublished>
+		<DateCreated>2014-06-16T12:40:23.343-07:00</DateCreated>
+		<VersionNumber>1</VersionNumber>
+	</AdminCatalog>
+`
+
+var catalogOwnerExample = `
+	<?xml version="1.0" encoding="UTF-8"?>
+	<Owner xmlns="http://www.vmware.com/vcloud/v1.5" type="application/vnd.vmware.vcloud.owner+xml" href="http://localhost:4444/api/catalog/e8a20fdf-8a78-440c-ac71-0420db59f854/owner">
+		<Link rel="up" type="application/vnd.vmware.vcloud.catalog+xml" href="http://localhost:4444/api/catalog/e8a20fdf-8a78-440c-ac71-0420db59f854"/>
+		<User type="application/vnd.vmware.admin.user+xml" name="system" href="http://localhost:4444/api/admin/user/5b2b5a8c-3c3f-4e4f-a4e1-0b9e6d4d4a6c"/>
+	</Owner>
+`
+
+var controlAccessExample = `
+	<?xml version="1.0" encoding="UTF-8"?>
+	<ControlAccessParams xmlns="http://www.vmware.com/vcloud/v1.5">
+		<IsSharedToEveryone>true</IsSharedToEveryone>
+		<EveryoneAccessLevel>ReadOnly</EveryoneAccessLevel>
+		<AccessSettings>
+			<AccessSetting>
+				<Subject href="http://localhost:4444/api/admin/user/8e4c5b1a-27b3-4b7c-9f0d-3c6a8f7f2d11" name="jdoe" type="application/vnd.vmware.admin.user+xml"/>
+				<AccessLevel>FullControl</AccessLevel>
+			</AccessSetting>
+		</AccessSettings>
+	</ControlAccessParams>
+`
+
 var catalogExample = `
 	<?xml version="1.0" ?>
 	<Catalog href="http://localhost:4444/api/catalog/e8a20fdf-8a78-440c-ac71-0420db59f854" id="urn:vcloud:catalog:e8a20fdf-8a78-440c-ac71-0420db59f854" name="Public Catalog" type="application/vnd.vmware.vcloud.catalog+xml" xmlns="http://www.vmware.com/vcloud/v1.5" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://www.vmware.com/vcloud/v1.5 http://10.6.32.3/api/v1.5/schema/master.xsd">
@@ -50,3 +50,187 @@ func (ci *CatalogItem) GetVAppTemplate() (VAppTemplate, error) {
 	return *cat, nil
 
 }
+
+// Refresh retrieves this catalog item again
+func (ci *CatalogItem) Refresh() error {
+
+	if ci.CatalogItem.HREF == "" {
+		return fmt.Errorf("cannot refresh, Object is empty")
+	}
+
+	u, _ := url.ParseRequestURI(ci.CatalogItem.HREF)
+
+	req := ci.c.NewRequest(map[string]string{}, types.HTTPGet, u, nil)
+
+	resp, err := checkResp(ci.c.DoHTTP(req))
+	if err != nil {
+		return fmt.Errorf("error retrieving catalog item: %s", err)
+	}
+
+	// Empty struct before a new unmarshal, otherwise we end up with duplicate
+	// elements in slices.
+	ci.CatalogItem = &types.CatalogItem{}
+
+	if err = decodeBody(resp, ci.CatalogItem); err != nil {
+		return fmt.Errorf("error decoding catalog item response: %s", err)
+	}
+
+	// The request was successful
+	return nil
+}
+
+// GetMetadata retrieves the metadata of this catalog item
+func (ci *CatalogItem) GetMetadata() (*types.Metadata, error) {
+
+	var s *url.URL
+	var err error
+	if l := ci.CatalogItem.Link.ForType(types.MimeMetadata, types.RelDown); l != nil {
+		s, err = url.ParseRequestURI(l.HREF)
+	} else if s, err = url.ParseRequestURI(ci.CatalogItem.HREF); err == nil {
+		s.Path += "/metadata"
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing catalog item href: %s", err)
+	}
+
+	req := ci.c.NewRequest(map[string]string{}, types.HTTPGet, s, nil)
+
+	resp, err := checkResp(ci.c.DoHTTP(req))
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving catalog item metadata: %s", err)
+	}
+
+	metadata := new(types.Metadata)
+
+	if err = decodeBody(resp, metadata); err != nil {
+		return nil, fmt.Errorf("error decoding catalog item metadata response: %s", err)
+	}
+
+	// The request was successful
+	return metadata, nil
+}
+
+// metadataValue returns the value of the metadata entry key, whether typed or
+// not
+func metadataValue(metadata *types.Metadata, key string) (string, bool) {
+
+	for _, entry := range metadata.MetadataEntry {
+		if entry.Key != key {
+			continue
+		}
+		if entry.TypedValue != nil {
+			return entry.TypedValue.Value, true
+		}
+		return entry.Value, true
+	}
+
+	return "", false
+}
+
+// Rename changes the name of this catalog item, keeping its description
+func (ci *CatalogItem) Rename(name string) error {
+
+	if name == "" {
+		return fmt.Errorf("catalog item needs a name")
+	}
+
+	var s *url.URL
+	var err error
+	if l := ci.CatalogItem.Link.Find(func(l *types.Link) bool { return l.Rel == types.RelEdit }); l != nil {
+		s, err = url.ParseRequestURI(l.HREF)
+	} else {
+		s, err = url.ParseRequestURI(ci.CatalogItem.HREF)
+	}
+	if err != nil {
+		return fmt.Errorf("error parsing catalog item href: %s", err)
+	}
+
+	params := &types.CatalogItem{
+		Xmlns:       types.NsVCloud,
+		Name:        name,
+		Description: ci.CatalogItem.Description,
+		Entity:      ci.CatalogItem.Entity,
+	}
+
+	updated := new(types.CatalogItem)
+
+	if err = executeRequest(ci.c, types.HTTPPut, s, types.MimeCatalogItem, params, updated); err != nil {
+		return fmt.Errorf("error renaming catalog item: %s", err)
+	}
+
+	ci.CatalogItem = updated
+
+	// The request was successful
+	return nil
+}
+
+// Delete deletes this catalog item along with the vApp template or media it
+// refers to
+func (ci *CatalogItem) Delete() error {
+
+	var s *url.URL
+	var err error
+	if l := ci.CatalogItem.Link.Find(func(l *types.Link) bool { return l.Rel == types.RelRemove }); l != nil {
+		s, err = url.ParseRequestURI(l.HREF)
+	} else {
+		s, err = url.ParseRequestURI(ci.CatalogItem.HREF)
+	}
+	if err != nil {
+		return fmt.Errorf("error parsing catalog item href: %s", err)
+	}
+
+	if err = executeRequest(ci.c, types.HTTPDelete, s, "", nil, nil); err != nil {
+		return fmt.Errorf("error deleting catalog item: %s", err)
+	}
+
+	// The request was successful
+	return nil
+}
+
+// Copy copies this catalog item to catalog as a new item named name, which
+// can be the catalog this item lives in
+func (ci *CatalogItem) Copy(catalog Catalog, name, description string) (Task, error) {
+	return ci.copyOrMove(catalog, types.RelCopy, name, description)
+}
+
+// Move moves this catalog item to catalog, keeping its name and description
+func (ci *CatalogItem) Move(catalog Catalog) (Task, error) {
+	return ci.copyOrMove(catalog, types.RelMove, ci.CatalogItem.Name, ci.CatalogItem.Description)
+}
+
+func (ci *CatalogItem) copyOrMove(catalog Catalog, action, name, description string) (Task, error) {
+
+	if ci.CatalogItem.HREF == "" || catalog.Catalog == nil || catalog.Catalog.HREF == "" {
+		return Task{}, fmt.Errorf("can't %s catalog item, objects passed are not valid", action)
+	}
+
+	var s *url.URL
+	var err error
+	if l := catalog.Catalog.Link.ForType(types.MimeCopyOrMoveCatalogItemParams, action); l != nil {
+		s, err = url.ParseRequestURI(l.HREF)
+	} else if s, err = url.ParseRequestURI(catalog.Catalog.HREF); err == nil {
+		s.Path += "/action/" + action
+	}
+	if err != nil {
+		return Task{}, fmt.Errorf("error parsing catalog href: %s", err)
+	}
+
+	params := &types.CopyOrMoveCatalogItemParams{
+		Xmlns:       types.NsVCloud,
+		Name:        name,
+		Description: description,
+		Source: &types.Reference{
+			HREF: ci.CatalogItem.HREF,
+			Name: ci.CatalogItem.Name,
+			Type: types.MimeCatalogItem,
+		},
+	}
+
+	task, err := executeTaskRequest(ci.c, types.HTTPPost, s, types.MimeCopyOrMoveCatalogItemParams, params)
+	if err != nil {
+		return Task{}, fmt.Errorf("error during catalog item %s: %s", action, err)
+	}
+
+	// The request was successful
+	return task, nil
+}
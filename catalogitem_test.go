@@ -5,9 +5,14 @@
 package govcloudair
 
 import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
 )
 
 var catalogItemsResponses = map[string]testResponse{
@@ -58,3 +63,189 @@ var catalogitemExample = `
 		<VersionNumber>4</VersionNumber>
 	</CatalogItem>
 `
+
+// catalogVersionsResponses serve a catalog holding several versions of an
+// image, each tagged with a channel in its metadata
+func catalogVersionsResponses() map[string]map[string]testResponse {
+	return map[string]map[string]testResponse{
+		"GET": {
+			"/api/catalog/e8a20fdf-8a78-440c-ac71-0420db59f854":              {200, nil, catalogVersionsExample},
+			"/api/catalogItem/11111111-aaaa-aaaa-aaaa-111111111111":          {200, nil, fmt.Sprintf(catalogItemVersionExample, "11111111-aaaa-aaaa-aaaa-111111111111", "myimage-2024.10.01", 1<<30, "2024-10-01T08:00:00.000Z", 1)},
+			"/api/catalogItem/11111111-aaaa-aaaa-aaaa-111111111111/metadata": {200, nil, fmt.Sprintf(catalogItemMetadataExample, "stable")},
+			"/api/catalogItem/22222222-aaaa-aaaa-aaaa-222222222222":          {200, nil, fmt.Sprintf(catalogItemVersionExample, "22222222-aaaa-aaaa-aaaa-222222222222", "myimage-2024.11.01", 2<<30, "2024-11-01T08:00:00.000Z", 3)},
+			"/api/catalogItem/22222222-aaaa-aaaa-aaaa-222222222222/metadata": {200, nil, fmt.Sprintf(catalogItemMetadataExample, "testing")},
+			"/api/catalogItem/33333333-aaaa-aaaa-aaaa-333333333333":          {200, nil, fmt.Sprintf(catalogItemVersionExample, "33333333-aaaa-aaaa-aaaa-333333333333", "otherimage-2024.12.01", 1<<30, "2024-12-01T08:00:00.000Z", 1)},
+			"/api/catalogItem/33333333-aaaa-aaaa-aaaa-333333333333/metadata": {200, nil, fmt.Sprintf(catalogItemMetadataExample, "stable")},
+		},
+		"PUT": {
+			"/api/catalogItem/22222222-aaaa-aaaa-aaaa-222222222222": {200, nil, fmt.Sprintf(catalogItemVersionExample, "22222222-aaaa-aaaa-aaaa-222222222222", "myimage-latest", 2<<30, "2024-11-01T08:00:00.000Z", 4)},
+		},
+		"POST": {
+			"/api/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4/action/copy": {202, nil, taskExample},
+			"/api/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4/action/move": {202, nil, taskExample},
+		},
+		"DELETE": {
+			"/api/catalogItem/22222222-aaaa-aaaa-aaaa-222222222222": {204, nil, ""},
+		},
+	}
+}
+
+func Test_CatalogItemVersions(t *testing.T) {
+
+	responses := catalogVersionsResponses()
+	ctx, err := setupTestContext(authHandler(testMethodHandler(responses, nil, new(callCounter))))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	cat := NewCatalog(ctx.Client)
+	cat.Catalog.HREF = ctx.Server.URL + "/api/catalog/e8a20fdf-8a78-440c-ac71-0420db59f854"
+
+	items, err := cat.ListCatalogItems()
+	if assert.NoError(t, err) && assert.Len(t, items, 3) {
+		assert.Equal(t, "myimage-2024.11.01", items[1].CatalogItem.Name)
+		assert.Equal(t, "2024-11-01T08:00:00.000Z", items[1].CatalogItem.DateCreated)
+		assert.Equal(t, int64(3), items[1].CatalogItem.VersionNumber)
+		assert.Equal(t, int64(2<<30), items[1].CatalogItem.Size)
+	}
+
+	latest, err := cat.FindLatestCatalogItemByPrefix("myimage-")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "myimage-2024.11.01", latest.CatalogItem.Name)
+	}
+
+	latest, err = cat.FindLatestCatalogItem(regexp.MustCompile(`image-2024\.1[02]\.01$`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "otherimage-2024.12.01", latest.CatalogItem.Name)
+	}
+
+	_, err = cat.FindLatestCatalogItemByPrefix("INVALID")
+	assert.Error(t, err)
+
+	// Items created at the same time are told apart by name
+	responses["GET"]["/api/catalog/e8a20fdf-8a78-440c-ac71-0420db59f854"] = testResponse{200, nil, strings.Replace(catalogVersionsExample, "myimage-2024.10.01", "myimage-2024.11.02", 1)}
+	responses["GET"]["/api/catalogItem/11111111-aaaa-aaaa-aaaa-111111111111"] = testResponse{200, nil, fmt.Sprintf(catalogItemVersionExample, "11111111-aaaa-aaaa-aaaa-111111111111", "myimage-2024.11.02", 1<<30, "2024-11-01T08:00:00.000Z", 1)}
+	latest, err = cat.FindLatestCatalogItemByPrefix("myimage-")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "myimage-2024.11.02", latest.CatalogItem.Name)
+	}
+
+	tagged, err := cat.FindCatalogItemByMetadata("channel", "stable")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "otherimage-2024.12.01", tagged.CatalogItem.Name)
+	}
+
+	tagged, err = cat.FindCatalogItemByMetadata("channel", "testing")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "myimage-2024.11.01", tagged.CatalogItem.Name)
+	}
+
+	_, err = cat.FindCatalogItemByMetadata("channel", "INVALID")
+	assert.Error(t, err)
+}
+
+func Test_CatalogItemOperations(t *testing.T) {
+
+	submitted := make(map[string][]byte)
+	ctx, err := setupTestContext(authHandler(testMethodHandler(catalogVersionsResponses(), submitted, new(callCounter))))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	item := NewCatalogItem(ctx.Client)
+	item.CatalogItem.HREF = ctx.Server.URL + "/api/catalogItem/22222222-aaaa-aaaa-aaaa-222222222222"
+	if !assert.NoError(t, item.Refresh()) {
+		return
+	}
+
+	metadata, err := item.GetMetadata()
+	if assert.NoError(t, err) {
+		value, ok := metadataValue(metadata, "channel")
+		assert.True(t, ok)
+		assert.Equal(t, "testing", value)
+		_, ok = metadataValue(metadata, "INVALID")
+		assert.False(t, ok)
+	}
+
+	assert.Error(t, item.Rename(""))
+	if assert.NoError(t, item.Rename("myimage-latest")) {
+		assert.Equal(t, "myimage-latest", item.CatalogItem.Name)
+		renamed := new(types.CatalogItem)
+		if assert.NoError(t, xml.Unmarshal(submitted["PUT /api/catalogItem/22222222-aaaa-aaaa-aaaa-222222222222"], renamed)) {
+			assert.Equal(t, "myimage-latest", renamed.Name)
+			assert.Equal(t, ctx.Server.URL+"/api/vAppTemplate/vappTemplate-22222222-aaaa-aaaa-aaaa-222222222222", renamed.Entity.HREF)
+		}
+	}
+
+	dest := NewCatalog(ctx.Client)
+	dest.Catalog.HREF = ctx.Server.URL + "/api/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4"
+
+	task, err := item.Copy(*dest, "myimage-copy", "copied image")
+	if assert.NoError(t, err) {
+		assert.Equal(t, ctx.Server.URL+"/api/task/1b8f926c-eff5-4bea-9b13-4e49bdd50c05", task.Task.HREF)
+		params := new(types.CopyOrMoveCatalogItemParams)
+		if assert.NoError(t, xml.Unmarshal(submitted["POST /api/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4/action/copy"], params)) {
+			assert.Equal(t, "myimage-copy", params.Name)
+			assert.Equal(t, "copied image", params.Description)
+			assert.Equal(t, item.CatalogItem.HREF, params.Source.HREF)
+		}
+	}
+
+	_, err = item.Move(*dest)
+	if assert.NoError(t, err) {
+		params := new(types.CopyOrMoveCatalogItemParams)
+		if assert.NoError(t, xml.Unmarshal(submitted["POST /api/catalog/7212e451-76e1-4631-b2de-ba1dfd8080e4/action/move"], params)) {
+			assert.Equal(t, "myimage-latest", params.Name)
+			assert.Equal(t, item.CatalogItem.HREF, params.Source.HREF)
+		}
+	}
+
+	_, err = item.Move(Catalog{})
+	assert.Error(t, err)
+
+	if assert.NoError(t, item.Delete()) {
+		assert.Contains(t, submitted, "DELETE /api/catalogItem/22222222-aaaa-aaaa-aaaa-222222222222")
+	}
+}
+
+var catalogItemMetadataExample = `
+	<?xml version="1.0" encoding="UTF-8"?>
+	<Metadata xmlns="http://www.vmware.com/vcloud/v1.5" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" type="application/vnd.vmware.vcloud.metadata+xml">
+		<MetadataEntry type="application/vnd.vmware.vcloud.metadata.value+xml">
+			<Key>owner</Key>
+			<TypedValue xsi:type="MetadataStringValue">
+				<Value>platform</Value>
+			</TypedValue>
+		</MetadataEntry>
+		<MetadataEntry type="application/vnd.vmware.vcloud.metadata.value+xml">
+			<Key>channel</Key>
+			<TypedValue xsi:type="MetadataStringValue">
+				<Value>%s</Value>
+			</TypedValue>
+		</MetadataEntry>
+	</Metadata>
+`
+
+var catalogVersionsExample = `<?xml version="1.0" encoding="UTF-8"?>
+<Catalog xmlns="http://www.vmware.com/vcloud/v1.5" name="images" id="urn:vcloud:catalog:e8a20fdf-8a78-440c-ac71-0420db59f854" href="http://localhost:4444/api/catalog/e8a20fdf-8a78-440c-ac71-0420db59f854" type="application/vnd.vmware.vcloud.catalog+xml">
+    <CatalogItems>
+        <CatalogItem href="http://localhost:4444/api/catalogItem/11111111-aaaa-aaaa-aaaa-111111111111" name="myimage-2024.10.01" type="application/vnd.vmware.vcloud.catalogItem+xml"/>
+        <CatalogItem href="http://localhost:4444/api/catalogItem/22222222-aaaa-aaaa-aaaa-222222222222" name="myimage-2024.11.01" type="application/vnd.vmware.vcloud.catalogItem+xml"/>
+        <CatalogItem href="http://localhost:4444/api/catalogItem/33333333-aaaa-aaaa-aaaa-333333333333" name="otherimage-2024.12.01" type="application/vnd.vmware.vcloud.catalogItem+xml"/>
+    </CatalogItems>
+    <IsPublished>false</IsPublished>
+</Catalog>
+`
+
+// catalogItemVersionExample is a catalog item of the images catalog, given
+// its id, name, size, creation date and version
+var catalogItemVersionExample = `<?xml version="1.0" encoding="UTF-8"?>
+<CatalogItem xmlns="http://www.vmware.com/vcloud/v1.5" size="%[3]d" name="%[2]s" id="urn:vcloud:catalogitem:%[1]s" type="application/vnd.vmware.vcloud.catalogItem+xml" href="http://localhost:4444/api/catalogItem/%[1]s">
+    <Link rel="up" type="application/vnd.vmware.vcloud.catalog+xml" href="http://localhost:4444/api/catalog/e8a20fdf-8a78-440c-ac71-0420db59f854"/>
+    <Link rel="down" type="application/vnd.vmware.vcloud.metadata+xml" href="http://localhost:4444/api/catalogItem/%[1]s/metadata"/>
+    <Description/>
+    <Entity type="application/vnd.vmware.vcloud.vAppTemplate+xml" name="%[2]s" href="http://localhost:4444/api/vAppTemplate/vappTemplate-%[1]s"/>
+    <DateCreated>%[4]s</DateCreated>
+    <VersionNumber>%[5]d</VersionNumber>
+</CatalogItem>
+`
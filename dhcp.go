@@ -0,0 +1,167 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// matchesNetwork reports whether ref points to the network with the given
+// name or HREF
+func matchesNetwork(ref *types.Reference, network string) bool {
+	return ref != nil && network != "" && (ref.Name == network || ref.HREF == network)
+}
+
+func validateDhcpPool(pool *types.DhcpPoolService) error {
+
+	if pool == nil || pool.Network == nil || (pool.Network.HREF == "" && pool.Network.Name == "") {
+		return fmt.Errorf("DHCP pool needs a network")
+	}
+
+	low := net.ParseIP(pool.LowIPAddress)
+	high := net.ParseIP(pool.HighIPAddress)
+	if low == nil || high == nil {
+		return fmt.Errorf("invalid DHCP range %s-%s", pool.LowIPAddress, pool.HighIPAddress)
+	}
+
+	if bytes.Compare(low.To16(), high.To16()) > 0 {
+		return fmt.Errorf("invalid DHCP range %s-%s, the low address is above the high address", pool.LowIPAddress, pool.HighIPAddress)
+	}
+
+	if pool.DefaultLeaseTime < 0 || pool.MaxLeaseTime < 0 {
+		return fmt.Errorf("DHCP lease times can't be negative")
+	}
+
+	if pool.MaxLeaseTime > 0 && pool.DefaultLeaseTime > pool.MaxLeaseTime {
+		return fmt.Errorf("DHCP default lease time %d is above the maximum lease time %d", pool.DefaultLeaseTime, pool.MaxLeaseTime)
+	}
+
+	return nil
+}
+
+// configDhcpService returns the DHCP service of config, adding an empty one
+// when the gateway has none
+func configDhcpService(config *types.GatewayFeatures) *types.GatewayDhcpService {
+	if config.GatewayDhcpService == nil {
+		config.GatewayDhcpService = &types.GatewayDhcpService{}
+	}
+	return config.GatewayDhcpService
+}
+
+// DhcpPools refreshes this gateway and returns its DHCP pools
+func (e *EdgeGateway) DhcpPools() ([]*types.DhcpPoolService, error) {
+
+	if err := e.Refresh(); err != nil {
+		return nil, err
+	}
+
+	if e.EdgeGateway.Configuration == nil || e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration == nil {
+		return nil, fmt.Errorf("edge gateway has no service configuration")
+	}
+
+	dhcp := e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration.GatewayDhcpService
+	if dhcp == nil {
+		return nil, nil
+	}
+
+	return dhcp.Pool, nil
+}
+
+// FindDhcpPool finds the DHCP pool of a network, by name or HREF, in the
+// last known configuration of this gateway
+func (e *EdgeGateway) FindDhcpPool(network string) (*types.DhcpPoolService, error) {
+
+	if e.EdgeGateway.Configuration == nil || e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration == nil {
+		return nil, fmt.Errorf("edge gateway has no service configuration")
+	}
+
+	if dhcp := e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration.GatewayDhcpService; dhcp != nil {
+		for _, pool := range dhcp.Pool {
+			if matchesNetwork(pool.Network, network) {
+				return pool, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("can't find DHCP pool for network: %s", network)
+}
+
+// findDhcpPoolIndex returns the index of the pool serving the same network as
+// pool, or -1
+func findDhcpPoolIndex(dhcp *types.GatewayDhcpService, pool *types.DhcpPoolService) int {
+	for k, p := range dhcp.Pool {
+		if matchesNetwork(p.Network, pool.Network.HREF) || matchesNetwork(p.Network, pool.Network.Name) {
+			return k
+		}
+	}
+	return -1
+}
+
+// CreateDhcpPool adds a DHCP pool to this gateway, enabling the DHCP service
+// when needed. A network can only have one pool.
+func (e *EdgeGateway) CreateDhcpPool(pool *types.DhcpPoolService) (Task, error) {
+
+	if err := validateDhcpPool(pool); err != nil {
+		return Task{}, err
+	}
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		dhcp := configDhcpService(config)
+
+		if findDhcpPoolIndex(dhcp, pool) >= 0 {
+			return fmt.Errorf("network %s already has a DHCP pool", pool.Network.Name)
+		}
+
+		dhcp.IsEnabled = true
+		dhcp.Pool = append(dhcp.Pool, pool)
+
+		return nil
+	})
+}
+
+// UpdateDhcpPool replaces the DHCP pool serving the same network as pool
+func (e *EdgeGateway) UpdateDhcpPool(pool *types.DhcpPoolService) (Task, error) {
+
+	if err := validateDhcpPool(pool); err != nil {
+		return Task{}, err
+	}
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		dhcp := configDhcpService(config)
+
+		k := findDhcpPoolIndex(dhcp, pool)
+		if k < 0 {
+			return fmt.Errorf("can't find DHCP pool for network: %s", pool.Network.Name)
+		}
+
+		dhcp.Pool[k] = pool
+
+		return nil
+	})
+}
+
+// DeleteDhcpPool removes the DHCP pool of a network, by name or HREF
+func (e *EdgeGateway) DeleteDhcpPool(network string) (Task, error) {
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		dhcp := configDhcpService(config)
+
+		for k, p := range dhcp.Pool {
+			if matchesNetwork(p.Network, network) {
+				dhcp.Pool = append(dhcp.Pool[:k], dhcp.Pool[k+1:]...)
+				return nil
+			}
+		}
+
+		return fmt.Errorf("can't find DHCP pool for network: %s", network)
+	})
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+func Test_DhcpPools(t *testing.T) {
+	cc := new(callCounter)
+	config := new(types.GatewayFeatures)
+
+	ctx, err := setupTestContext(authHandler(edgeServicesHandler(dhcpEdgegatewayExample, config, cc)))
+	if assert.NoError(t, err) {
+
+		edge, err := ctx.VDC.FindEdgeGateway("M916272752-5793")
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+
+			pools, err := edge.DhcpPools()
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) && assert.Len(t, pools, 1) {
+				assert.Equal(t, "192.168.109.100", pools[0].LowIPAddress)
+				assert.Equal(t, 7200, pools[0].MaxLeaseTime)
+			}
+
+			pool, err := edge.FindDhcpPool("M916272752-5793-default-routed")
+			if assert.NoError(t, err) {
+				assert.Equal(t, "192.168.109.199", pool.HighIPAddress)
+			}
+
+			newpool := &types.DhcpPoolService{
+				IsEnabled:        true,
+				Network:          &types.Reference{HREF: ctx.Server.URL + "/api/admin/network/32e903a4-c726-497f-9ec7-e4a43b58bdbc", Name: "JGray Network"},
+				DefaultLeaseTime: 3600,
+				MaxLeaseTime:     7200,
+				LowIPAddress:     "192.168.108.50",
+				HighIPAddress:    "192.168.108.99",
+			}
+
+			_, err = edge.CreateDhcpPool(newpool)
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				assert.True(t, config.GatewayDhcpService.IsEnabled)
+				if assert.Len(t, config.GatewayDhcpService.Pool, 2) {
+					assert.Equal(t, "JGray Network", config.GatewayDhcpService.Pool[1].Network.Name)
+				}
+			}
+
+			_, err = edge.CreateDhcpPool(&types.DhcpPoolService{
+				Network:       &types.Reference{Name: "M916272752-5793-default-routed"},
+				LowIPAddress:  "192.168.109.10",
+				HighIPAddress: "192.168.109.20",
+			})
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+
+			newpool.LowIPAddress = "192.168.108.150"
+			_, err = edge.CreateDhcpPool(newpool)
+			assert.Error(t, err)
+
+			newpool.LowIPAddress = "192.168.108.50"
+			newpool.DefaultLeaseTime = 9000
+			_, err = edge.UpdateDhcpPool(newpool)
+			assert.Error(t, err)
+			assert.Equal(t, 0, cc.Pop())
+
+			_, err = edge.UpdateDhcpPool(&types.DhcpPoolService{
+				Network:          &types.Reference{Name: "M916272752-5793-default-routed"},
+				DefaultLeaseTime: 600,
+				MaxLeaseTime:     1200,
+				LowIPAddress:     "192.168.109.100",
+				HighIPAddress:    "192.168.109.149",
+			})
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) && assert.Len(t, config.GatewayDhcpService.Pool, 1) {
+				assert.Equal(t, "192.168.109.149", config.GatewayDhcpService.Pool[0].HighIPAddress)
+				assert.Equal(t, 1200, config.GatewayDhcpService.Pool[0].MaxLeaseTime)
+			}
+
+			_, err = edge.DeleteDhcpPool(ctx.Server.URL + "/api/admin/network/cb0f4c9e-1a46-49d4-9fcb-d228000a6bc1")
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				assert.Len(t, config.GatewayDhcpService.Pool, 0)
+			}
+
+			_, err = edge.DeleteDhcpPool("JGray Network")
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+		}
+	}
+}
+
+// dhcpEdgegatewayExample is edgegatewayExample with a DHCP pool on the
+// default routed network
+var dhcpEdgegatewayExample = strings.Replace(edgegatewayExample, `            <GatewayIpsecVpnService>`, `            <GatewayDhcpService>
+                <IsEnabled>true</IsEnabled>
+                <Pool>
+                    <IsEnabled>true</IsEnabled>
+                    <Network href="http://localhost:4444/api/admin/network/cb0f4c9e-1a46-49d4-9fcb-d228000a6bc1" name="M916272752-5793-default-routed" type="application/vnd.vmware.admin.network+xml"/>
+                    <DefaultLeaseTime>3600</DefaultLeaseTime>
+                    <MaxLeaseTime>7200</MaxLeaseTime>
+                    <LowIpAddress>192.168.109.100</LowIpAddress>
+                    <HighIpAddress>192.168.109.199</HighIpAddress>
+                </Pool>
+            </GatewayDhcpService>
+            <GatewayIpsecVpnService>`, 1)
@@ -0,0 +1,304 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"fmt"
+	"net/url"
+
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// Disk an independent disk client
+type Disk struct {
+	Disk *types.Disk
+	c    Client
+}
+
+// NewDisk creates a new independent disk client
+func NewDisk(c Client) *Disk {
+	return &Disk{
+		Disk: new(types.Disk),
+		c:    c,
+	}
+}
+
+// DiskParams describes an independent disk to create
+type DiskParams struct {
+	Name        string
+	Description string
+	// Size is the size of the disk in bytes.
+	Size int64
+	// BusType and BusSubType select the controller the disk is attached
+	// with, see the DiskBusType and DiskBusSubType constants. vCloud picks
+	// them when empty.
+	BusType    string
+	BusSubType string
+	// StorageProfile is the name of the storage profile of the vdc the disk
+	// is stored on, the default profile of the vdc when empty.
+	StorageProfile string
+}
+
+// storageProfile returns the storage profile of this vdc named name
+func (v *Vdc) storageProfile(name string) (*types.Reference, error) {
+
+	for _, profiles := range v.Vdc.VdcStorageProfiles {
+		for _, profile := range profiles.VdcStorageProfile {
+			if profile.Name == name {
+				return profile, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("can't find storage profile: %s", name)
+}
+
+// CreateDisk creates an independent disk in this vdc. The disk is ready once
+// the returned task is done.
+func (v *Vdc) CreateDisk(params DiskParams) (Disk, Task, error) {
+
+	if params.Name == "" {
+		return Disk{}, Task{}, fmt.Errorf("disk needs a name")
+	}
+	if params.Size <= 0 {
+		return Disk{}, Task{}, fmt.Errorf("disk %s needs a size", params.Name)
+	}
+
+	disk := &types.Disk{
+		Name:        params.Name,
+		Description: params.Description,
+		Size:        params.Size,
+		BusType:     params.BusType,
+		BusSubType:  params.BusSubType,
+	}
+
+	if params.StorageProfile != "" {
+		profile, err := v.storageProfile(params.StorageProfile)
+		if err != nil {
+			return Disk{}, Task{}, err
+		}
+		disk.StorageProfile = &types.Reference{HREF: profile.HREF, Name: profile.Name}
+	}
+
+	var s *url.URL
+	var err error
+	if l := v.Vdc.Link.ForType(types.MimeDiskCreateParams, types.RelAdd); l != nil {
+		s, err = url.ParseRequestURI(l.HREF)
+	} else if s, err = url.ParseRequestURI(v.Vdc.HREF); err == nil {
+		s.Path += "/disk"
+	}
+	if err != nil {
+		return Disk{}, Task{}, fmt.Errorf("error parsing vdc href: %s", err)
+	}
+
+	created := NewDisk(v.c)
+
+	payload := &types.DiskCreateParams{Xmlns: types.NsVCloud, Disk: disk}
+	if err = executeRequest(v.c, types.HTTPPost, s, types.MimeDiskCreateParams, payload, created.Disk); err != nil {
+		return Disk{}, Task{}, fmt.Errorf("error creating disk: %s", err)
+	}
+
+	if created.Disk.Tasks == nil || len(created.Disk.Tasks.Task) == 0 {
+		return Disk{}, Task{}, fmt.Errorf("error creating disk: no task returned")
+	}
+
+	task := NewTask(v.c)
+	task.Task = created.Disk.Tasks.Task[0]
+
+	// The request was successful
+	return *created, *task, nil
+}
+
+// ListDisks refreshes this vdc and returns references to its independent
+// disks
+func (v *Vdc) ListDisks() ([]*types.ResourceReference, error) {
+
+	if err := v.Refresh(); err != nil {
+		return nil, err
+	}
+
+	var disks []*types.ResourceReference
+	for _, entities := range v.Vdc.ResourceEntities {
+		for _, entity := range entities.ResourceEntity {
+			if entity.Type == types.MimeDisk {
+				disks = append(disks, entity)
+			}
+		}
+	}
+
+	return disks, nil
+}
+
+// FindDisk retrieves the independent disk of this vdc named name
+func (v *Vdc) FindDisk(name string) (Disk, error) {
+
+	refs, err := v.ListDisks()
+	if err != nil {
+		return Disk{}, err
+	}
+
+	for _, ref := range refs {
+		if ref.Name != name {
+			continue
+		}
+
+		disk := NewDisk(v.c)
+		disk.Disk.HREF = ref.HREF
+		if err := disk.Refresh(); err != nil {
+			return Disk{}, err
+		}
+
+		// The request was successful
+		return *disk, nil
+	}
+
+	return Disk{}, fmt.Errorf("can't find disk: %s", name)
+}
+
+// Refresh retrieves this disk again
+func (d *Disk) Refresh() error {
+
+	if d.Disk.HREF == "" {
+		return fmt.Errorf("cannot refresh, Object is empty")
+	}
+
+	u, _ := url.ParseRequestURI(d.Disk.HREF)
+
+	req := d.c.NewRequest(map[string]string{}, types.HTTPGet, u, nil)
+
+	resp, err := checkResp(d.c.DoHTTP(req))
+	if err != nil {
+		return fmt.Errorf("error retrieving disk: %s", err)
+	}
+
+	// Empty struct before a new unmarshal, otherwise we end up with duplicate
+	// elements in slices.
+	d.Disk = &types.Disk{}
+
+	if err = decodeBody(resp, d.Disk); err != nil {
+		return fmt.Errorf("error decoding disk response: %s", err)
+	}
+
+	// The request was successful
+	return nil
+}
+
+// linkURL returns the URL of the link of this disk with the given rel, or
+// the URL of the disk itself when there is no such link
+func (d *Disk) linkURL(rel string) (*url.URL, error) {
+
+	if l := d.Disk.Link.Find(func(l *types.Link) bool { return l.Rel == rel }); l != nil {
+		return url.ParseRequestURI(l.HREF)
+	}
+
+	s, err := url.ParseRequestURI(d.Disk.HREF)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing disk href: %s", err)
+	}
+
+	return s, nil
+}
+
+// Resize grows this disk to size bytes, disks can't shrink
+func (d *Disk) Resize(size int64) (Task, error) {
+
+	if err := d.Refresh(); err != nil {
+		return Task{}, err
+	}
+
+	if size < d.Disk.Size {
+		return Task{}, fmt.Errorf("can't shrink disk %s from %d to %d bytes", d.Disk.Name, d.Disk.Size, size)
+	}
+
+	s, err := d.linkURL(types.RelEdit)
+	if err != nil {
+		return Task{}, err
+	}
+
+	params := &types.Disk{
+		Xmlns:          types.NsVCloud,
+		Name:           d.Disk.Name,
+		Description:    d.Disk.Description,
+		Size:           size,
+		BusType:        d.Disk.BusType,
+		BusSubType:     d.Disk.BusSubType,
+		StorageProfile: d.Disk.StorageProfile,
+	}
+
+	task, err := executeTaskRequest(d.c, types.HTTPPut, s, types.MimeDisk, params)
+	if err != nil {
+		return Task{}, fmt.Errorf("error resizing disk: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
+
+// Delete deletes this disk, which must not be attached to a VM
+func (d *Disk) Delete() (Task, error) {
+
+	s, err := d.linkURL(types.RelRemove)
+	if err != nil {
+		return Task{}, err
+	}
+
+	task, err := executeTaskRequest(d.c, types.HTTPDelete, s, "", nil)
+	if err != nil {
+		return Task{}, fmt.Errorf("error deleting disk: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
+
+// diskAction attaches disk to or detaches it from this VM
+func (v *VM) diskAction(rel, action string, disk Disk) (Task, error) {
+
+	var s *url.URL
+	var err error
+	if l := v.VM.Link.Find(func(l *types.Link) bool { return l.Rel == rel }); l != nil {
+		s, err = url.ParseRequestURI(l.HREF)
+	} else if s, err = url.ParseRequestURI(v.VM.HREF); err == nil {
+		s.Path += "/disk/action/" + action
+	}
+	if err != nil {
+		return Task{}, fmt.Errorf("error parsing VM href: %s", err)
+	}
+
+	params := &types.DiskAttachOrDetachParams{
+		Xmlns: types.NsVCloud,
+		Disk: &types.Reference{
+			HREF: disk.Disk.HREF,
+			Name: disk.Disk.Name,
+			Type: types.MimeDisk,
+		},
+	}
+
+	return executeTaskRequest(v.c, types.HTTPPost, s, types.MimeDiskAttachOrDetachParams, params)
+}
+
+// AttachDisk attaches the independent disk to this VM
+func (v *VM) AttachDisk(disk Disk) (Task, error) {
+
+	task, err := v.diskAction(types.RelDiskAttach, "attach", disk)
+	if err != nil {
+		return Task{}, fmt.Errorf("error attaching disk: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
+
+// DetachDisk detaches the independent disk from this VM
+func (v *VM) DetachDisk(disk Disk) (Task, error) {
+
+	task, err := v.diskAction(types.RelDiskDetach, "detach", disk)
+	if err != nil {
+		return Task{}, fmt.Errorf("error detaching disk: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+var diskResponses = map[string]map[string]testResponse{
+	"GET": {
+		"/api/vdc/00000000-0000-0000-0000-000000000000":  {200, nil, vdcDiskExample},
+		"/api/disk/99999999-9999-9999-9999-999999999999": {200, nil, diskExample},
+	},
+	"POST": {
+		"/api/vdc/00000000-0000-0000-0000-000000000000/disk":                   {201, nil, diskCreatedExample},
+		"/api/vApp/vm-11111111-1111-1111-1111-111111111111/disk/action/attach": {202, nil, taskExample},
+		"/api/vApp/vm-11111111-1111-1111-1111-111111111111/disk/action/detach": {202, nil, taskExample},
+	},
+	"PUT": {
+		"/api/disk/99999999-9999-9999-9999-999999999999": {202, nil, taskExample},
+	},
+	"DELETE": {
+		"/api/disk/99999999-9999-9999-9999-999999999999": {202, nil, taskExample},
+	},
+}
+
+func Test_Disk(t *testing.T) {
+
+	submitted := make(map[string][]byte)
+	ctx, err := setupTestContext(authHandler(testMethodHandler(diskResponses, submitted, new(callCounter))))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, _, err = ctx.VDC.CreateDisk(DiskParams{Name: "data"})
+	assert.Error(t, err)
+	_, _, err = ctx.VDC.CreateDisk(DiskParams{Name: "data", Size: 1 << 30, StorageProfile: "INVALID"})
+	assert.Error(t, err)
+
+	disk, task, err := ctx.VDC.CreateDisk(DiskParams{
+		Name:           "data",
+		Description:    "database volume",
+		Size:           10 << 30,
+		BusType:        types.DiskBusTypeSCSI,
+		BusSubType:     types.DiskBusSubTypeParaVirtual,
+		StorageProfile: "storageProfile",
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, ctx.Server.URL+"/api/disk/99999999-9999-9999-9999-999999999999", disk.Disk.HREF)
+		assert.Equal(t, ctx.Server.URL+"/api/task/1b8f926c-eff5-4bea-9b13-4e49bdd50c05", task.Task.HREF)
+		created := new(types.DiskCreateParams)
+		if assert.NoError(t, xml.Unmarshal(submitted["POST /api/vdc/00000000-0000-0000-0000-000000000000/disk"], created)) {
+			assert.Equal(t, int64(10<<30), created.Disk.Size)
+			assert.Equal(t, types.DiskBusTypeSCSI, created.Disk.BusType)
+			assert.Equal(t, types.DiskBusSubTypeParaVirtual, created.Disk.BusSubType)
+			if assert.NotNil(t, created.Disk.StorageProfile) {
+				assert.Equal(t, "storageProfile", created.Disk.StorageProfile.Name)
+			}
+		}
+	}
+
+	refs, err := ctx.VDC.ListDisks()
+	if assert.NoError(t, err) && assert.Len(t, refs, 1) {
+		assert.Equal(t, "data", refs[0].Name)
+	}
+
+	found, err := ctx.VDC.FindDisk("data")
+	if assert.NoError(t, err) {
+		assert.Equal(t, disk.Disk.HREF, found.Disk.HREF)
+		assert.Equal(t, int64(10<<30), found.Disk.Size)
+	}
+	_, err = ctx.VDC.FindDisk("INVALID")
+	assert.Error(t, err)
+
+	// Disks grow but never shrink
+	_, err = found.Resize(1 << 30)
+	assert.Error(t, err)
+	assert.NotContains(t, submitted, "PUT /api/disk/99999999-9999-9999-9999-999999999999")
+
+	_, err = found.Resize(20 << 30)
+	if assert.NoError(t, err) {
+		resized := new(types.Disk)
+		if assert.NoError(t, xml.Unmarshal(submitted["PUT /api/disk/99999999-9999-9999-9999-999999999999"], resized)) {
+			assert.Equal(t, int64(20<<30), resized.Size)
+			assert.Equal(t, "data", resized.Name)
+			assert.Equal(t, types.DiskBusSubTypeLsiLogic, resized.BusSubType)
+		}
+	}
+
+	vm := NewVM(ctx.Client)
+	vm.VM.HREF = ctx.Server.URL + "/api/vApp/vm-11111111-1111-1111-1111-111111111111"
+
+	_, err = vm.AttachDisk(found)
+	if assert.NoError(t, err) {
+		params := new(types.DiskAttachOrDetachParams)
+		if assert.NoError(t, xml.Unmarshal(submitted["POST /api/vApp/vm-11111111-1111-1111-1111-111111111111/disk/action/attach"], params)) {
+			assert.Equal(t, found.Disk.HREF, params.Disk.HREF)
+			assert.Equal(t, types.MimeDisk, params.Disk.Type)
+		}
+	}
+
+	_, err = vm.DetachDisk(found)
+	if assert.NoError(t, err) {
+		assert.Contains(t, submitted, "POST /api/vApp/vm-11111111-1111-1111-1111-111111111111/disk/action/detach")
+	}
+
+	_, err = found.Delete()
+	assert.NoError(t, err)
+	assert.Contains(t, submitted, "DELETE /api/disk/99999999-9999-9999-9999-999999999999")
+}
+
+// diskCreatedExample is a disk being created, along with the creation task
+var diskCreatedExample = `<?xml version="1.0" encoding="UTF-8"?>
+<Disk xmlns="http://www.vmware.com/vcloud/v1.5" size="10737418240" busSubType="VirtualSCSI" busType="6" status="0" name="data" id="urn:vcloud:disk:99999999-9999-9999-9999-999999999999" href="http://localhost:4444/api/disk/99999999-9999-9999-9999-999999999999" type="application/vnd.vmware.vcloud.disk+xml">
+    <Description>database volume</Description>
+    <Tasks>
+        <Task status="running" operationName="vdcCreateDisk" name="task" href="http://localhost:4444/api/task/1b8f926c-eff5-4bea-9b13-4e49bdd50c05" type="application/vnd.vmware.vcloud.task+xml"/>
+    </Tasks>
+    <StorageProfile href="http://localhost:4444/api/vdcStorageProfile/88888888-8888-8888-8888-888888888888" name="storageProfile" type="application/vnd.vmware.vcloud.vdcStorageProfile+xml"/>
+</Disk>
+`
+
+var diskExample = `<?xml version="1.0" encoding="UTF-8"?>
+<Disk xmlns="http://www.vmware.com/vcloud/v1.5" size="10737418240" busSubType="lsilogic" busType="6" status="1" name="data" id="urn:vcloud:disk:99999999-9999-9999-9999-999999999999" href="http://localhost:4444/api/disk/99999999-9999-9999-9999-999999999999" type="application/vnd.vmware.vcloud.disk+xml">
+    <Link rel="edit" href="http://localhost:4444/api/disk/99999999-9999-9999-9999-999999999999" type="application/vnd.vmware.vcloud.disk+xml"/>
+    <Link rel="remove" href="http://localhost:4444/api/disk/99999999-9999-9999-9999-999999999999"/>
+    <Description>database volume</Description>
+    <StorageProfile href="http://localhost:4444/api/vdcStorageProfile/88888888-8888-8888-8888-888888888888" name="storageProfile" type="application/vnd.vmware.vcloud.vdcStorageProfile+xml"/>
+</Disk>
+`
+
+// vdcDiskExample is vdcExample holding the disk
+var vdcDiskExample = strings.Replace(vdcExample, "</ResourceEntities>", `<ResourceEntity href="http://localhost:4444/api/disk/99999999-9999-9999-9999-999999999999" name="data" type="application/vnd.vmware.vcloud.disk+xml"/></ResourceEntities>`, 1)
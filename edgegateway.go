@@ -9,7 +9,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"net/url"
-	"os"
+	"sync"
 
 	types "github.com/vmware/govcloudair/types/v56"
 )
@@ -56,261 +56,488 @@ func (e *EdgeGateway) Refresh() error {
 	return nil
 }
 
-// Remove1to1Mapping removes a 1 to 1 mapping on the gateway
-func (e *EdgeGateway) Remove1to1Mapping(internal, external string) (Task, error) {
+// edgeGatewayServiceAttempts is how many times ModifyServices applies a
+// mutation before giving up on concurrent changes to the gateway
+const edgeGatewayServiceAttempts = 3
+
+// edgeGatewayLocks serialises service changes to the same gateway, keyed by
+// gateway HREF, across all the EdgeGateway clients of this process
+var edgeGatewayLocks = struct {
+	sync.Mutex
+	m map[string]*sync.Mutex
+}{m: make(map[string]*sync.Mutex)}
+
+func edgeGatewayLock(href string) *sync.Mutex {
+	edgeGatewayLocks.Lock()
+	defer edgeGatewayLocks.Unlock()
+
+	l, ok := edgeGatewayLocks.m[href]
+	if !ok {
+		l = new(sync.Mutex)
+		edgeGatewayLocks.m[href] = l
+	}
+	return l
+}
+
+// gatewayServices returns the service configuration of the last known state
+// of this gateway, marshalled so that it can be compared and copied
+func (e *EdgeGateway) gatewayServices() ([]byte, error) {
 
-	// Refresh EdgeGateway rules
-	err := e.Refresh()
+	if e.EdgeGateway.Configuration == nil || e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration == nil {
+		return nil, fmt.Errorf("edge gateway has no service configuration")
+	}
+
+	output, err := xml.Marshal(e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration)
 	if err != nil {
-		fmt.Printf("error: %v\n", err)
+		return nil, fmt.Errorf("error marshalling edge gateway services: %s", err)
+	}
+
+	return output, nil
+}
+
+// copyServices decodes a service configuration returned by gatewayServices
+func copyServices(data []byte) (*types.GatewayFeatures, error) {
+
+	config := new(types.GatewayFeatures)
+	if err := xml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("error copying edge gateway services: %s", err)
 	}
 
-	var uplinkif string
-	for _, gifs := range e.EdgeGateway.Configuration.GatewayInterfaces.GatewayInterface {
-		if gifs.InterfaceType == "uplink" {
-			uplinkif = gifs.Network.HREF
+	return config, nil
+}
+
+// changedServices returns the names of the services that differ between
+// before and after
+func changedServices(before, after *types.GatewayFeatures) ([]string, error) {
+
+	services := []struct {
+		name          string
+		before, after interface{}
+	}{
+		{"FirewallService", before.FirewallService, after.FirewallService},
+		{"NatService", before.NatService, after.NatService},
+		{"GatewayDhcpService", before.GatewayDhcpService, after.GatewayDhcpService},
+		{"GatewayIpsecVpnService", before.GatewayIpsecVpnService, after.GatewayIpsecVpnService},
+		{"LoadBalancerService", before.LoadBalancerService, after.LoadBalancerService},
+		{"StaticRoutingService", before.StaticRoutingService, after.StaticRoutingService},
+	}
+
+	var changed []string
+	for _, s := range services {
+		b, err := xml.Marshal(s.before)
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling %s: %s", s.name, err)
+		}
+		a, err := xml.Marshal(s.after)
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling %s: %s", s.name, err)
+		}
+		if !bytes.Equal(b, a) {
+			changed = append(changed, s.name)
 		}
 	}
 
-	newedgeconfig := e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration
+	return changed, nil
+}
 
-	// Take care of the NAT service
-	newnatservice := &types.NatService{}
+// includesServices reports whether all the services in want are in services
+func includesServices(services, want []string) bool {
+	for _, w := range want {
+		found := false
+		for _, s := range services {
+			if s == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
 
-	// Copy over the NAT configuration
-	newnatservice.IsEnabled = newedgeconfig.NatService.IsEnabled
-	newnatservice.NatType = newedgeconfig.NatService.NatType
-	newnatservice.Policy = newedgeconfig.NatService.Policy
-	newnatservice.ExternalIP = newedgeconfig.NatService.ExternalIP
+// ModifyServices changes the services of this gateway in one transaction. It
+// refreshes the gateway, hands a copy of its service configuration to mutate
+// and, when mutate changed something, submits the new configuration and waits
+// for the resulting task. When mutate changes nothing no request is sent and
+// the returned Task is already complete, waiting on it returns at once.
+//
+// Calls for the same gateway are serialised within this process. Changes made
+// by other clients are detected by re-reading the gateway right before the
+// submission and after the task, in which case mutate is applied again to the
+// fresh configuration, so it must be safe to call more than once.
+func (e *EdgeGateway) ModifyServices(mutate func(config *types.GatewayFeatures) error) (Task, error) {
+
+	if e.EdgeGateway == nil || e.EdgeGateway.HREF == "" {
+		return Task{}, fmt.Errorf("cannot modify services, Object is empty")
+	}
 
-	for k, v := range newedgeconfig.NatService.NatRule {
+	l := edgeGatewayLock(e.EdgeGateway.HREF)
+	l.Lock()
+	defer l.Unlock()
 
-		// Kludgy IF to avoid deleting DNAT rules not created by us.
-		// If matches, let's skip it and continue the loop
-		if v.RuleType == "DNAT" &&
-			v.GatewayNatRule.OriginalIP == external &&
-			v.GatewayNatRule.TranslatedIP == internal &&
-			v.GatewayNatRule.OriginalPort == "any" &&
-			v.GatewayNatRule.TranslatedPort == "any" &&
-			v.GatewayNatRule.Protocol == "any" &&
-			v.GatewayNatRule.Interface.HREF == uplinkif {
-			continue
+	for attempt := 0; attempt < edgeGatewayServiceAttempts; attempt++ {
+
+		if err := e.Refresh(); err != nil {
+			return Task{}, err
 		}
 
-		// Kludgy IF to avoid deleting SNAT rules not created by us.
-		// If matches, let's skip it and continue the loop
-		if v.RuleType == "SNAT" &&
-			v.GatewayNatRule.OriginalIP == internal &&
-			v.GatewayNatRule.TranslatedIP == external &&
-			v.GatewayNatRule.Interface.HREF == uplinkif {
-			continue
+		before, err := e.gatewayServices()
+		if err != nil {
+			return Task{}, err
 		}
 
-		// If doesn't match the above IFs, it's something we need to preserve,
-		// let's add it to the new NatService struct
-		newnatservice.NatRule = append(newnatservice.NatRule, newedgeconfig.NatService.NatRule[k])
+		// Decode twice to get a deep copy to mutate next to the original
+		original, err := copyServices(before)
+		if err != nil {
+			return Task{}, err
+		}
+		config, err := copyServices(before)
+		if err != nil {
+			return Task{}, err
+		}
 
-	}
+		if err := mutate(config); err != nil {
+			return Task{}, err
+		}
 
-	// Fill the new NatService Section
-	newedgeconfig.NatService = newnatservice
+		changed, err := changedServices(original, config)
+		if err != nil {
+			return Task{}, err
+		}
 
-	// Take care of the Firewall service
-	newfwservice := &types.FirewallService{}
+		if len(changed) == 0 {
+			return completedTask(e.c), nil
+		}
 
-	// Copy over the firewall configuration
-	newfwservice.IsEnabled = newedgeconfig.FirewallService.IsEnabled
-	newfwservice.DefaultAction = newedgeconfig.FirewallService.DefaultAction
-	newfwservice.LogDefaultAction = newedgeconfig.FirewallService.LogDefaultAction
+		// Make sure nobody changed the gateway while mutate was running
+		if err := e.Refresh(); err != nil {
+			return Task{}, err
+		}
 
-	for k, v := range newedgeconfig.FirewallService.FirewallRule {
+		current, err := e.gatewayServices()
+		if err != nil {
+			return Task{}, err
+		}
 
-		// Kludgy IF to avoid deleting inbound FW rules not created by us.
-		// If matches, let's skip it and continue the loop
-		if v.Policy == "allow" &&
-			v.Protocols.Any == true &&
-			v.DestinationPortRange == "Any" &&
-			v.SourcePortRange == "Any" &&
-			v.SourceIP == "Any" &&
-			v.DestinationIP == external {
+		if !bytes.Equal(before, current) {
 			continue
 		}
 
-		// Kludgy IF to avoid deleting outbound FW rules not created by us.
-		// If matches, let's skip it and continue the loop
-		if v.Policy == "allow" &&
-			v.Protocols.Any == true &&
-			v.DestinationPortRange == "Any" &&
-			v.SourcePortRange == "Any" &&
-			v.SourceIP == internal &&
-			v.DestinationIP == "Any" {
-			continue
+		task, err := e.configureServices(config)
+		if err != nil {
+			return Task{}, err
 		}
 
-		// If doesn't match the above IFs, it's something we need to preserve,
-		// let's add it to the new FirewallService struct
-		newfwservice.FirewallRule = append(newfwservice.FirewallRule, newedgeconfig.FirewallService.FirewallRule[k])
+		if err := task.WaitTaskCompletion(); err != nil {
+			return task, err
+		}
 
-	}
+		// A configuration submitted by another client at the same time can
+		// replace ours, which shows as a changed service being back to its
+		// original state
+		if err := e.Refresh(); err != nil {
+			return task, err
+		}
 
-	// Fill the new FirewallService Section
-	newedgeconfig.FirewallService = newfwservice
+		if e.EdgeGateway.Configuration == nil || e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration == nil {
+			return task, fmt.Errorf("edge gateway has no service configuration")
+		}
 
-	// Fix
-	newedgeconfig.NatService.IsEnabled = true
+		applied, err := changedServices(original, e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration)
+		if err != nil {
+			return task, err
+		}
 
-	output, err := xml.MarshalIndent(newedgeconfig, "  ", "    ")
-	if err != nil {
-		fmt.Printf("error: %v\n", err)
+		if !includesServices(applied, changed) {
+			continue
+		}
+
+		return task, nil
 	}
 
-	debug := os.Getenv("GOVCLOUDAIR_DEBUG")
+	return Task{}, fmt.Errorf("edge gateway services changed concurrently, giving up after %d attempts", edgeGatewayServiceAttempts)
+}
 
-	if debug == "true" {
-		fmt.Printf("\n\nXML DEBUG: %s\n\n", string(output))
-	}
+// Remove1to1Mapping removes a 1 to 1 mapping on the uplink used for the
+// default route of the gateway
+func (e *EdgeGateway) Remove1to1Mapping(internal, external string) (Task, error) {
+	return e.Remove1to1MappingOn("", internal, external)
+}
 
-	b := bytes.NewBufferString(xml.Header + string(output))
+// Remove1to1MappingOn removes a 1 to 1 mapping on the uplink connected to
+// network, by name or HREF, or on the default uplink when network is empty
+func (e *EdgeGateway) Remove1to1MappingOn(network, internal, external string) (Task, error) {
 
-	s, _ := url.ParseRequestURI(e.EdgeGateway.HREF)
-	s.Path += "/action/configureServices"
+	return e.ModifyServices(func(newedgeconfig *types.GatewayFeatures) error {
 
-	req := e.c.NewRequest(map[string]string{}, "POST", s, b)
+		uplinkif, err := e.mappingUplink(network)
+		if err != nil {
+			return err
+		}
 
-	req.Header.Add("Content-Type", "application/vnd.vmware.admin.edgeGatewayServiceConfiguration+xml")
+		// Take care of the NAT service
+		newnatservice := &types.NatService{}
+
+		if newedgeconfig.NatService != nil {
+			// Copy over the NAT configuration
+			newnatservice.IsEnabled = newedgeconfig.NatService.IsEnabled
+			newnatservice.NatType = newedgeconfig.NatService.NatType
+			newnatservice.Policy = newedgeconfig.NatService.Policy
+			newnatservice.ExternalIP = newedgeconfig.NatService.ExternalIP
+
+			for k, v := range newedgeconfig.NatService.NatRule {
+
+				// Kludgy IF to avoid deleting DNAT rules not created by us.
+				// If matches, let's skip it and continue the loop
+				if v.RuleType == "DNAT" &&
+					v.GatewayNatRule.OriginalIP == external &&
+					v.GatewayNatRule.TranslatedIP == internal &&
+					v.GatewayNatRule.OriginalPort == "any" &&
+					v.GatewayNatRule.TranslatedPort == "any" &&
+					v.GatewayNatRule.Protocol == "any" &&
+					v.GatewayNatRule.Interface.HREF == uplinkif {
+					continue
+				}
+
+				// Kludgy IF to avoid deleting SNAT rules not created by us.
+				// If matches, let's skip it and continue the loop
+				if v.RuleType == "SNAT" &&
+					v.GatewayNatRule.OriginalIP == internal &&
+					v.GatewayNatRule.TranslatedIP == external &&
+					v.GatewayNatRule.Interface.HREF == uplinkif {
+					continue
+				}
+
+				// If doesn't match the above IFs, it's something we need to preserve,
+				// let's add it to the new NatService struct
+				newnatservice.NatRule = append(newnatservice.NatRule, newedgeconfig.NatService.NatRule[k])
+
+			}
+		}
 
-	resp, err := checkResp(e.c.DoHTTP(req))
-	if err != nil {
-		return Task{}, fmt.Errorf("error reconfiguring Edge Gateway: %s", err)
-	}
+		// Fill the new NatService Section
+		newedgeconfig.NatService = newnatservice
+
+		// Take care of the Firewall service
+		newfwservice := &types.FirewallService{}
+
+		if newedgeconfig.FirewallService != nil {
+			// Copy over the firewall configuration
+			newfwservice.IsEnabled = newedgeconfig.FirewallService.IsEnabled
+			newfwservice.DefaultAction = newedgeconfig.FirewallService.DefaultAction
+			newfwservice.LogDefaultAction = newedgeconfig.FirewallService.LogDefaultAction
+
+			for k, v := range newedgeconfig.FirewallService.FirewallRule {
+
+				// Kludgy IF to avoid deleting inbound FW rules not created by us.
+				// If matches, let's skip it and continue the loop
+				if v.Policy == "allow" &&
+					v.Protocols.Any == true &&
+					v.DestinationPortRange == "Any" &&
+					v.SourcePortRange == "Any" &&
+					v.SourceIP == "Any" &&
+					v.DestinationIP == external {
+					continue
+				}
+
+				// Kludgy IF to avoid deleting outbound FW rules not created by us.
+				// If matches, let's skip it and continue the loop
+				if v.Policy == "allow" &&
+					v.Protocols.Any == true &&
+					v.DestinationPortRange == "Any" &&
+					v.SourcePortRange == "Any" &&
+					v.SourceIP == internal &&
+					v.DestinationIP == "Any" {
+					continue
+				}
+
+				// If doesn't match the above IFs, it's something we need to preserve,
+				// let's add it to the new FirewallService struct
+				newfwservice.FirewallRule = append(newfwservice.FirewallRule, newedgeconfig.FirewallService.FirewallRule[k])
+
+			}
+		}
 
-	task := NewTask(e.c)
+		// Fill the new FirewallService Section
+		newedgeconfig.FirewallService = newfwservice
 
-	if err = decodeBody(resp, task.Task); err != nil {
-		return Task{}, fmt.Errorf("error decoding Task response: %s", err)
-	}
+		// Fix
+		newedgeconfig.NatService.IsEnabled = true
 
-	// The request was successful
-	return *task, nil
+		return nil
+	})
 
 }
 
-// Create1to1Mapping creates a 1-to-1 mapping in the gateway
+// Create1to1Mapping creates a 1-to-1 mapping in the gateway on the uplink
+// used for its default route
 func (e *EdgeGateway) Create1to1Mapping(internal, external, description string) (Task, error) {
+	return e.Create1to1MappingOn("", internal, external, description)
+}
 
-	// Refresh EdgeGateway rules
-	err := e.Refresh()
-	if err != nil {
-		fmt.Printf("error: %v\n", err)
-	}
+// Create1to1MappingOn creates a 1-to-1 mapping in the gateway on the uplink
+// connected to network, by name or HREF, or on the default uplink when
+// network is empty. Rules of the mapping that already exist are kept as they
+// are, so a mapping that was partly applied is completed rather than doubled.
+func (e *EdgeGateway) Create1to1MappingOn(network, internal, external, description string) (Task, error) {
 
-	var uplinkif string
-	for _, gifs := range e.EdgeGateway.Configuration.GatewayInterfaces.GatewayInterface {
-		if gifs.InterfaceType == "uplink" {
-			uplinkif = gifs.Network.HREF
+	return e.ModifyServices(func(newedgeconfig *types.GatewayFeatures) error {
+
+		uplinkif, err := e.mappingUplink(network)
+		if err != nil {
+			return err
 		}
-	}
 
-	newedgeconfig := e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration
+		if newedgeconfig.NatService == nil {
+			newedgeconfig.NatService = &types.NatService{IsEnabled: true}
+		}
 
-	snat := &types.NatRule{
-		Description: description,
-		RuleType:    "SNAT",
-		IsEnabled:   true,
-		GatewayNatRule: &types.GatewayNatRule{
-			Interface: &types.Reference{
-				HREF: uplinkif,
+		if newedgeconfig.FirewallService == nil {
+			newedgeconfig.FirewallService = &types.FirewallService{IsEnabled: true}
+		}
+
+		snat := &types.NatRule{
+			Description: description,
+			RuleType:    "SNAT",
+			IsEnabled:   true,
+			GatewayNatRule: &types.GatewayNatRule{
+				Interface: &types.Reference{
+					HREF: uplinkif,
+				},
+				OriginalIP:   internal,
+				TranslatedIP: external,
+				Protocol:     "any",
 			},
-			OriginalIP:   internal,
-			TranslatedIP: external,
-			Protocol:     "any",
-		},
-	}
+		}
 
-	newedgeconfig.NatService.NatRule = append(newedgeconfig.NatService.NatRule, snat)
+		if !hasNatRule(newedgeconfig.NatService, snat) {
+			newedgeconfig.NatService.NatRule = append(newedgeconfig.NatService.NatRule, snat)
+		}
 
-	dnat := &types.NatRule{
-		Description: description,
-		RuleType:    "DNAT",
-		IsEnabled:   true,
-		GatewayNatRule: &types.GatewayNatRule{
-			Interface: &types.Reference{
-				HREF: uplinkif,
+		dnat := &types.NatRule{
+			Description: description,
+			RuleType:    "DNAT",
+			IsEnabled:   true,
+			GatewayNatRule: &types.GatewayNatRule{
+				Interface: &types.Reference{
+					HREF: uplinkif,
+				},
+				OriginalIP:     external,
+				OriginalPort:   "any",
+				TranslatedIP:   internal,
+				TranslatedPort: "any",
+				Protocol:       "any",
 			},
-			OriginalIP:     external,
-			OriginalPort:   "any",
-			TranslatedIP:   internal,
-			TranslatedPort: "any",
-			Protocol:       "any",
-		},
-	}
+		}
 
-	newedgeconfig.NatService.NatRule = append(newedgeconfig.NatService.NatRule, dnat)
-
-	fwin := &types.FirewallRule{
-		Description: description,
-		IsEnabled:   true,
-		Policy:      "allow",
-		Protocols: &types.FirewallRuleProtocols{
-			Any: true,
-		},
-		DestinationPortRange: "Any",
-		DestinationIP:        external,
-		SourcePortRange:      "Any",
-		SourceIP:             "Any",
-		EnableLogging:        false,
-	}
+		if !hasNatRule(newedgeconfig.NatService, dnat) {
+			newedgeconfig.NatService.NatRule = append(newedgeconfig.NatService.NatRule, dnat)
+		}
 
-	newedgeconfig.FirewallService.FirewallRule = append(newedgeconfig.FirewallService.FirewallRule, fwin)
-
-	fwout := &types.FirewallRule{
-		Description: description,
-		IsEnabled:   true,
-		Policy:      "allow",
-		Protocols: &types.FirewallRuleProtocols{
-			Any: true,
-		},
-		DestinationPortRange: "Any",
-		DestinationIP:        "Any",
-		SourcePortRange:      "Any",
-		SourceIP:             internal,
-		EnableLogging:        false,
-	}
+		fwin := &types.FirewallRule{
+			Description: description,
+			IsEnabled:   true,
+			Policy:      "allow",
+			Protocols: &types.FirewallRuleProtocols{
+				Any: true,
+			},
+			DestinationPortRange: "Any",
+			DestinationIP:        external,
+			SourcePortRange:      "Any",
+			SourceIP:             "Any",
+			EnableLogging:        false,
+		}
 
-	newedgeconfig.FirewallService.FirewallRule = append(newedgeconfig.FirewallService.FirewallRule, fwout)
+		if !hasFirewallRule(newedgeconfig.FirewallService, fwin) {
+			newedgeconfig.FirewallService.FirewallRule = append(newedgeconfig.FirewallService.FirewallRule, fwin)
+		}
 
-	output, err := xml.MarshalIndent(newedgeconfig, "  ", "    ")
-	if err != nil {
-		fmt.Printf("error: %v\n", err)
-	}
+		fwout := &types.FirewallRule{
+			Description: description,
+			IsEnabled:   true,
+			Policy:      "allow",
+			Protocols: &types.FirewallRuleProtocols{
+				Any: true,
+			},
+			DestinationPortRange: "Any",
+			DestinationIP:        "Any",
+			SourcePortRange:      "Any",
+			SourceIP:             internal,
+			EnableLogging:        false,
+		}
 
-	debug := os.Getenv("GOVCLOUDAIR_DEBUG")
+		if !hasFirewallRule(newedgeconfig.FirewallService, fwout) {
+			newedgeconfig.FirewallService.FirewallRule = append(newedgeconfig.FirewallService.FirewallRule, fwout)
+		}
 
-	if debug == "true" {
-		fmt.Printf("\n\nXML DEBUG: %s\n\n", string(output))
-	}
+		return nil
+	})
 
-	b := bytes.NewBufferString(xml.Header + string(output))
+}
 
-	s, _ := url.ParseRequestURI(e.EdgeGateway.HREF)
-	s.Path += "/action/configureServices"
+// hasNatRule reports whether nat has a rule of the same type and description
+// as rule, translating the same addresses on the same interface
+func hasNatRule(nat *types.NatService, rule *types.NatRule) bool {
+	for _, r := range nat.NatRule {
+		if r.RuleType != rule.RuleType || r.Description != rule.Description || r.GatewayNatRule == nil {
+			continue
+		}
+		if r.GatewayNatRule.Interface == nil || r.GatewayNatRule.Interface.HREF != rule.GatewayNatRule.Interface.HREF {
+			continue
+		}
+		if r.GatewayNatRule.OriginalIP == rule.GatewayNatRule.OriginalIP && r.GatewayNatRule.TranslatedIP == rule.GatewayNatRule.TranslatedIP {
+			return true
+		}
+	}
+	return false
+}
 
-	req := e.c.NewRequest(map[string]string{}, "POST", s, b)
+// hasFirewallRule reports whether fw has a rule with the same description,
+// source and destination addresses as rule
+func hasFirewallRule(fw *types.FirewallService, rule *types.FirewallRule) bool {
+	for _, r := range fw.FirewallRule {
+		if r.Description == rule.Description && r.SourceIP == rule.SourceIP && r.DestinationIP == rule.DestinationIP {
+			return true
+		}
+	}
+	return false
+}
 
-	req.Header.Add("Content-Type", "application/vnd.vmware.admin.edgeGatewayServiceConfiguration+xml")
+// mappingUplink returns the HREF of the network of the uplink connected to
+// network, or of the default uplink when network is empty
+func (e *EdgeGateway) mappingUplink(network string) (string, error) {
 
-	resp, err := checkResp(e.c.DoHTTP(req))
+	var uplink *types.GatewayInterface
+	var err error
+	if network == "" {
+		uplink, err = e.DefaultUplink()
+	} else {
+		uplink, err = e.FindUplink(network)
+	}
 	if err != nil {
-		return Task{}, fmt.Errorf("error reconfiguring Edge Gateway: %s", err)
+		return "", err
 	}
 
-	task := NewTask(e.c)
+	return uplink.Network.HREF, nil
+}
+
+// configureServices submits the full service configuration of this gateway,
+// vCloud replaces all the services with the ones in config.
+func (e *EdgeGateway) configureServices(config *types.GatewayFeatures) (Task, error) {
 
-	if err = decodeBody(resp, task.Task); err != nil {
-		return Task{}, fmt.Errorf("error decoding Task response: %s", err)
+	s, err := url.ParseRequestURI(e.EdgeGateway.HREF)
+	if err != nil {
+		return Task{}, fmt.Errorf("error parsing Edge Gateway href: %s", err)
 	}
+	s.Path += "/action/configureServices"
 
-	// The request was successful
-	return *task, nil
+	task, err := executeTaskRequest(e.c, types.HTTPPost, s, types.MimeEdgeGatewayServiceConfiguration, config)
+	if err != nil {
+		return Task{}, fmt.Errorf("error reconfiguring Edge Gateway: %s", err)
+	}
 
+	// The request was successful
+	return task, nil
 }
@@ -5,11 +5,83 @@
 package govcloudair
 
 import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
 )
 
+// edgeGatewayState is a gateway whose service configuration changes with each
+// configureServices call
+type edgeGatewayState struct {
+	sync.Mutex
+	head, services, tail string
+	// updated is the last gateway sent in an update
+	updated *types.EdgeGateway
+	// onRead, when set, is called on each read of the gateway and returns the
+	// service configuration to serve from then on, like another client would
+	onRead func(services string) string
+}
+
+func newEdgeGatewayState(edgegateway string) *edgeGatewayState {
+	start := strings.Index(edgegateway, "<EdgeGatewayServiceConfiguration>")
+	end := strings.Index(edgegateway, "</EdgeGatewayServiceConfiguration>") + len("</EdgeGatewayServiceConfiguration>")
+	return &edgeGatewayState{head: edgegateway[:start], services: edgegateway[start:end], tail: edgegateway[end:]}
+}
+
+// config decodes the current service configuration
+func (s *edgeGatewayState) config() *types.GatewayFeatures {
+	s.Lock()
+	defer s.Unlock()
+	config := new(types.GatewayFeatures)
+	xml.Unmarshal([]byte(s.services), config)
+	return config
+}
+
+func (s *edgeGatewayState) handler(cc *callCounter) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		s.Lock()
+		defer s.Unlock()
+
+		var body string
+		switch r.URL.Path {
+		case "/api/vdc/00000000-0000-0000-0000-000000000000/edgeGateways":
+			body = edgegatewayqueryresultsExample
+		case "/api/admin/edgeGateway/00000000-0000-0000-0000-000000000000":
+			if r.Method == "PUT" {
+				s.updated = new(types.EdgeGateway)
+				xml.NewDecoder(r.Body).Decode(s.updated)
+				body = taskExample
+				break
+			}
+			if s.onRead != nil {
+				s.services = s.onRead(s.services)
+			}
+			body = s.head + s.services + s.tail
+		case "/api/admin/edgeGateway/00000000-0000-0000-0000-000000000000/action/configureServices":
+			submitted, _ := ioutil.ReadAll(r.Body)
+			s.services = strings.TrimPrefix(string(submitted), xml.Header)
+			body = taskExample
+		case "/api/task/1b8f926c-eff5-4bea-9b13-4e49bdd50c05":
+			body = taskExample
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		cc.Inc()
+		rw.WriteHeader(http.StatusOK)
+		rw.Write([]byte(strings.Replace(body, "localhost:4444", r.Host, -1)))
+	})
+}
+
 func Test_Refresh(t *testing.T) {
 	cc := new(callCounter)
 	responses := map[string]testResponse{
@@ -32,29 +104,170 @@ func Test_Refresh(t *testing.T) {
 
 func Test_1to1Mappings(t *testing.T) {
 	cc := new(callCounter)
-	responses := map[string]testResponse{
-		"/api/vdc/00000000-0000-0000-0000-000000000000/edgeGateways":                           {200, nil, edgegatewayqueryresultsExample},
-		"/api/admin/edgeGateway/00000000-0000-0000-0000-000000000000":                          {200, nil, edgegatewayExample},
-		"/api/admin/edgeGateway/00000000-0000-0000-0000-000000000000/action/configureServices": {200, nil, taskExample},
-	}
+	state := newEdgeGatewayState(edgegatewayExample)
 
-	ctx, err := setupTestContext(authHandler(testHandler(responses, cc)))
+	ctx, err := setupTestContext(authHandler(state.handler(cc)))
 	if assert.NoError(t, err) {
 
 		edge, err := ctx.VDC.FindEdgeGateway("M916272752-5793")
 		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
 			assert.Equal(t, "M916272752-5793", edge.EdgeGateway.Name)
 
+			// refresh, re-read, submit, task and final refresh
 			_, err = edge.Create1to1Mapping("10.0.0.1", "20.0.0.2", "description")
-			if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				config := state.config()
+				assert.Len(t, config.NatService.NatRule, 6)
+				assert.Len(t, config.FirewallService.FirewallRule, 6)
+				assert.Len(t, edge.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration.NatService.NatRule, 6)
+
+				// the mapping is already there
+				task, err := edge.Create1to1Mapping("10.0.0.1", "20.0.0.2", "description")
+				if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+					assert.NoError(t, task.WaitTaskCompletion())
+				}
 
 				_, err = edge.Remove1to1Mapping("10.0.0.1", "20.0.0.2")
-				assert.NoError(t, err)
-				assert.Equal(t, 2, cc.Pop())
+				if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+					config = state.config()
+					assert.Len(t, config.NatService.NatRule, 4)
+					assert.Len(t, config.FirewallService.FirewallRule, 4)
+				}
+			}
+
+			// another client replaces the NAT rules while the mapping is
+			// applied, the retry only adds the missing NAT rules
+			natService := regexp.MustCompile(`(?s)<NatService>.*</NatService>`)
+			original := natService.FindString(state.services)
+			reads := 0
+			state.onRead = func(services string) string {
+				reads++
+				if reads == 3 {
+					return natService.ReplaceAllLiteralString(services, original)
+				}
+				return services
+			}
+
+			_, err = edge.Create1to1Mapping("10.0.0.1", "20.0.0.2", "description")
+			if assert.NoError(t, err) && assert.Equal(t, 10, cc.Pop()) {
+				config := state.config()
+				assert.Len(t, config.NatService.NatRule, 6)
+				assert.Len(t, config.FirewallService.FirewallRule, 6)
+			}
+		}
+	}
+
+}
+
+func Test_ModifyServices(t *testing.T) {
+	cc := new(callCounter)
+	state := newEdgeGatewayState(edgegatewayExample)
+
+	ctx, err := setupTestContext(authHandler(state.handler(cc)))
+	if assert.NoError(t, err) {
+
+		edge, err := ctx.VDC.FindEdgeGateway("M916272752-5793")
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+
+			task, err := edge.ModifyServices(func(config *types.GatewayFeatures) error { return nil })
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.NoError(t, task.WaitTaskCompletion())
+				assert.Equal(t, 0, cc.Pop())
+			}
+
+			_, err = edge.ModifyServices(func(config *types.GatewayFeatures) error { return fmt.Errorf("no") })
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+
+			disableFirewall := func(config *types.GatewayFeatures) error {
+				config.FirewallService.IsEnabled = !config.FirewallService.IsEnabled
+				return nil
+			}
+
+			// another client changes the gateway while mutate runs
+			reads := 0
+			state.onRead = func(services string) string {
+				reads++
+				if reads == 2 {
+					return strings.Replace(services, "<DefaultAction>drop</DefaultAction>", "<DefaultAction>allow</DefaultAction>", 1)
+				}
+				return services
+			}
+
+			_, err = edge.ModifyServices(disableFirewall)
+			if assert.NoError(t, err) && assert.Equal(t, 7, cc.Pop()) {
+				config := state.config()
+				assert.False(t, config.FirewallService.IsEnabled)
+				assert.Equal(t, "allow", config.FirewallService.DefaultAction)
+			}
+
+			// another client submits its own configuration over ours
+			reads = 0
+			state.onRead = func(services string) string {
+				reads++
+				if reads == 3 {
+					return strings.Replace(services, "<IsEnabled>true</IsEnabled>", "<IsEnabled>false</IsEnabled>", 1)
+				}
+				return services
+			}
+
+			_, err = edge.ModifyServices(disableFirewall)
+			if assert.NoError(t, err) && assert.Equal(t, 10, cc.Pop()) {
+				assert.True(t, state.config().FirewallService.IsEnabled)
 			}
+
+			// another client keeps disabling the firewall
+			reads = 0
+			state.onRead = func(services string) string {
+				reads++
+				return firewallEnabled.ReplaceAllString(services, "${1}false$2")
+			}
+
+			_, err = edge.ModifyServices(func(config *types.GatewayFeatures) error {
+				config.FirewallService.IsEnabled = true
+				return nil
+			})
+			assert.Error(t, err)
+			assert.Equal(t, 15, cc.Pop())
+			assert.Equal(t, 9, reads)
 		}
 	}
+}
 
+var firewallEnabled = regexp.MustCompile(`(<FirewallService[^>]*>\s*<IsEnabled>)true(</IsEnabled>)`)
+
+func Test_ModifyServicesConcurrently(t *testing.T) {
+	cc := new(callCounter)
+	state := newEdgeGatewayState(edgegatewayExample)
+
+	ctx, err := setupTestContext(authHandler(state.handler(cc)))
+	if assert.NoError(t, err) {
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			edge, err := ctx.VDC.FindEdgeGateway("M916272752-5793")
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			wg.Add(1)
+			go func(edge EdgeGateway, i int) {
+				defer wg.Done()
+				_, err := edge.ModifyServices(func(config *types.GatewayFeatures) error {
+					config.FirewallService.FirewallRule = append(config.FirewallService.FirewallRule, &types.FirewallRule{
+						Description: fmt.Sprintf("rule %d", i),
+						Policy:      FirewallPolicyAllow,
+						Protocols:   &types.FirewallRuleProtocols{Any: true},
+					})
+					return nil
+				})
+				assert.NoError(t, err)
+			}(edge, i)
+		}
+		wg.Wait()
+
+		assert.Len(t, state.config().FirewallService.FirewallRule, 7)
+	}
 }
 
 var edgegatewayqueryresultsExample = `
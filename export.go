@@ -0,0 +1,401 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"archive/tar"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// ExportProgress is the state of an export
+type ExportProgress struct {
+	// File is the name of the file being downloaded.
+	File      string
+	FileBytes int64
+	FileSize  int64
+	// TotalBytes and TotalSize count every file of the package but the OVF
+	// descriptor.
+	TotalBytes int64
+	TotalSize  int64
+}
+
+// ExportOptions controls vApp template exports
+type ExportOptions struct {
+	// Progress when set is called as the files of the package are written.
+	Progress func(ExportProgress)
+}
+
+// exportSink is where the files of an exported package are written
+type exportSink interface {
+	// create returns the writer for the file named name of size bytes
+	create(name string, size int64) (io.WriteCloser, error)
+}
+
+// dirSink writes the files of a package to a directory
+type dirSink string
+
+func (d dirSink) create(name string, size int64) (io.WriteCloser, error) {
+
+	p := filepath.Join(string(d), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return nil, err
+	}
+
+	return os.Create(p)
+}
+
+// tarSink writes the files of a package to a tar archive
+type tarSink struct {
+	tw *tar.Writer
+}
+
+// nopWriteCloser lets the entry of a tar archive be closed like a file
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func (t tarSink) create(name string, size int64) (io.WriteCloser, error) {
+
+	hdr := &tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     size,
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	}
+	if err := t.tw.WriteHeader(hdr); err != nil {
+		return nil, err
+	}
+
+	return nopWriteCloser{t.tw}, nil
+}
+
+// progressWriter reports the bytes written through it
+type progressWriter struct {
+	progress *ExportProgress
+	report   func(ExportProgress)
+}
+
+func (p progressWriter) Write(b []byte) (int, error) {
+
+	p.progress.FileBytes += int64(len(b))
+	p.progress.TotalBytes += int64(len(b))
+	if p.report != nil {
+		p.report(*p.progress)
+	}
+
+	return len(b), nil
+}
+
+// ExportOVF downloads this vApp template as an OVF package into dir. The
+// descriptor is named after the vApp template, the other files keep the
+// names the descriptor gives them.
+func (v *VAppTemplate) ExportOVF(dir string, opts ExportOptions) error {
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating export directory: %s", err)
+	}
+
+	return v.export(dirSink(dir), opts)
+}
+
+// ExportOVA downloads this vApp template as an OVA archive written to w
+func (v *VAppTemplate) ExportOVA(w io.Writer, opts ExportOptions) error {
+
+	tw := tar.NewWriter(w)
+	if err := v.export(tarSink{tw}, opts); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("error writing OVA archive: %s", err)
+	}
+
+	return nil
+}
+
+// EnableDownload makes vCloud prepare this vApp template for download
+func (v *VAppTemplate) EnableDownload() (Task, error) {
+
+	s, err := url.ParseRequestURI(v.VAppTemplate.HREF)
+	if err != nil {
+		return Task{}, fmt.Errorf("error parsing vApp template href: %s", err)
+	}
+	s.Path += "/action/enableDownload"
+
+	task, err := executeTaskRequest(v.c, types.HTTPPost, s, "", nil)
+	if err != nil {
+		return Task{}, fmt.Errorf("error enabling download of vApp template: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
+
+// DisableDownload releases the files vCloud prepared for the download of
+// this vApp template
+func (v *VAppTemplate) DisableDownload() error {
+
+	s, err := url.ParseRequestURI(v.VAppTemplate.HREF)
+	if err != nil {
+		return fmt.Errorf("error parsing vApp template href: %s", err)
+	}
+	s.Path += "/action/disableDownload"
+
+	if err = executeRequest(v.c, types.HTTPPost, s, "", nil, nil); err != nil {
+		return fmt.Errorf("error disabling download of vApp template: %s", err)
+	}
+
+	// The request was successful
+	return nil
+}
+
+// descriptorName returns the name of the OVF descriptor of an exported
+// package
+func (v *VAppTemplate) descriptorName() string {
+
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' {
+			return '_'
+		}
+		return r
+	}, v.VAppTemplate.Name)
+
+	if name == "" || name == "." || name == ".." {
+		name = "descriptor"
+	}
+
+	return name + ".ovf"
+}
+
+// downloadFile returns the file of this vApp template named name, as listed
+// by vCloud, or nil
+func (v *VAppTemplate) downloadFile(name string) *types.File {
+
+	if v.VAppTemplate.Files != nil {
+		for _, f := range v.VAppTemplate.Files.File {
+			if f.Name == name {
+				return f
+			}
+		}
+	}
+
+	return nil
+}
+
+// downloadURL returns the URL the file named name is downloaded from. The
+// link of the file is used when vCloud lists it, the name is resolved
+// against the URL of the descriptor otherwise.
+func (v *VAppTemplate) downloadURL(name string, descriptor *url.URL) (*url.URL, error) {
+
+	if f := v.downloadFile(name); f != nil {
+		if l := f.Link.Find(func(l *types.Link) bool { return l.Rel == types.RelDownloadDefault }); l != nil {
+			return url.ParseRequestURI(l.HREF)
+		}
+	}
+
+	if descriptor == nil {
+		return nil, fmt.Errorf("can't find the download link of file: %s", name)
+	}
+
+	return descriptor.ResolveReference(&url.URL{Path: name}), nil
+}
+
+// descriptorURL returns the URL the OVF descriptor of this vApp template is
+// downloaded from
+func (v *VAppTemplate) descriptorURL() (*url.URL, error) {
+
+	if v.downloadFile(ovfDescriptorName) != nil {
+		return v.downloadURL(ovfDescriptorName, nil)
+	}
+
+	l := v.VAppTemplate.Link.Find(func(l *types.Link) bool { return l.Rel == types.RelDownloadDefault })
+	if l == nil {
+		return nil, fmt.Errorf("can't find the OVF descriptor of vApp template %s, is download enabled?", v.VAppTemplate.Name)
+	}
+
+	return url.ParseRequestURI(l.HREF)
+}
+
+// checksumHash returns the hash checksum was computed with, going by its
+// length, or nil when it isn't known
+func checksumHash(checksum string) hash.Hash {
+
+	switch len(checksum) {
+	case 2 * sha1.Size:
+		return sha1.New()
+	case 2 * sha256.Size:
+		return sha256.New()
+	}
+
+	return nil
+}
+
+// download writes the file at u to w, checking that it has size bytes when
+// size isn't negative, and that it matches checksum when checksum is a SHA-1
+// or SHA-256 digest
+func (v *VAppTemplate) download(u *url.URL, w io.Writer, size int64, checksum string) error {
+
+	req := v.c.NewRequest(map[string]string{}, types.HTTPGet, u, nil)
+
+	resp, err := checkResp(v.c.DoHTTP(req))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	h := checksumHash(checksum)
+	if h != nil {
+		w = io.MultiWriter(w, h)
+	}
+
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if size >= 0 && n != size {
+		return fmt.Errorf("received %d bytes, expected %d", n, size)
+	}
+
+	if h != nil && !strings.EqualFold(hex.EncodeToString(h.Sum(nil)), checksum) {
+		return fmt.Errorf("checksum mismatch, expected %s", checksum)
+	}
+
+	return nil
+}
+
+// fileSize returns the size of the file at u as announced by the server
+func (v *VAppTemplate) fileSize(u *url.URL) (int64, error) {
+
+	req := v.c.NewRequest(map[string]string{}, "HEAD", u, nil)
+
+	resp, err := checkResp(v.c.DoHTTP(req))
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	if resp.ContentLength < 0 {
+		return 0, fmt.Errorf("unknown size")
+	}
+
+	return resp.ContentLength, nil
+}
+
+// export enables the download of this vApp template, writes its descriptor
+// and files to sink and disables the download again
+func (v *VAppTemplate) export(sink exportSink, opts ExportOptions) (err error) {
+
+	task, err := v.EnableDownload()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := v.DisableDownload(); e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	if err = task.WaitTaskCompletion(); err != nil {
+		return fmt.Errorf("error enabling download of vApp template: %s", err)
+	}
+
+	if err = v.Refresh(); err != nil {
+		return err
+	}
+
+	descriptorURL, err := v.descriptorURL()
+	if err != nil {
+		return err
+	}
+
+	req := v.c.NewRequest(map[string]string{}, types.HTTPGet, descriptorURL, nil)
+
+	resp, err := checkResp(v.c.DoHTTP(req))
+	if err != nil {
+		return fmt.Errorf("error retrieving OVF descriptor: %s", err)
+	}
+
+	descriptor, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("error retrieving OVF descriptor: %s", err)
+	}
+
+	refs, err := parseOVFDescriptor(descriptor)
+	if err != nil {
+		return err
+	}
+
+	urls := make([]*url.URL, len(refs))
+	var progress ExportProgress
+	for i, ref := range refs {
+		if urls[i], err = v.downloadURL(ref.Name, descriptorURL); err != nil {
+			return err
+		}
+		// The size of the entries of an archive is needed up front
+		if ref.Size <= 0 {
+			if refs[i].Size, err = v.fileSize(urls[i]); err != nil {
+				return fmt.Errorf("error retrieving the size of %s: %s", ref.Name, err)
+			}
+		}
+		progress.TotalSize += refs[i].Size
+	}
+
+	w, err := sink.create(v.descriptorName(), int64(len(descriptor)))
+	if err == nil {
+		_, err = w.Write(descriptor)
+		if e := w.Close(); err == nil {
+			err = e
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("error writing OVF descriptor: %s", err)
+	}
+
+	for i, ref := range refs {
+		var checksum string
+		if f := v.downloadFile(ref.Name); f != nil {
+			checksum = f.Checksum
+		}
+
+		progress.File, progress.FileBytes, progress.FileSize = ref.Name, 0, ref.Size
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+
+		w, err := sink.create(ref.Name, ref.Size)
+		if err != nil {
+			return fmt.Errorf("error writing %s: %s", ref.Name, err)
+		}
+
+		err = v.download(urls[i], io.MultiWriter(w, progressWriter{&progress, opts.Progress}), ref.Size, checksum)
+		if e := w.Close(); err == nil {
+			err = e
+		}
+		if err != nil {
+			return fmt.Errorf("error downloading %s: %s", ref.Name, err)
+		}
+	}
+
+	// The request was successful
+	return nil
+}
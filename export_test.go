@@ -0,0 +1,133 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// exportResponses serve a vApp template with download enabled, whose only
+// disk is disk
+func exportResponses(disk []byte) map[string]map[string]testResponse {
+	sum := sha1.Sum(disk)
+	return map[string]map[string]testResponse{
+		"GET": {
+			"/api/vAppTemplate/vappTemplate-66666666-6666-6666-6666-666666666666": {200, nil, fmt.Sprintf(exportTemplateExample, len(disk), hex.EncodeToString(sum[:]))},
+			"/api/task/1b8f926c-eff5-4bea-9b13-4e49bdd50c05":                      {200, nil, taskExample},
+			"/transfer/6666/descriptor.ovf":                                       {200, nil, fmt.Sprintf(ovfDescriptorExample, len(disk))},
+			"/transfer/6666/disk1.vmdk":                                           {200, nil, string(disk)},
+		},
+		"POST": {
+			"/api/vAppTemplate/vappTemplate-66666666-6666-6666-6666-666666666666/action/enableDownload":  {202, nil, taskExample},
+			"/api/vAppTemplate/vappTemplate-66666666-6666-6666-6666-666666666666/action/disableDownload": {204, nil, ""},
+		},
+	}
+}
+
+func Test_ExportOVF(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "govcloudair")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	expected := bytes.Repeat([]byte("0123456789"), 1000)
+	responses := exportResponses(expected)
+	submitted := make(map[string][]byte)
+	ctx, err := setupTestContext(authHandler(testMethodHandler(responses, submitted, new(callCounter))))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	vapptemplate := NewVAppTemplate(ctx.Client)
+	vapptemplate.VAppTemplate.HREF = ctx.Server.URL + "/api/vAppTemplate/vappTemplate-66666666-6666-6666-6666-666666666666"
+
+	var last ExportProgress
+	opts := ExportOptions{Progress: func(p ExportProgress) { last = p }}
+
+	if assert.NoError(t, vapptemplate.ExportOVF(dir, opts)) {
+		descriptor, err := ioutil.ReadFile(filepath.Join(dir, "centos_7.ovf"))
+		if assert.NoError(t, err) {
+			assert.Contains(t, string(descriptor), `ovf:href="disk1.vmdk"`)
+		}
+		disk, err := ioutil.ReadFile(filepath.Join(dir, "disk1.vmdk"))
+		if assert.NoError(t, err) {
+			assert.Equal(t, expected, disk)
+		}
+		assert.Equal(t, ExportProgress{File: "disk1.vmdk", FileBytes: 10000, FileSize: 10000, TotalBytes: 10000, TotalSize: 10000}, last)
+	}
+
+	// Download is disabled once the export is done
+	disable := "POST /api/vAppTemplate/vappTemplate-66666666-6666-6666-6666-666666666666/action/disableDownload"
+	assert.Contains(t, submitted, disable)
+
+	// A corrupted disk is reported, download is disabled all the same
+	delete(submitted, disable)
+	responses["GET"]["/transfer/6666/disk1.vmdk"] = testResponse{200, nil, "X" + string(expected[1:])}
+	err = vapptemplate.ExportOVF(dir, ExportOptions{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "checksum mismatch")
+	}
+	assert.Contains(t, submitted, disable)
+}
+
+func Test_ExportOVA(t *testing.T) {
+
+	expected := bytes.Repeat([]byte("0123456789"), 1000)
+	submitted := make(map[string][]byte)
+	ctx, err := setupTestContext(authHandler(testMethodHandler(exportResponses(expected), submitted, new(callCounter))))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	vapptemplate := NewVAppTemplate(ctx.Client)
+	vapptemplate.VAppTemplate.HREF = ctx.Server.URL + "/api/vAppTemplate/vappTemplate-66666666-6666-6666-6666-666666666666"
+
+	ova := new(bytes.Buffer)
+	if !assert.NoError(t, vapptemplate.ExportOVA(ova, ExportOptions{})) {
+		return
+	}
+	assert.Contains(t, submitted, "POST /api/vAppTemplate/vappTemplate-66666666-6666-6666-6666-666666666666/action/disableDownload")
+
+	// The descriptor comes first, then the disk
+	tr := tar.NewReader(ova)
+	hdr, err := tr.Next()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "centos_7.ovf", hdr.Name)
+	}
+	hdr, err = tr.Next()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "disk1.vmdk", hdr.Name)
+		disk, _ := ioutil.ReadAll(tr)
+		assert.Equal(t, expected, disk)
+	}
+	_, err = tr.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+// exportTemplateExample is a vApp template with download enabled, listing a
+// disk of the given size and SHA-1 checksum
+var exportTemplateExample = `<?xml version="1.0" encoding="UTF-8"?>
+<VAppTemplate xmlns="http://www.vmware.com/vcloud/v1.5" ovfDescriptorUploaded="true" goldMaster="false" status="1" name="centos/7" id="urn:vcloud:vapptemplate:66666666-6666-6666-6666-666666666666" href="http://localhost:4444/api/vAppTemplate/vappTemplate-66666666-6666-6666-6666-666666666666" type="application/vnd.vmware.vcloud.vAppTemplate+xml">
+    <Link rel="download:default" href="http://localhost:4444/transfer/6666/descriptor.ovf"/>
+    <Files>
+        <File size="%d" checksum="%s" name="disk1.vmdk">
+            <Link rel="download:default" href="http://localhost:4444/transfer/6666/disk1.vmdk"/>
+        </File>
+    </Files>
+</VAppTemplate>
+`
@@ -0,0 +1,418 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// UplinkSubnet is a subnet of an external network an edge gateway is
+// connected to, with the external IPs sub-allocated to the gateway
+type UplinkSubnet struct {
+	Network   *types.Reference // The external network
+	Gateway   string           // Gateway of the subnet
+	Netmask   string           // Netmask of the subnet
+	IPAddress string           // Address of the gateway interface itself
+	Allocated []*types.IPRange // Ranges sub-allocated to the gateway
+	InUse     []string         // Sub-allocated addresses used by NAT rules or load balancer virtual servers
+}
+
+// FreeIPs returns the sub-allocated addresses of this subnet that are neither
+// used by a rule nor by the gateway interface, in ascending order
+func (s UplinkSubnet) FreeIPs() []string {
+
+	used := make(map[uint32]bool, len(s.InUse)+1)
+	for _, ip := range append([]string{s.IPAddress}, s.InUse...) {
+		if n, ok := ipToUint32(ip); ok {
+			used[n] = true
+		}
+	}
+
+	var free []uint32
+	seen := make(map[uint32]bool)
+	for _, r := range s.Allocated {
+		start, ok1 := ipToUint32(r.StartAddress)
+		end, ok2 := ipToUint32(r.EndAddress)
+		if !ok1 || !ok2 {
+			continue
+		}
+		for n := start; n <= end && n >= start; n++ {
+			if !used[n] && !seen[n] {
+				seen[n] = true
+				free = append(free, n)
+			}
+		}
+	}
+
+	sort.Slice(free, func(i, j int) bool { return free[i] < free[j] })
+
+	ips := make([]string, len(free))
+	for i, n := range free {
+		ips[i] = uint32ToIP(n)
+	}
+	return ips
+}
+
+// ipToUint32 converts an IPv4 address to a number
+func ipToUint32(ip string) (uint32, bool) {
+	parsed := net.ParseIP(strings.TrimSpace(ip)).To4()
+	if parsed == nil {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(parsed), true
+}
+
+func uint32ToIP(n uint32) string {
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, n)
+	return ip.String()
+}
+
+// addressesOf returns the IPv4 addresses of an address or an address range
+// written start-end, as used in NAT rules
+func addressesOf(address string) []uint32 {
+
+	parts := strings.SplitN(address, "-", 2)
+	start, ok := ipToUint32(parts[0])
+	if !ok {
+		return nil
+	}
+	end := start
+	if len(parts) == 2 {
+		if end, ok = ipToUint32(parts[1]); !ok || end < start {
+			return nil
+		}
+	}
+
+	var addresses []uint32
+	for n := start; n <= end && n >= start; n++ {
+		addresses = append(addresses, n)
+	}
+	return addresses
+}
+
+// inRanges reports whether n is in one of ranges
+func inRanges(n uint32, ranges []*types.IPRange) bool {
+	for _, r := range ranges {
+		start, ok1 := ipToUint32(r.StartAddress)
+		end, ok2 := ipToUint32(r.EndAddress)
+		if ok1 && ok2 && n >= start && n <= end {
+			return true
+		}
+	}
+	return false
+}
+
+// overlapsRanges reports whether the addresses from start to end overlap one
+// of ranges
+func overlapsRanges(start, end uint32, ranges []*types.IPRange) bool {
+	for _, r := range ranges {
+		from, ok1 := ipToUint32(r.StartAddress)
+		to, ok2 := ipToUint32(r.EndAddress)
+		if ok1 && ok2 && start <= to && end >= from {
+			return true
+		}
+	}
+	return false
+}
+
+// externalAddresses returns the external addresses used on the uplink
+// connected to network by the NAT rules and load balancer virtual servers of
+// this gateway
+func (e *EdgeGateway) externalAddresses(network string) []uint32 {
+
+	if e.EdgeGateway.Configuration == nil || e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration == nil {
+		return nil
+	}
+	services := e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration
+
+	var addresses []uint32
+	if services.NatService != nil {
+		for _, rule := range services.NatService.NatRule {
+			gnr := rule.GatewayNatRule
+			if gnr == nil || gnr.Interface == nil || gnr.Interface.HREF != network {
+				continue
+			}
+			switch rule.RuleType {
+			case NatRuleTypeDNAT:
+				addresses = append(addresses, addressesOf(gnr.OriginalIP)...)
+			case NatRuleTypeSNAT:
+				addresses = append(addresses, addressesOf(gnr.TranslatedIP)...)
+			}
+		}
+	}
+
+	if services.LoadBalancerService != nil {
+		for _, vs := range services.LoadBalancerService.VirtualServer {
+			if vs.Interface != nil && vs.Interface.HREF == network {
+				addresses = append(addresses, addressesOf(vs.IPAddress)...)
+			}
+		}
+	}
+
+	return addresses
+}
+
+// UplinkSubnets refreshes this gateway and returns the subnets of its uplink
+// interfaces, with the external IPs sub-allocated to the gateway and the ones
+// already used by NAT rules or load balancer virtual servers
+func (e *EdgeGateway) UplinkSubnets() ([]UplinkSubnet, error) {
+
+	if err := e.Refresh(); err != nil {
+		return nil, err
+	}
+
+	if e.EdgeGateway.Configuration == nil || e.EdgeGateway.Configuration.GatewayInterfaces == nil {
+		return nil, fmt.Errorf("edge gateway has no interfaces")
+	}
+
+	var subnets []UplinkSubnet
+	for _, gif := range e.EdgeGateway.Configuration.GatewayInterfaces.GatewayInterface {
+		if !strings.EqualFold(gif.InterfaceType, "uplink") || gif.Network == nil {
+			continue
+		}
+
+		addresses := e.externalAddresses(gif.Network.HREF)
+		for _, sp := range gif.SubnetParticipation {
+			subnet := UplinkSubnet{
+				Network:   gif.Network,
+				Gateway:   sp.Gateway,
+				Netmask:   sp.Netmask,
+				IPAddress: sp.IPAddress,
+			}
+			if sp.IPRanges != nil {
+				subnet.Allocated = sp.IPRanges.IPRange
+			}
+
+			seen := make(map[uint32]bool)
+			for _, n := range addresses {
+				if !seen[n] && inRanges(n, subnet.Allocated) {
+					seen[n] = true
+					subnet.InUse = append(subnet.InUse, uint32ToIP(n))
+				}
+			}
+
+			subnets = append(subnets, subnet)
+		}
+	}
+
+	return subnets, nil
+}
+
+// FreeExternalIPs returns the sub-allocated external IPs of the uplink
+// connected to network, by name or HREF, that no rule uses yet
+func (e *EdgeGateway) FreeExternalIPs(network string) ([]string, error) {
+
+	subnets, err := e.UplinkSubnets()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, subnet := range subnets {
+		if matchesNetwork(subnet.Network, network) {
+			return subnet.FreeIPs(), nil
+		}
+	}
+
+	return nil, fmt.Errorf("can't find uplink: %s", network)
+}
+
+// uplinkInterface returns the uplink of gateway connected to network, by name
+// or HREF
+func uplinkInterface(gateway *types.EdgeGateway, network string) (*types.GatewayInterface, error) {
+
+	if gateway.Configuration == nil || gateway.Configuration.GatewayInterfaces == nil {
+		return nil, fmt.Errorf("edge gateway has no interfaces")
+	}
+
+	for _, gif := range gateway.Configuration.GatewayInterfaces.GatewayInterface {
+		if strings.EqualFold(gif.InterfaceType, "uplink") && matchesNetwork(gif.Network, network) {
+			if len(gif.SubnetParticipation) == 0 {
+				return nil, fmt.Errorf("uplink %s has no subnet", network)
+			}
+			return gif, nil
+		}
+	}
+
+	return nil, fmt.Errorf("can't find uplink: %s", network)
+}
+
+// inSubnet reports whether ip is an address of the subnet of sp
+func inSubnet(sp *types.SubnetParticipation, ip string) bool {
+	n, ok1 := ipToUint32(ip)
+	gw, ok2 := ipToUint32(sp.Gateway)
+	mask, ok3 := ipToUint32(sp.Netmask)
+	return ok1 && ok2 && ok3 && n&mask == gw&mask
+}
+
+// validateIPRange checks that start-end is a range of the subnet of sp
+func validateIPRange(sp *types.SubnetParticipation, start, end string) (uint32, uint32, error) {
+
+	from, ok1 := ipToUint32(start)
+	to, ok2 := ipToUint32(end)
+	if !ok1 || !ok2 || to < from {
+		return 0, 0, fmt.Errorf("invalid IP range %s-%s", start, end)
+	}
+
+	gw, ok1 := ipToUint32(sp.Gateway)
+	mask, ok2 := ipToUint32(sp.Netmask)
+	if !ok1 || !ok2 {
+		return 0, 0, fmt.Errorf("invalid uplink subnet %s/%s", sp.Gateway, sp.Netmask)
+	}
+
+	if from&mask != gw&mask || to&mask != gw&mask {
+		return 0, 0, fmt.Errorf("IP range %s-%s is outside of the uplink subnet %s/%s", start, end, sp.Gateway, sp.Netmask)
+	}
+
+	return from, to, nil
+}
+
+// AddExternalIPRange sub-allocates the external IPs from start to end of the
+// uplink connected to network, by name or HREF, to this gateway. The range
+// must be in the uplink subnet and not overlap the ranges already allocated.
+func (e *EdgeGateway) AddExternalIPRange(network, start, end string) (Task, error) {
+
+	return e.configureInterfaces(func(gateway *types.EdgeGateway) error {
+
+		gif, err := uplinkInterface(gateway, network)
+		if err != nil {
+			return err
+		}
+
+		// The range goes to the subnet holding its first address, a
+		// single subnet gets any range so that the error says why
+		sp := gif.SubnetParticipation[0]
+		for _, p := range gif.SubnetParticipation {
+			if inSubnet(p, start) {
+				sp = p
+				break
+			}
+		}
+
+		from, to, err := validateIPRange(sp, start, end)
+		if err != nil {
+			return err
+		}
+
+		if sp.IPRanges == nil {
+			sp.IPRanges = &types.IPRanges{}
+		}
+
+		for _, r := range sp.IPRanges.IPRange {
+			rfrom, ok1 := ipToUint32(r.StartAddress)
+			rto, ok2 := ipToUint32(r.EndAddress)
+			if ok1 && ok2 && from <= rto && rfrom <= to {
+				return fmt.Errorf("IP range %s-%s overlaps the allocated range %s-%s", start, end, r.StartAddress, r.EndAddress)
+			}
+		}
+
+		sp.IPRanges.IPRange = append(sp.IPRanges.IPRange, &types.IPRange{
+			StartAddress: start,
+			EndAddress:   end,
+		})
+
+		return nil
+	})
+}
+
+// RemoveExternalIPRange gives back the sub-allocated range from start to end
+// of the uplink connected to network, by name or HREF. It fails while a NAT
+// rule or a load balancer virtual server uses an address of the range.
+func (e *EdgeGateway) RemoveExternalIPRange(network, start, end string) (Task, error) {
+
+	return e.configureInterfaces(func(gateway *types.EdgeGateway) error {
+
+		gif, err := uplinkInterface(gateway, network)
+		if err != nil {
+			return err
+		}
+
+		for _, sp := range gif.SubnetParticipation {
+			if sp.IPRanges == nil {
+				continue
+			}
+
+			for k, r := range sp.IPRanges.IPRange {
+				if r.StartAddress != start || r.EndAddress != end {
+					continue
+				}
+
+				for _, n := range e.externalAddresses(gif.Network.HREF) {
+					if inRanges(n, []*types.IPRange{r}) {
+						return fmt.Errorf("IP range %s-%s is in use: %s", start, end, uint32ToIP(n))
+					}
+				}
+
+				sp.IPRanges.IPRange = append(sp.IPRanges.IPRange[:k], sp.IPRanges.IPRange[k+1:]...)
+				return nil
+			}
+		}
+
+		return fmt.Errorf("can't find IP range: %s-%s", start, end)
+	})
+}
+
+// configureInterfaces refreshes this gateway, lets mutate change a copy of it
+// and submits the copy. vCloud has no action dedicated to gateway interfaces, they
+// change with an update of the whole gateway. The copy is submitted without
+// its service configuration, which vCloud then leaves as it is, so that a
+// service change made since the refresh isn't undone. It shares the lock of
+// ModifyServices so that both don't overwrite each other.
+func (e *EdgeGateway) configureInterfaces(mutate func(gateway *types.EdgeGateway) error) (Task, error) {
+
+	if e.EdgeGateway == nil || e.EdgeGateway.HREF == "" {
+		return Task{}, fmt.Errorf("cannot configure interfaces, Object is empty")
+	}
+
+	l := edgeGatewayLock(e.EdgeGateway.HREF)
+	l.Lock()
+	defer l.Unlock()
+
+	if err := e.Refresh(); err != nil {
+		return Task{}, err
+	}
+
+	data, err := xml.Marshal(e.EdgeGateway)
+	if err != nil {
+		return Task{}, fmt.Errorf("error copying Edge Gateway: %s", err)
+	}
+
+	gateway := new(types.EdgeGateway)
+	if err := xml.Unmarshal(data, gateway); err != nil {
+		return Task{}, fmt.Errorf("error copying Edge Gateway: %s", err)
+	}
+
+	if err := mutate(gateway); err != nil {
+		return Task{}, err
+	}
+
+	s, err := url.ParseRequestURI(gateway.HREF)
+	if err != nil {
+		return Task{}, fmt.Errorf("error parsing Edge Gateway href: %s", err)
+	}
+
+	gateway.Xmlns = types.NsVCloud
+	gateway.Link = nil
+	gateway.Tasks = nil
+	if gateway.Configuration != nil {
+		gateway.Configuration.EdgeGatewayServiceConfiguration = nil
+	}
+
+	task, err := executeTaskRequest(e.c, types.HTTPPut, s, types.MimeEdgeGateway, gateway)
+	if err != nil {
+		return Task{}, fmt.Errorf("error reconfiguring Edge Gateway interfaces: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+func Test_UplinkSubnets(t *testing.T) {
+	cc := new(callCounter)
+	state := newEdgeGatewayState(edgegatewayExample)
+
+	ctx, err := setupTestContext(authHandler(state.handler(cc)))
+	if assert.NoError(t, err) {
+
+		edge, err := ctx.VDC.FindEdgeGateway("M916272752-5793")
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+
+			subnets, err := edge.UplinkSubnets()
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) && assert.Len(t, subnets, 1) {
+				assert.Equal(t, "d2p3-ext", subnets[0].Network.Name)
+				assert.Equal(t, "255.255.254.0", subnets[0].Netmask)
+				assert.Equal(t, "23.92.225.51", subnets[0].IPAddress)
+				assert.Len(t, subnets[0].Allocated, 12)
+				assert.Equal(t, []string{"23.92.225.51", "23.92.224.255"}, subnets[0].InUse)
+			}
+
+			free, err := edge.FreeExternalIPs("d2p3-ext")
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) && assert.Len(t, free, 14) {
+				assert.Equal(t, "23.92.224.34", free[0])
+				assert.Equal(t, "23.92.225.49", free[7])
+				assert.Equal(t, "23.92.225.75", free[13])
+			}
+
+			_, err = edge.FreeExternalIPs("JGray Network")
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+		}
+	}
+}
+
+func Test_ExternalIPRanges(t *testing.T) {
+	cc := new(callCounter)
+	state := newEdgeGatewayState(edgegatewayExample)
+
+	ctx, err := setupTestContext(authHandler(state.handler(cc)))
+	if assert.NoError(t, err) {
+
+		edge, err := ctx.VDC.FindEdgeGateway("M916272752-5793")
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+
+			uplinkRanges := func() int {
+				for _, gif := range state.updated.Configuration.GatewayInterfaces.GatewayInterface {
+					if gif.InterfaceType == "uplink" {
+						return len(gif.SubnetParticipation[0].IPRanges.IPRange)
+					}
+				}
+				return 0
+			}
+
+			_, err = edge.AddExternalIPRange("d2p3-ext", "23.92.225.100", "23.92.225.110")
+			if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) && assert.NotNil(t, state.updated) {
+				assert.Equal(t, "M916272752-5793", state.updated.Name)
+				assert.Equal(t, 13, uplinkRanges())
+				assert.Nil(t, state.updated.Tasks)
+				// the services are left out, vCloud keeps them as they are
+				assert.Nil(t, state.updated.Configuration.EdgeGatewayServiceConfiguration)
+				// the cached gateway is untouched
+				assert.Len(t, edge.EdgeGateway.Configuration.GatewayInterfaces.GatewayInterface[2].SubnetParticipation[0].IPRanges.IPRange, 12)
+			}
+
+			_, err = edge.AddExternalIPRange("d2p3-ext", "23.92.225.74", "23.92.225.80")
+			assert.Error(t, err)
+
+			_, err = edge.AddExternalIPRange("d2p3-ext", "23.92.226.1", "23.92.226.9")
+			assert.Error(t, err)
+
+			_, err = edge.AddExternalIPRange("JGray Network", "192.168.108.10", "192.168.108.20")
+			assert.Error(t, err)
+			assert.Equal(t, 3, cc.Pop())
+
+			// used by two NAT rules
+			_, err = edge.RemoveExternalIPRange("d2p3-ext", "23.92.224.255", "23.92.224.255")
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+
+			_, err = edge.RemoveExternalIPRange(ctx.Server.URL+"/api/admin/network/6254f107-9876-4d03-986f-8bec7a4bcb3f", "23.92.225.73", "23.92.225.75")
+			if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+				assert.Equal(t, 11, uplinkRanges())
+			}
+
+			_, err = edge.RemoveExternalIPRange("d2p3-ext", "23.92.225.73", "23.92.225.74")
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+		}
+	}
+}
+
+// twoSubnetsEdgegatewayExample is edgegatewayExample where the d2p3-ext
+// uplink has a second subnet
+var twoSubnetsEdgegatewayExample = strings.Replace(edgegatewayExample, `</SubnetParticipation>
+                <ApplyRateLimit>true</ApplyRateLimit>`, `</SubnetParticipation>
+                <SubnetParticipation>
+                    <Gateway>23.92.230.1</Gateway>
+                    <Netmask>255.255.255.0</Netmask>
+                    <IpRanges>
+                        <IpRange>
+                            <StartAddress>23.92.230.10</StartAddress>
+                            <EndAddress>23.92.230.19</EndAddress>
+                        </IpRange>
+                    </IpRanges>
+                </SubnetParticipation>
+                <ApplyRateLimit>true</ApplyRateLimit>`, 1)
+
+func Test_ExternalIPRangesSubnets(t *testing.T) {
+	cc := new(callCounter)
+	state := newEdgeGatewayState(twoSubnetsEdgegatewayExample)
+
+	ctx, err := setupTestContext(authHandler(state.handler(cc)))
+	if assert.NoError(t, err) {
+
+		edge, err := ctx.VDC.FindEdgeGateway("M916272752-5793")
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+
+			subnets, err := edge.UplinkSubnets()
+			if assert.NoError(t, err) && assert.Len(t, subnets, 2) {
+				assert.Len(t, subnets[0].Allocated, 12)
+				assert.Equal(t, "23.92.230.1", subnets[1].Gateway)
+				assert.Len(t, subnets[1].Allocated, 1)
+			}
+
+			// both subnets are sent back, the range goes to the second one
+			_, err = edge.AddExternalIPRange("d2p3-ext", "23.92.230.20", "23.92.230.29")
+			if assert.NoError(t, err) && assert.NotNil(t, state.updated) {
+				sp := state.updated.Configuration.GatewayInterfaces.GatewayInterface[2].SubnetParticipation
+				if assert.Len(t, sp, 2) {
+					assert.Len(t, sp[0].IPRanges.IPRange, 12)
+					assert.Len(t, sp[1].IPRanges.IPRange, 2)
+				}
+				assert.Equal(t, types.NsVCloud, state.updated.Xmlns)
+			}
+
+			_, err = edge.RemoveExternalIPRange("d2p3-ext", "23.92.230.10", "23.92.230.19")
+			if assert.NoError(t, err) {
+				sp := state.updated.Configuration.GatewayInterfaces.GatewayInterface[2].SubnetParticipation
+				if assert.Len(t, sp, 2) {
+					assert.Len(t, sp[0].IPRanges.IPRange, 12)
+					assert.Len(t, sp[1].IPRanges.IPRange, 0)
+				}
+			}
+		}
+	}
+}
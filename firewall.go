@@ -0,0 +1,306 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// The firewall policies and protocols understood by the edge gateway
+const (
+	FirewallPolicyAllow = "allow"
+	FirewallPolicyDrop  = "drop"
+
+	FirewallProtocolAny  = "any"
+	FirewallProtocolTCP  = "tcp"
+	FirewallProtocolUDP  = "udp"
+	FirewallProtocolICMP = "icmp"
+)
+
+// NewFirewallRuleProtocols builds the protocol flags of a firewall rule from
+// a list of protocol names, tcp and udp can be combined.
+func NewFirewallRuleProtocols(protocols ...string) (*types.FirewallRuleProtocols, error) {
+
+	p := &types.FirewallRuleProtocols{}
+
+	for _, protocol := range protocols {
+		switch strings.ToLower(protocol) {
+		case FirewallProtocolAny:
+			p.Any = true
+		case FirewallProtocolTCP:
+			p.TCP = true
+		case FirewallProtocolUDP:
+			p.UDP = true
+		case FirewallProtocolICMP:
+			p.ICMP = true
+		default:
+			return nil, fmt.Errorf("unsupported firewall protocol: %s", protocol)
+		}
+	}
+
+	if p.Any && (p.TCP || p.UDP || p.ICMP) || p.ICMP && (p.TCP || p.UDP) {
+		return nil, fmt.Errorf("protocols %v can't be combined", protocols)
+	}
+
+	if !p.Any && !p.TCP && !p.UDP && !p.ICMP {
+		p.Any = true
+	}
+
+	return p, nil
+}
+
+// PortRange formats a port range for a firewall rule, a from port of 0
+// matches any port and a to port of 0 matches the single from port.
+func PortRange(from, to int) string {
+	if from <= 0 {
+		return "Any"
+	}
+	if to <= 0 || to == from {
+		return strconv.Itoa(from)
+	}
+	return fmt.Sprintf("%d-%d", from, to)
+}
+
+func validatePortRange(portRange string) error {
+
+	if portRange == "" || strings.EqualFold(portRange, "any") {
+		return nil
+	}
+
+	for _, port := range strings.SplitN(portRange, "-", 2) {
+		p, err := strconv.Atoi(port)
+		if err != nil || p < 1 || p > 65535 {
+			return fmt.Errorf("invalid port range: %s", portRange)
+		}
+	}
+
+	return nil
+}
+
+func validateFirewallRule(rule *types.FirewallRule) error {
+
+	if rule == nil {
+		return fmt.Errorf("firewall rule can't be empty")
+	}
+
+	if rule.Policy != FirewallPolicyAllow && rule.Policy != FirewallPolicyDrop {
+		return fmt.Errorf("invalid firewall policy: %s", rule.Policy)
+	}
+
+	if rule.Protocols == nil {
+		return fmt.Errorf("firewall rule %q has no protocols", rule.Description)
+	}
+
+	if err := validatePortRange(rule.DestinationPortRange); err != nil {
+		return err
+	}
+
+	return validatePortRange(rule.SourcePortRange)
+}
+
+// configFirewallService returns the firewall service of config, adding an
+// empty one when the gateway has none
+func configFirewallService(config *types.GatewayFeatures) *types.FirewallService {
+	if config.FirewallService == nil {
+		config.FirewallService = &types.FirewallService{}
+	}
+	return config.FirewallService
+}
+
+// FirewallRules refreshes this gateway and returns its firewall rules in
+// evaluation order
+func (e *EdgeGateway) FirewallRules() ([]*types.FirewallRule, error) {
+
+	if err := e.Refresh(); err != nil {
+		return nil, err
+	}
+
+	if e.EdgeGateway.Configuration == nil || e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration == nil {
+		return nil, fmt.Errorf("edge gateway has no service configuration")
+	}
+
+	fw := e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration.FirewallService
+	if fw == nil {
+		return nil, nil
+	}
+
+	return fw.FirewallRule, nil
+}
+
+// FindFirewallRuleByID finds a firewall rule by ID in the last known
+// configuration of this gateway
+func (e *EdgeGateway) FindFirewallRuleByID(id string) (*types.FirewallRule, error) {
+
+	if e.EdgeGateway.Configuration == nil || e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration == nil {
+		return nil, fmt.Errorf("edge gateway has no service configuration")
+	}
+
+	if fw := e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration.FirewallService; fw != nil {
+		for _, rule := range fw.FirewallRule {
+			if rule.ID == id {
+				return rule, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("can't find firewall rule: %s", id)
+}
+
+// FindFirewallRulesByDescription finds the firewall rules with the given
+// description in the last known configuration of this gateway
+func (e *EdgeGateway) FindFirewallRulesByDescription(description string) ([]*types.FirewallRule, error) {
+
+	if e.EdgeGateway.Configuration == nil || e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration == nil {
+		return nil, fmt.Errorf("edge gateway has no service configuration")
+	}
+
+	var rules []*types.FirewallRule
+	if fw := e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration.FirewallService; fw != nil {
+		for _, rule := range fw.FirewallRule {
+			if rule.Description == description {
+				rules = append(rules, rule)
+			}
+		}
+	}
+
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("can't find firewall rule: %s", description)
+	}
+
+	return rules, nil
+}
+
+// AddFirewallRule inserts rule at position in the firewall rules of this
+// gateway, a negative position or one past the last rule appends it. The
+// gateway assigns the rule ID.
+func (e *EdgeGateway) AddFirewallRule(rule *types.FirewallRule, position int) (Task, error) {
+
+	if err := validateFirewallRule(rule); err != nil {
+		return Task{}, err
+	}
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		fw := configFirewallService(config)
+
+		if position > len(fw.FirewallRule) {
+			return fmt.Errorf("invalid firewall rule position %d, there are %d rules", position, len(fw.FirewallRule))
+		}
+
+		newrule := *rule
+		newrule.ID = ""
+
+		if position < 0 || position == len(fw.FirewallRule) {
+			fw.FirewallRule = append(fw.FirewallRule, &newrule)
+		} else {
+			fw.FirewallRule = append(fw.FirewallRule[:position], append([]*types.FirewallRule{&newrule}, fw.FirewallRule[position:]...)...)
+		}
+
+		return nil
+	})
+}
+
+// UpdateFirewallRule replaces the firewall rule with the same ID as rule,
+// keeping its position
+func (e *EdgeGateway) UpdateFirewallRule(rule *types.FirewallRule) (Task, error) {
+
+	if err := validateFirewallRule(rule); err != nil {
+		return Task{}, err
+	}
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		fw := configFirewallService(config)
+
+		for k, v := range fw.FirewallRule {
+			if v.ID == rule.ID {
+				fw.FirewallRule[k] = rule
+				return nil
+			}
+		}
+
+		return fmt.Errorf("can't find firewall rule: %s", rule.ID)
+	})
+}
+
+// DeleteFirewallRule removes the firewall rule with the given ID
+func (e *EdgeGateway) DeleteFirewallRule(id string) (Task, error) {
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		fw := configFirewallService(config)
+
+		for k, v := range fw.FirewallRule {
+			if v.ID == id {
+				fw.FirewallRule = append(fw.FirewallRule[:k], fw.FirewallRule[k+1:]...)
+				return nil
+			}
+		}
+
+		return fmt.Errorf("can't find firewall rule: %s", id)
+	})
+}
+
+// ReorderFirewallRules moves the firewall rules with the given IDs to the top
+// of the rule list in that order, the remaining rules keep their relative
+// order after them.
+func (e *EdgeGateway) ReorderFirewallRules(ids ...string) (Task, error) {
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		fw := configFirewallService(config)
+
+		byID := make(map[string]*types.FirewallRule, len(fw.FirewallRule))
+		for _, rule := range fw.FirewallRule {
+			byID[rule.ID] = rule
+		}
+
+		reordered := make([]*types.FirewallRule, 0, len(fw.FirewallRule))
+		moved := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			rule, ok := byID[id]
+			if !ok {
+				return fmt.Errorf("can't find firewall rule: %s", id)
+			}
+			if moved[id] {
+				return fmt.Errorf("firewall rule %s listed more than once", id)
+			}
+			moved[id] = true
+			reordered = append(reordered, rule)
+		}
+
+		for _, rule := range fw.FirewallRule {
+			if !moved[rule.ID] {
+				reordered = append(reordered, rule)
+			}
+		}
+
+		fw.FirewallRule = reordered
+
+		return nil
+	})
+}
+
+// SetFirewallDefaultAction sets the action applied to traffic that matches
+// no firewall rule, and whether that traffic is logged
+func (e *EdgeGateway) SetFirewallDefaultAction(action string, logDefaultAction bool) (Task, error) {
+
+	if action != FirewallPolicyAllow && action != FirewallPolicyDrop {
+		return Task{}, fmt.Errorf("invalid firewall default action: %s", action)
+	}
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		fw := configFirewallService(config)
+		fw.DefaultAction = action
+		fw.LogDefaultAction = logDefaultAction
+
+		return nil
+	})
+}
@@ -0,0 +1,187 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// edgeServicesHandler serves the edgegateway fixture and decodes the
+// configuration submitted to configureServices into config. The submitted
+// configuration is served once, to the read that verifies it, after which
+// the fixture is served again so every change starts from the same state.
+func edgeServicesHandler(edgegateway string, config *types.GatewayFeatures, cc *callCounter) http.Handler {
+	state := newEdgeGatewayState(edgegateway)
+	fixture := state.services
+	submitted := false
+	state.onRead = func(services string) string {
+		if submitted {
+			submitted = false
+			return services
+		}
+		return fixture
+	}
+
+	handler := state.handler(cc)
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/admin/edgeGateway/00000000-0000-0000-0000-000000000000/action/configureServices" {
+			body, _ := ioutil.ReadAll(r.Body)
+			*config = types.GatewayFeatures{}
+			xml.Unmarshal(body, config)
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			submitted = true
+		}
+		handler.ServeHTTP(rw, r)
+	})
+}
+
+func firewallRuleIDs(config *types.GatewayFeatures) []string {
+	var ids []string
+	for _, rule := range config.FirewallService.FirewallRule {
+		ids = append(ids, rule.ID)
+	}
+	return ids
+}
+
+func Test_NewFirewallRuleProtocols(t *testing.T) {
+	p, err := NewFirewallRuleProtocols("TCP", "udp")
+	if assert.NoError(t, err) {
+		assert.True(t, p.TCP)
+		assert.True(t, p.UDP)
+		assert.False(t, p.Any)
+	}
+
+	p, err = NewFirewallRuleProtocols()
+	if assert.NoError(t, err) {
+		assert.True(t, p.Any)
+	}
+
+	_, err = NewFirewallRuleProtocols("icmp", "tcp")
+	assert.Error(t, err)
+
+	_, err = NewFirewallRuleProtocols("gre")
+	assert.Error(t, err)
+
+	assert.Equal(t, "Any", PortRange(0, 0))
+	assert.Equal(t, "22", PortRange(22, 0))
+	assert.Equal(t, "8000-8080", PortRange(8000, 8080))
+}
+
+func Test_FirewallRules(t *testing.T) {
+	cc := new(callCounter)
+	config := new(types.GatewayFeatures)
+
+	ctx, err := setupTestContext(authHandler(edgeServicesHandler(edgegatewayExample, config, cc)))
+	if assert.NoError(t, err) {
+
+		edge, err := ctx.VDC.FindEdgeGateway("M916272752-5793")
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+
+			rules, err := edge.FirewallRules()
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Len(t, rules, 4)
+			}
+
+			rule, err := edge.FindFirewallRuleByID("2")
+			if assert.NoError(t, err) {
+				assert.Equal(t, "inet prd-001", rule.Description)
+			}
+
+			rules, err = edge.FindFirewallRulesByDescription("suppah megah rulez creati0nz")
+			if assert.NoError(t, err) {
+				assert.Len(t, rules, 2)
+			}
+
+			_, err = edge.FindFirewallRuleByID("42")
+			assert.Error(t, err)
+
+			protocols, _ := NewFirewallRuleProtocols(FirewallProtocolTCP)
+			https := &types.FirewallRule{
+				ID:                   "99",
+				IsEnabled:            true,
+				Description:          "https",
+				Policy:               FirewallPolicyAllow,
+				Protocols:            protocols,
+				DestinationPortRange: PortRange(443, 0),
+				DestinationVM: &types.VMSelection{
+					VAppScopedVMID: "CentOS64-32bit",
+					VMNicID:        0,
+					IPType:         "assigned",
+				},
+				SourcePortRange: "Any",
+				SourceIP:        "Any",
+				EnableLogging:   true,
+			}
+
+			_, err = edge.AddFirewallRule(https, 1)
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				assert.Equal(t, []string{"1", "", "2", "3", "4"}, firewallRuleIDs(config))
+				added := config.FirewallService.FirewallRule[1]
+				assert.Equal(t, "https", added.Description)
+				assert.Equal(t, "443", added.DestinationPortRange)
+				assert.True(t, added.EnableLogging)
+				if assert.NotNil(t, added.DestinationVM) {
+					assert.Equal(t, "CentOS64-32bit", added.DestinationVM.VAppScopedVMID)
+				}
+				// the gateway was refreshed with the applied configuration
+				assert.Len(t, edge.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration.FirewallService.FirewallRule, 5)
+			}
+
+			_, err = edge.AddFirewallRule(https, -1)
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				assert.Equal(t, []string{"1", "2", "3", "4", ""}, firewallRuleIDs(config))
+			}
+
+			_, err = edge.AddFirewallRule(https, 10)
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+
+			https.DestinationPortRange = "443-70000"
+			_, err = edge.AddFirewallRule(https, -1)
+			assert.Error(t, err)
+			assert.Equal(t, 0, cc.Pop())
+
+			https.ID = "3"
+			https.DestinationPortRange = PortRange(443, 0)
+			_, err = edge.UpdateFirewallRule(https)
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				assert.Equal(t, []string{"1", "2", "3", "4"}, firewallRuleIDs(config))
+				assert.Equal(t, "https", config.FirewallService.FirewallRule[2].Description)
+			}
+
+			_, err = edge.DeleteFirewallRule("2")
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				assert.Equal(t, []string{"1", "3", "4"}, firewallRuleIDs(config))
+			}
+
+			_, err = edge.DeleteFirewallRule("42")
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+
+			_, err = edge.ReorderFirewallRules("4", "2")
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				assert.Equal(t, []string{"4", "2", "1", "3"}, firewallRuleIDs(config))
+			}
+
+			_, err = edge.SetFirewallDefaultAction(FirewallPolicyAllow, true)
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				assert.Equal(t, "allow", config.FirewallService.DefaultAction)
+				assert.True(t, config.FirewallService.LogDefaultAction)
+				assert.Len(t, config.FirewallService.FirewallRule, 4)
+				// the other services are submitted unchanged
+				if assert.NotNil(t, config.NatService) {
+					assert.Len(t, config.NatService.NatRule, 4)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,908 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// GatewayConfigVersion is the version of the documents written by
+// ExportConfig
+const GatewayConfigVersion = 1
+
+// GatewayConfig is a portable copy of the services of an edge gateway, meant
+// to be stored as JSON or YAML. Its types only hold the settings of the
+// services: networks are referred to by name, and identifiers, namespaces and
+// status assigned by vCloud are left out, so that a document can be imported
+// on another gateway. Lists whose order doesn't matter are sorted, firewall
+// and NAT rules keep the order they are evaluated in. A nil service is left
+// alone on import.
+//
+// ParseGatewayConfig reads JSON. The yaml tags match the json ones, so a YAML
+// library such as gopkg.in/yaml.v2 reads and writes the same document.
+type GatewayConfig struct {
+	Version       int                  `json:"version" yaml:"version"`
+	Firewall      *FirewallConfig      `json:"firewall,omitempty" yaml:"firewall,omitempty"`
+	Nat           *NatConfig           `json:"nat,omitempty" yaml:"nat,omitempty"`
+	LoadBalancer  *LoadBalancerConfig  `json:"loadBalancer,omitempty" yaml:"loadBalancer,omitempty"`
+	Dhcp          *DhcpConfig          `json:"dhcp,omitempty" yaml:"dhcp,omitempty"`
+	IpsecVpn      *IpsecVpnConfig      `json:"ipsecVpn,omitempty" yaml:"ipsecVpn,omitempty"`
+	StaticRouting *StaticRoutingConfig `json:"staticRouting,omitempty" yaml:"staticRouting,omitempty"`
+}
+
+// FirewallConfig is the firewall service of a GatewayConfig
+type FirewallConfig struct {
+	Enabled          bool                  `json:"enabled" yaml:"enabled"`
+	DefaultAction    string                `json:"defaultAction,omitempty" yaml:"defaultAction,omitempty"`
+	LogDefaultAction bool                  `json:"logDefaultAction,omitempty" yaml:"logDefaultAction,omitempty"`
+	Rules            []*FirewallRuleConfig `json:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+// FirewallRuleConfig is a firewall rule of a GatewayConfig
+type FirewallRuleConfig struct {
+	Enabled              bool                     `json:"enabled" yaml:"enabled"`
+	Description          string                   `json:"description,omitempty" yaml:"description,omitempty"`
+	Policy               string                   `json:"policy,omitempty" yaml:"policy,omitempty"`
+	Protocols            *FirewallProtocolsConfig `json:"protocols,omitempty" yaml:"protocols,omitempty"`
+	IcmpSubType          string                   `json:"icmpSubType,omitempty" yaml:"icmpSubType,omitempty"`
+	MatchOnTranslate     bool                     `json:"matchOnTranslate,omitempty" yaml:"matchOnTranslate,omitempty"`
+	Port                 int                      `json:"port,omitempty" yaml:"port,omitempty"`
+	DestinationPortRange string                   `json:"destinationPortRange,omitempty" yaml:"destinationPortRange,omitempty"`
+	DestinationIP        string                   `json:"destinationIp,omitempty" yaml:"destinationIp,omitempty"`
+	DestinationVM        *FirewallVMConfig        `json:"destinationVm,omitempty" yaml:"destinationVm,omitempty"`
+	SourcePort           int                      `json:"sourcePort,omitempty" yaml:"sourcePort,omitempty"`
+	SourcePortRange      string                   `json:"sourcePortRange,omitempty" yaml:"sourcePortRange,omitempty"`
+	SourceIP             string                   `json:"sourceIp,omitempty" yaml:"sourceIp,omitempty"`
+	SourceVM             *FirewallVMConfig        `json:"sourceVm,omitempty" yaml:"sourceVm,omitempty"`
+	Direction            string                   `json:"direction,omitempty" yaml:"direction,omitempty"`
+	EnableLogging        bool                     `json:"enableLogging,omitempty" yaml:"enableLogging,omitempty"`
+}
+
+// FirewallProtocolsConfig is the set of protocols a firewall rule of a
+// GatewayConfig applies to
+type FirewallProtocolsConfig struct {
+	Any  bool `json:"any,omitempty" yaml:"any,omitempty"`
+	TCP  bool `json:"tcp,omitempty" yaml:"tcp,omitempty"`
+	UDP  bool `json:"udp,omitempty" yaml:"udp,omitempty"`
+	ICMP bool `json:"icmp,omitempty" yaml:"icmp,omitempty"`
+}
+
+// FirewallVMConfig selects the VM NIC a firewall rule of a GatewayConfig
+// applies to
+type FirewallVMConfig struct {
+	VAppScopedVMID string `json:"vAppScopedVmId" yaml:"vAppScopedVmId"`
+	NicID          int    `json:"nicId" yaml:"nicId"`
+	IPType         string `json:"ipType" yaml:"ipType"`
+}
+
+// NatConfig is the NAT service of a GatewayConfig
+type NatConfig struct {
+	Enabled    bool             `json:"enabled" yaml:"enabled"`
+	NatType    string           `json:"natType,omitempty" yaml:"natType,omitempty"`
+	Policy     string           `json:"policy,omitempty" yaml:"policy,omitempty"`
+	ExternalIP string           `json:"externalIp,omitempty" yaml:"externalIp,omitempty"`
+	Rules      []*NatRuleConfig `json:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+// NatRuleConfig is a NAT rule of a GatewayConfig, on the uplink connected to
+// the network named Interface
+type NatRuleConfig struct {
+	Type           string `json:"type" yaml:"type"`
+	Enabled        bool   `json:"enabled" yaml:"enabled"`
+	Description    string `json:"description,omitempty" yaml:"description,omitempty"`
+	Interface      string `json:"interface" yaml:"interface"`
+	OriginalIP     string `json:"originalIp" yaml:"originalIp"`
+	OriginalPort   string `json:"originalPort,omitempty" yaml:"originalPort,omitempty"`
+	TranslatedIP   string `json:"translatedIp" yaml:"translatedIp"`
+	TranslatedPort string `json:"translatedPort,omitempty" yaml:"translatedPort,omitempty"`
+	Protocol       string `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	IcmpSubType    string `json:"icmpSubType,omitempty" yaml:"icmpSubType,omitempty"`
+}
+
+// LoadBalancerConfig is the load balancer service of a GatewayConfig
+type LoadBalancerConfig struct {
+	Enabled        bool                               `json:"enabled" yaml:"enabled"`
+	Pools          []*LoadBalancerPoolConfig          `json:"pools,omitempty" yaml:"pools,omitempty"`
+	VirtualServers []*LoadBalancerVirtualServerConfig `json:"virtualServers,omitempty" yaml:"virtualServers,omitempty"`
+}
+
+// LoadBalancerPoolConfig is a load balancer pool of a GatewayConfig
+type LoadBalancerPoolConfig struct {
+	Name         string                           `json:"name" yaml:"name"`
+	Description  string                           `json:"description,omitempty" yaml:"description,omitempty"`
+	ServicePorts []*LoadBalancerServicePortConfig `json:"servicePorts,omitempty" yaml:"servicePorts,omitempty"`
+	Members      []*LoadBalancerMemberConfig      `json:"members,omitempty" yaml:"members,omitempty"`
+}
+
+// LoadBalancerServicePortConfig is a service port of a load balancer pool,
+// or of one of its members, in a GatewayConfig
+type LoadBalancerServicePortConfig struct {
+	Enabled         bool                           `json:"enabled" yaml:"enabled"`
+	Protocol        string                         `json:"protocol" yaml:"protocol"`
+	Algorithm       string                         `json:"algorithm" yaml:"algorithm"`
+	Port            string                         `json:"port" yaml:"port"`
+	HealthCheckPort string                         `json:"healthCheckPort,omitempty" yaml:"healthCheckPort,omitempty"`
+	HealthCheck     *LoadBalancerHealthCheckConfig `json:"healthCheck,omitempty" yaml:"healthCheck,omitempty"`
+}
+
+// LoadBalancerHealthCheckConfig is the health check of a load balancer
+// service port in a GatewayConfig
+type LoadBalancerHealthCheckConfig struct {
+	Mode              string `json:"mode" yaml:"mode"`
+	URI               string `json:"uri,omitempty" yaml:"uri,omitempty"`
+	HealthThreshold   string `json:"healthThreshold,omitempty" yaml:"healthThreshold,omitempty"`
+	UnhealthThreshold string `json:"unhealthThreshold,omitempty" yaml:"unhealthThreshold,omitempty"`
+	Interval          string `json:"interval,omitempty" yaml:"interval,omitempty"`
+	Timeout           string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// LoadBalancerMemberConfig is a member of a load balancer pool of a
+// GatewayConfig
+type LoadBalancerMemberConfig struct {
+	IPAddress    string                           `json:"ipAddress" yaml:"ipAddress"`
+	Weight       string                           `json:"weight" yaml:"weight"`
+	ServicePorts []*LoadBalancerServicePortConfig `json:"servicePorts,omitempty" yaml:"servicePorts,omitempty"`
+}
+
+// LoadBalancerVirtualServerConfig is a load balancer virtual server of a
+// GatewayConfig, on the uplink connected to the network named Interface
+type LoadBalancerVirtualServerConfig struct {
+	Enabled         bool                                `json:"enabled" yaml:"enabled"`
+	Name            string                              `json:"name" yaml:"name"`
+	Description     string                              `json:"description,omitempty" yaml:"description,omitempty"`
+	Interface       string                              `json:"interface" yaml:"interface"`
+	IPAddress       string                              `json:"ipAddress" yaml:"ipAddress"`
+	ServiceProfiles []*LoadBalancerServiceProfileConfig `json:"serviceProfiles,omitempty" yaml:"serviceProfiles,omitempty"`
+	Logging         bool                                `json:"logging,omitempty" yaml:"logging,omitempty"`
+	Pool            string                              `json:"pool" yaml:"pool"`
+}
+
+// LoadBalancerServiceProfileConfig is a service profile of a load balancer
+// virtual server in a GatewayConfig
+type LoadBalancerServiceProfileConfig struct {
+	Enabled     bool                           `json:"enabled" yaml:"enabled"`
+	Protocol    string                         `json:"protocol" yaml:"protocol"`
+	Port        string                         `json:"port" yaml:"port"`
+	Persistence *LoadBalancerPersistenceConfig `json:"persistence,omitempty" yaml:"persistence,omitempty"`
+}
+
+// LoadBalancerPersistenceConfig is the persistence of a load balancer
+// service profile in a GatewayConfig
+type LoadBalancerPersistenceConfig struct {
+	Method     string `json:"method" yaml:"method"`
+	CookieName string `json:"cookieName,omitempty" yaml:"cookieName,omitempty"`
+	CookieMode string `json:"cookieMode,omitempty" yaml:"cookieMode,omitempty"`
+}
+
+// DhcpConfig is the DHCP service of a GatewayConfig
+type DhcpConfig struct {
+	Enabled bool              `json:"enabled" yaml:"enabled"`
+	Pools   []*DhcpPoolConfig `json:"pools,omitempty" yaml:"pools,omitempty"`
+}
+
+// DhcpPoolConfig is the DHCP pool of the network named Network in a
+// GatewayConfig
+type DhcpPoolConfig struct {
+	Enabled          bool   `json:"enabled" yaml:"enabled"`
+	Network          string `json:"network" yaml:"network"`
+	DefaultLeaseTime int    `json:"defaultLeaseTime,omitempty" yaml:"defaultLeaseTime,omitempty"`
+	MaxLeaseTime     int    `json:"maxLeaseTime" yaml:"maxLeaseTime"`
+	LowIPAddress     string `json:"lowIpAddress" yaml:"lowIpAddress"`
+	HighIPAddress    string `json:"highIpAddress" yaml:"highIpAddress"`
+}
+
+// IpsecVpnConfig is the IPsec VPN service of a GatewayConfig
+type IpsecVpnConfig struct {
+	Enabled  bool                    `json:"enabled" yaml:"enabled"`
+	Endpoint *IpsecVpnEndpointConfig `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	Tunnels  []*IpsecVpnTunnelConfig `json:"tunnels,omitempty" yaml:"tunnels,omitempty"`
+}
+
+// IpsecVpnEndpointConfig is the endpoint of the IPsec VPN service of a
+// GatewayConfig, on the uplink connected to the network named Network
+type IpsecVpnEndpointConfig struct {
+	Network  string `json:"network" yaml:"network"`
+	PublicIP string `json:"publicIp,omitempty" yaml:"publicIp,omitempty"`
+}
+
+// IpsecVpnTunnelConfig is an IPsec VPN tunnel of a GatewayConfig. At most one
+// of ThirdPartyPeer, LocalPeer and RemotePeer is set, the last two name
+// gateways of a vCloud, which are only known by their identifier.
+type IpsecVpnTunnelConfig struct {
+	Name                  string                        `json:"name" yaml:"name"`
+	Description           string                        `json:"description,omitempty" yaml:"description,omitempty"`
+	Enabled               bool                          `json:"enabled" yaml:"enabled"`
+	ThirdPartyPeer        *IpsecVpnThirdPartyPeerConfig `json:"thirdPartyPeer,omitempty" yaml:"thirdPartyPeer,omitempty"`
+	LocalPeer             *IpsecVpnLocalPeerConfig      `json:"localPeer,omitempty" yaml:"localPeer,omitempty"`
+	RemotePeer            *IpsecVpnRemotePeerConfig     `json:"remotePeer,omitempty" yaml:"remotePeer,omitempty"`
+	PeerIPAddress         string                        `json:"peerIpAddress" yaml:"peerIpAddress"`
+	PeerID                string                        `json:"peerId" yaml:"peerId"`
+	LocalIPAddress        string                        `json:"localIpAddress" yaml:"localIpAddress"`
+	LocalID               string                        `json:"localId" yaml:"localId"`
+	LocalSubnets          []*IpsecVpnSubnetConfig       `json:"localSubnets,omitempty" yaml:"localSubnets,omitempty"`
+	PeerSubnets           []*IpsecVpnSubnetConfig       `json:"peerSubnets,omitempty" yaml:"peerSubnets,omitempty"`
+	SharedSecret          string                        `json:"sharedSecret,omitempty" yaml:"sharedSecret,omitempty"`
+	SharedSecretEncrypted bool                          `json:"sharedSecretEncrypted,omitempty" yaml:"sharedSecretEncrypted,omitempty"`
+	EncryptionProtocol    string                        `json:"encryptionProtocol" yaml:"encryptionProtocol"`
+	Mtu                   int                           `json:"mtu" yaml:"mtu"`
+}
+
+// IpsecVpnThirdPartyPeerConfig is a peer of an IPsec VPN tunnel outside of
+// vCloud in a GatewayConfig
+type IpsecVpnThirdPartyPeerConfig struct {
+	PeerID string `json:"peerId,omitempty" yaml:"peerId,omitempty"`
+}
+
+// IpsecVpnLocalPeerConfig is a peer of an IPsec VPN tunnel in the same
+// vCloud in a GatewayConfig
+type IpsecVpnLocalPeerConfig struct {
+	ID   string `json:"id" yaml:"id"`
+	Name string `json:"name" yaml:"name"`
+}
+
+// IpsecVpnRemotePeerConfig is a peer of an IPsec VPN tunnel in another
+// vCloud in a GatewayConfig
+type IpsecVpnRemotePeerConfig struct {
+	ID              string `json:"id" yaml:"id"`
+	Name            string `json:"name" yaml:"name"`
+	VcdURL          string `json:"vcdUrl" yaml:"vcdUrl"`
+	VcdOrganization string `json:"vcdOrganization" yaml:"vcdOrganization"`
+	VcdUsername     string `json:"vcdUsername" yaml:"vcdUsername"`
+}
+
+// IpsecVpnSubnetConfig is a subnet at one end of an IPsec VPN tunnel in a
+// GatewayConfig
+type IpsecVpnSubnetConfig struct {
+	Name    string `json:"name" yaml:"name"`
+	Gateway string `json:"gateway" yaml:"gateway"`
+	Netmask string `json:"netmask" yaml:"netmask"`
+}
+
+// StaticRoutingConfig is the static routing service of a GatewayConfig
+type StaticRoutingConfig struct {
+	Enabled bool                 `json:"enabled" yaml:"enabled"`
+	Routes  []*StaticRouteConfig `json:"routes,omitempty" yaml:"routes,omitempty"`
+}
+
+// StaticRouteConfig is a static route of a GatewayConfig, bound to the
+// interface connected to the network named GatewayInterface or to the kind of
+// interface named by Interface
+type StaticRouteConfig struct {
+	Name             string `json:"name" yaml:"name"`
+	Network          string `json:"network" yaml:"network"`
+	NextHopIP        string `json:"nextHopIp" yaml:"nextHopIp"`
+	Interface        string `json:"interface,omitempty" yaml:"interface,omitempty"`
+	GatewayInterface string `json:"gatewayInterface,omitempty" yaml:"gatewayInterface,omitempty"`
+}
+
+// ParseGatewayConfig decodes a JSON document written from an ExportConfig
+// result
+func ParseGatewayConfig(data []byte) (*GatewayConfig, error) {
+
+	config := new(GatewayConfig)
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("error decoding gateway configuration: %s", err)
+	}
+
+	if config.Version != GatewayConfigVersion {
+		return nil, fmt.Errorf("unsupported gateway configuration version: %d", config.Version)
+	}
+
+	return config, nil
+}
+
+// eachNetworkReference calls fn with each network reference of services
+func eachNetworkReference(services *types.GatewayFeatures, fn func(ref **types.Reference) error) error {
+
+	var refs []**types.Reference
+	if services.NatService != nil {
+		for _, rule := range services.NatService.NatRule {
+			if rule.GatewayNatRule != nil {
+				refs = append(refs, &rule.GatewayNatRule.Interface)
+			}
+		}
+	}
+	if services.LoadBalancerService != nil {
+		for _, vs := range services.LoadBalancerService.VirtualServer {
+			refs = append(refs, &vs.Interface)
+		}
+	}
+	if services.GatewayDhcpService != nil {
+		for _, pool := range services.GatewayDhcpService.Pool {
+			refs = append(refs, &pool.Network)
+		}
+	}
+	if services.GatewayIpsecVpnService != nil && services.GatewayIpsecVpnService.Endpoint != nil {
+		refs = append(refs, &services.GatewayIpsecVpnService.Endpoint.Network)
+	}
+	if services.StaticRoutingService != nil {
+		for _, route := range services.StaticRoutingService.StaticRoute {
+			refs = append(refs, &route.GatewayInterface)
+		}
+	}
+
+	for _, ref := range refs {
+		if *ref == nil {
+			continue
+		}
+		if err := fn(ref); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// refName returns the name of the network of ref, nil has no name
+func refName(ref *types.Reference) string {
+	if ref == nil {
+		return ""
+	}
+	return ref.Name
+}
+
+// nameRef returns a reference to the network named name, an empty name has
+// no reference
+func nameRef(name string) *types.Reference {
+	if name == "" {
+		return nil
+	}
+	return &types.Reference{Name: name}
+}
+
+// portableConfig turns services, whose network references it changes, into a
+// portable document. interfaces are the interfaces of the gateway the
+// services come from.
+func portableConfig(services *types.GatewayFeatures, interfaces []*types.GatewayInterface, includeSecrets bool) (*GatewayConfig, error) {
+
+	err := eachNetworkReference(services, func(ref **types.Reference) error {
+		name := (*ref).Name
+		if name == "" {
+			for _, gif := range interfaces {
+				if gif.Network != nil && gif.Network.HREF == (*ref).HREF {
+					name = gif.Network.Name
+				}
+			}
+		}
+		if name == "" {
+			return fmt.Errorf("can't find the name of network: %s", (*ref).HREF)
+		}
+		*ref = &types.Reference{Name: name}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	config := &GatewayConfig{Version: GatewayConfigVersion}
+
+	if fw := services.FirewallService; fw != nil {
+		config.Firewall = &FirewallConfig{
+			Enabled:          fw.IsEnabled,
+			DefaultAction:    fw.DefaultAction,
+			LogDefaultAction: fw.LogDefaultAction,
+		}
+		for _, rule := range fw.FirewallRule {
+			r := &FirewallRuleConfig{
+				Enabled:              rule.IsEnabled,
+				Description:          rule.Description,
+				Policy:               rule.Policy,
+				IcmpSubType:          rule.IcmpSubType,
+				MatchOnTranslate:     rule.MatchOnTranslate,
+				Port:                 rule.Port,
+				DestinationPortRange: rule.DestinationPortRange,
+				DestinationIP:        rule.DestinationIP,
+				DestinationVM:        portableVM(rule.DestinationVM),
+				SourcePort:           rule.SourcePort,
+				SourcePortRange:      rule.SourcePortRange,
+				SourceIP:             rule.SourceIP,
+				SourceVM:             portableVM(rule.SourceVM),
+				Direction:            rule.Direction,
+				EnableLogging:        rule.EnableLogging,
+			}
+			if p := rule.Protocols; p != nil {
+				r.Protocols = &FirewallProtocolsConfig{Any: p.Any, TCP: p.TCP, UDP: p.UDP, ICMP: p.ICMP}
+			}
+			config.Firewall.Rules = append(config.Firewall.Rules, r)
+		}
+	}
+
+	if nat := services.NatService; nat != nil {
+		config.Nat = &NatConfig{
+			Enabled:    nat.IsEnabled,
+			NatType:    nat.NatType,
+			Policy:     nat.Policy,
+			ExternalIP: nat.ExternalIP,
+		}
+		for _, rule := range nat.NatRule {
+			gnr := rule.GatewayNatRule
+			if gnr == nil {
+				return nil, fmt.Errorf("NAT rule %s is not a gateway NAT rule", rule.ID)
+			}
+			config.Nat.Rules = append(config.Nat.Rules, &NatRuleConfig{
+				Type:           rule.RuleType,
+				Enabled:        rule.IsEnabled,
+				Description:    rule.Description,
+				Interface:      refName(gnr.Interface),
+				OriginalIP:     gnr.OriginalIP,
+				OriginalPort:   gnr.OriginalPort,
+				TranslatedIP:   gnr.TranslatedIP,
+				TranslatedPort: gnr.TranslatedPort,
+				Protocol:       gnr.Protocol,
+				IcmpSubType:    gnr.IcmpSubType,
+			})
+		}
+	}
+
+	if lb := services.LoadBalancerService; lb != nil {
+		config.LoadBalancer = &LoadBalancerConfig{Enabled: lb.IsEnabled}
+		for _, pool := range lb.Pool {
+			p := &LoadBalancerPoolConfig{
+				Name:         pool.Name,
+				Description:  pool.Description,
+				ServicePorts: portableServicePorts(pool.ServicePort),
+			}
+			for _, member := range pool.Member {
+				p.Members = append(p.Members, &LoadBalancerMemberConfig{
+					IPAddress:    member.IPAddress,
+					Weight:       member.Weight,
+					ServicePorts: portableServicePorts(member.ServicePort),
+				})
+			}
+			sort.SliceStable(p.Members, func(i, j int) bool { return p.Members[i].IPAddress < p.Members[j].IPAddress })
+			config.LoadBalancer.Pools = append(config.LoadBalancer.Pools, p)
+		}
+		for _, vs := range lb.VirtualServer {
+			v := &LoadBalancerVirtualServerConfig{
+				Enabled:     vs.IsEnabled,
+				Name:        vs.Name,
+				Description: vs.Description,
+				Interface:   refName(vs.Interface),
+				IPAddress:   vs.IPAddress,
+				Logging:     vs.Logging,
+				Pool:        vs.Pool,
+			}
+			for _, profile := range vs.ServiceProfile {
+				sp := &LoadBalancerServiceProfileConfig{
+					Enabled:  profile.IsEnabled,
+					Protocol: profile.Protocol,
+					Port:     profile.Port,
+				}
+				if ps := profile.Persistence; ps != nil {
+					sp.Persistence = &LoadBalancerPersistenceConfig{Method: ps.Method, CookieName: ps.CookieName, CookieMode: ps.CookieMode}
+				}
+				v.ServiceProfiles = append(v.ServiceProfiles, sp)
+			}
+			config.LoadBalancer.VirtualServers = append(config.LoadBalancer.VirtualServers, v)
+		}
+		pools, servers := config.LoadBalancer.Pools, config.LoadBalancer.VirtualServers
+		sort.SliceStable(pools, func(i, j int) bool { return pools[i].Name < pools[j].Name })
+		sort.SliceStable(servers, func(i, j int) bool { return servers[i].Name < servers[j].Name })
+	}
+
+	if dhcp := services.GatewayDhcpService; dhcp != nil {
+		config.Dhcp = &DhcpConfig{Enabled: dhcp.IsEnabled}
+		for _, pool := range dhcp.Pool {
+			config.Dhcp.Pools = append(config.Dhcp.Pools, &DhcpPoolConfig{
+				Enabled:          pool.IsEnabled,
+				Network:          refName(pool.Network),
+				DefaultLeaseTime: pool.DefaultLeaseTime,
+				MaxLeaseTime:     pool.MaxLeaseTime,
+				LowIPAddress:     pool.LowIPAddress,
+				HighIPAddress:    pool.HighIPAddress,
+			})
+		}
+		pools := config.Dhcp.Pools
+		sort.SliceStable(pools, func(i, j int) bool { return pools[i].Network < pools[j].Network })
+	}
+
+	if vpn := services.GatewayIpsecVpnService; vpn != nil {
+		config.IpsecVpn = &IpsecVpnConfig{Enabled: vpn.IsEnabled}
+		if vpn.Endpoint != nil {
+			config.IpsecVpn.Endpoint = &IpsecVpnEndpointConfig{Network: refName(vpn.Endpoint.Network), PublicIP: vpn.Endpoint.PublicIP}
+		}
+		for _, tunnel := range vpn.Tunnel {
+			t := &IpsecVpnTunnelConfig{
+				Name:               tunnel.Name,
+				Description:        tunnel.Description,
+				Enabled:            tunnel.IsEnabled,
+				PeerIPAddress:      tunnel.PeerIPAddress,
+				PeerID:             tunnel.PeerID,
+				LocalIPAddress:     tunnel.LocalIPAddress,
+				LocalID:            tunnel.LocalID,
+				LocalSubnets:       portableSubnets(tunnel.LocalSubnet),
+				PeerSubnets:        portableSubnets(tunnel.PeerSubnet),
+				EncryptionProtocol: tunnel.EncryptionProtocol,
+				Mtu:                tunnel.Mtu,
+			}
+			if p := tunnel.IpsecVpnThirdPartyPeer; p != nil {
+				t.ThirdPartyPeer = &IpsecVpnThirdPartyPeerConfig{PeerID: p.PeerID}
+			}
+			if p := tunnel.IpsecVpnLocalPeer; p != nil {
+				t.LocalPeer = &IpsecVpnLocalPeerConfig{ID: p.ID, Name: p.Name}
+			}
+			if p := tunnel.IpsecVpnRemotePeer; p != nil {
+				t.RemotePeer = &IpsecVpnRemotePeerConfig{ID: p.ID, Name: p.Name, VcdURL: p.VcdURL, VcdOrganization: p.VcdOrganization, VcdUsername: p.VcdUsername}
+			}
+			if includeSecrets {
+				t.SharedSecret, t.SharedSecretEncrypted = tunnel.SharedSecret, tunnel.SharedSecretEncrypted
+			}
+			config.IpsecVpn.Tunnels = append(config.IpsecVpn.Tunnels, t)
+		}
+		tunnels := config.IpsecVpn.Tunnels
+		sort.SliceStable(tunnels, func(i, j int) bool { return tunnels[i].Name < tunnels[j].Name })
+	}
+
+	if routing := services.StaticRoutingService; routing != nil {
+		config.StaticRouting = &StaticRoutingConfig{Enabled: routing.IsEnabled}
+		for _, route := range routing.StaticRoute {
+			config.StaticRouting.Routes = append(config.StaticRouting.Routes, &StaticRouteConfig{
+				Name:             route.Name,
+				Network:          route.Network,
+				NextHopIP:        route.NextHopIP,
+				Interface:        route.Interface,
+				GatewayInterface: refName(route.GatewayInterface),
+			})
+		}
+		routes := config.StaticRouting.Routes
+		sort.SliceStable(routes, func(i, j int) bool { return routes[i].Name < routes[j].Name })
+	}
+
+	return config, nil
+}
+
+// portableVM returns the portable copy of vm
+func portableVM(vm *types.VMSelection) *FirewallVMConfig {
+	if vm == nil {
+		return nil
+	}
+	return &FirewallVMConfig{VAppScopedVMID: vm.VAppScopedVMID, NicID: vm.VMNicID, IPType: vm.IPType}
+}
+
+// portableServicePorts returns the portable copies of ports
+func portableServicePorts(ports []*types.LBPoolServicePort) []*LoadBalancerServicePortConfig {
+	var portable []*LoadBalancerServicePortConfig
+	for _, port := range ports {
+		p := &LoadBalancerServicePortConfig{
+			Enabled:         port.IsEnabled,
+			Protocol:        port.Protocol,
+			Algorithm:       port.Algorithm,
+			Port:            port.Port,
+			HealthCheckPort: port.HealthCheckPort,
+		}
+		if hc := port.HealthCheck; hc != nil {
+			p.HealthCheck = &LoadBalancerHealthCheckConfig{
+				Mode:              hc.Mode,
+				URI:               hc.URI,
+				HealthThreshold:   hc.HealthThreshold,
+				UnhealthThreshold: hc.UnhealthThreshold,
+				Interval:          hc.Interval,
+				Timeout:           hc.Timeout,
+			}
+		}
+		portable = append(portable, p)
+	}
+	return portable
+}
+
+// portableSubnets returns the portable copies of subnets
+func portableSubnets(subnets []*types.IpsecVpnSubnet) []*IpsecVpnSubnetConfig {
+	var portable []*IpsecVpnSubnetConfig
+	for _, subnet := range subnets {
+		portable = append(portable, &IpsecVpnSubnetConfig{Name: subnet.Name, Gateway: subnet.Gateway, Netmask: subnet.Netmask})
+	}
+	return portable
+}
+
+// gatewayServices turns this document back into the services it describes,
+// with network references holding names only
+func (g *GatewayConfig) gatewayServices() *types.GatewayFeatures {
+
+	services := new(types.GatewayFeatures)
+
+	if fw := g.Firewall; fw != nil {
+		services.FirewallService = &types.FirewallService{
+			IsEnabled:        fw.Enabled,
+			DefaultAction:    fw.DefaultAction,
+			LogDefaultAction: fw.LogDefaultAction,
+		}
+		for _, r := range fw.Rules {
+			rule := &types.FirewallRule{
+				IsEnabled:            r.Enabled,
+				Description:          r.Description,
+				Policy:               r.Policy,
+				IcmpSubType:          r.IcmpSubType,
+				MatchOnTranslate:     r.MatchOnTranslate,
+				Port:                 r.Port,
+				DestinationPortRange: r.DestinationPortRange,
+				DestinationIP:        r.DestinationIP,
+				DestinationVM:        r.DestinationVM.vmSelection(),
+				SourcePort:           r.SourcePort,
+				SourcePortRange:      r.SourcePortRange,
+				SourceIP:             r.SourceIP,
+				SourceVM:             r.SourceVM.vmSelection(),
+				Direction:            r.Direction,
+				EnableLogging:        r.EnableLogging,
+			}
+			if p := r.Protocols; p != nil {
+				rule.Protocols = &types.FirewallRuleProtocols{Any: p.Any, TCP: p.TCP, UDP: p.UDP, ICMP: p.ICMP}
+			}
+			services.FirewallService.FirewallRule = append(services.FirewallService.FirewallRule, rule)
+		}
+	}
+
+	if nat := g.Nat; nat != nil {
+		services.NatService = &types.NatService{
+			IsEnabled:  nat.Enabled,
+			NatType:    nat.NatType,
+			Policy:     nat.Policy,
+			ExternalIP: nat.ExternalIP,
+		}
+		for _, r := range nat.Rules {
+			services.NatService.NatRule = append(services.NatService.NatRule, &types.NatRule{
+				RuleType:    r.Type,
+				IsEnabled:   r.Enabled,
+				Description: r.Description,
+				GatewayNatRule: &types.GatewayNatRule{
+					Interface:      nameRef(r.Interface),
+					OriginalIP:     r.OriginalIP,
+					OriginalPort:   r.OriginalPort,
+					TranslatedIP:   r.TranslatedIP,
+					TranslatedPort: r.TranslatedPort,
+					Protocol:       r.Protocol,
+					IcmpSubType:    r.IcmpSubType,
+				},
+			})
+		}
+	}
+
+	if lb := g.LoadBalancer; lb != nil {
+		services.LoadBalancerService = &types.LoadBalancerService{IsEnabled: lb.Enabled}
+		for _, p := range lb.Pools {
+			pool := &types.LoadBalancerPool{
+				Name:        p.Name,
+				Description: p.Description,
+				ServicePort: servicePorts(p.ServicePorts),
+			}
+			for _, m := range p.Members {
+				pool.Member = append(pool.Member, &types.LBPoolMember{
+					IPAddress:   m.IPAddress,
+					Weight:      m.Weight,
+					ServicePort: servicePorts(m.ServicePorts),
+				})
+			}
+			services.LoadBalancerService.Pool = append(services.LoadBalancerService.Pool, pool)
+		}
+		for _, v := range lb.VirtualServers {
+			vs := &types.LoadBalancerVirtualServer{
+				IsEnabled:   v.Enabled,
+				Name:        v.Name,
+				Description: v.Description,
+				Interface:   nameRef(v.Interface),
+				IPAddress:   v.IPAddress,
+				Logging:     v.Logging,
+				Pool:        v.Pool,
+			}
+			for _, sp := range v.ServiceProfiles {
+				profile := &types.LBVirtualServerServiceProfile{
+					IsEnabled: sp.Enabled,
+					Protocol:  sp.Protocol,
+					Port:      sp.Port,
+				}
+				if ps := sp.Persistence; ps != nil {
+					profile.Persistence = &types.LBPersistence{Method: ps.Method, CookieName: ps.CookieName, CookieMode: ps.CookieMode}
+				}
+				vs.ServiceProfile = append(vs.ServiceProfile, profile)
+			}
+			services.LoadBalancerService.VirtualServer = append(services.LoadBalancerService.VirtualServer, vs)
+		}
+	}
+
+	if dhcp := g.Dhcp; dhcp != nil {
+		services.GatewayDhcpService = &types.GatewayDhcpService{IsEnabled: dhcp.Enabled}
+		for _, p := range dhcp.Pools {
+			services.GatewayDhcpService.Pool = append(services.GatewayDhcpService.Pool, &types.DhcpPoolService{
+				IsEnabled:        p.Enabled,
+				Network:          nameRef(p.Network),
+				DefaultLeaseTime: p.DefaultLeaseTime,
+				MaxLeaseTime:     p.MaxLeaseTime,
+				LowIPAddress:     p.LowIPAddress,
+				HighIPAddress:    p.HighIPAddress,
+			})
+		}
+	}
+
+	if vpn := g.IpsecVpn; vpn != nil {
+		services.GatewayIpsecVpnService = &types.GatewayIpsecVpnService{IsEnabled: vpn.Enabled}
+		if vpn.Endpoint != nil {
+			services.GatewayIpsecVpnService.Endpoint = &types.GatewayIpsecVpnEndpoint{Network: nameRef(vpn.Endpoint.Network), PublicIP: vpn.Endpoint.PublicIP}
+		}
+		for _, t := range vpn.Tunnels {
+			tunnel := &types.GatewayIpsecVpnTunnel{
+				Name:                  t.Name,
+				Description:           t.Description,
+				IsEnabled:             t.Enabled,
+				PeerIPAddress:         t.PeerIPAddress,
+				PeerID:                t.PeerID,
+				LocalIPAddress:        t.LocalIPAddress,
+				LocalID:               t.LocalID,
+				LocalSubnet:           vpnSubnets(t.LocalSubnets),
+				PeerSubnet:            vpnSubnets(t.PeerSubnets),
+				SharedSecret:          t.SharedSecret,
+				SharedSecretEncrypted: t.SharedSecretEncrypted,
+				EncryptionProtocol:    t.EncryptionProtocol,
+				Mtu:                   t.Mtu,
+			}
+			if p := t.ThirdPartyPeer; p != nil {
+				tunnel.IpsecVpnThirdPartyPeer = &types.IpsecVpnThirdPartyPeer{PeerID: p.PeerID}
+			}
+			if p := t.LocalPeer; p != nil {
+				tunnel.IpsecVpnLocalPeer = &types.IpsecVpnLocalPeer{ID: p.ID, Name: p.Name}
+			}
+			if p := t.RemotePeer; p != nil {
+				tunnel.IpsecVpnRemotePeer = &types.IpsecVpnRemotePeer{ID: p.ID, Name: p.Name, VcdURL: p.VcdURL, VcdOrganization: p.VcdOrganization, VcdUsername: p.VcdUsername}
+			}
+			services.GatewayIpsecVpnService.Tunnel = append(services.GatewayIpsecVpnService.Tunnel, tunnel)
+		}
+	}
+
+	if routing := g.StaticRouting; routing != nil {
+		services.StaticRoutingService = &types.StaticRoutingService{IsEnabled: routing.Enabled}
+		for _, r := range routing.Routes {
+			services.StaticRoutingService.StaticRoute = append(services.StaticRoutingService.StaticRoute, &types.StaticRoute{
+				Name:             r.Name,
+				Network:          r.Network,
+				NextHopIP:        r.NextHopIP,
+				Interface:        r.Interface,
+				GatewayInterface: nameRef(r.GatewayInterface),
+			})
+		}
+	}
+
+	return services
+}
+
+// vmSelection returns the VM selection vm describes
+func (vm *FirewallVMConfig) vmSelection() *types.VMSelection {
+	if vm == nil {
+		return nil
+	}
+	return &types.VMSelection{VAppScopedVMID: vm.VAppScopedVMID, VMNicID: vm.NicID, IPType: vm.IPType}
+}
+
+// servicePorts returns the load balancer service ports portable describes
+func servicePorts(portable []*LoadBalancerServicePortConfig) []*types.LBPoolServicePort {
+	var ports []*types.LBPoolServicePort
+	for _, p := range portable {
+		port := &types.LBPoolServicePort{
+			IsEnabled:       p.Enabled,
+			Protocol:        p.Protocol,
+			Algorithm:       p.Algorithm,
+			Port:            p.Port,
+			HealthCheckPort: p.HealthCheckPort,
+		}
+		if hc := p.HealthCheck; hc != nil {
+			port.HealthCheck = &types.LBPoolHealthCheck{
+				Mode:              hc.Mode,
+				URI:               hc.URI,
+				HealthThreshold:   hc.HealthThreshold,
+				UnhealthThreshold: hc.UnhealthThreshold,
+				Interval:          hc.Interval,
+				Timeout:           hc.Timeout,
+			}
+		}
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+// vpnSubnets returns the IPsec VPN subnets portable describes
+func vpnSubnets(portable []*IpsecVpnSubnetConfig) []*types.IpsecVpnSubnet {
+	var subnets []*types.IpsecVpnSubnet
+	for _, s := range portable {
+		subnets = append(subnets, &types.IpsecVpnSubnet{Name: s.Name, Gateway: s.Gateway, Netmask: s.Netmask})
+	}
+	return subnets
+}
+
+// ExportConfig refreshes this gateway and returns a portable copy of its
+// firewall, NAT, load balancer, DHCP, IPsec VPN and static routing services.
+// The shared secrets of the VPN tunnels are left out unless includeSecrets
+// is set.
+func (e *EdgeGateway) ExportConfig(includeSecrets bool) (*GatewayConfig, error) {
+
+	if err := e.Refresh(); err != nil {
+		return nil, err
+	}
+
+	data, err := e.gatewayServices()
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := copyServices(data)
+	if err != nil {
+		return nil, err
+	}
+
+	interfaces, err := e.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	return portableConfig(services, interfaces, includeSecrets)
+}
+
+// ImportConfig replaces the services of this gateway present in config,
+// translating network names to the networks of the interfaces of this
+// gateway. A VPN tunnel without shared secret keeps the secret of the tunnel
+// with the same name on this gateway.
+func (e *EdgeGateway) ImportConfig(config *GatewayConfig) (Task, error) {
+
+	if config == nil || config.Version != GatewayConfigVersion {
+		return Task{}, fmt.Errorf("unsupported gateway configuration")
+	}
+
+	return e.ModifyServices(func(current *types.GatewayFeatures) error {
+
+		// Fresh services on each call, mutate can run more than once
+		services := config.gatewayServices()
+
+		err := eachNetworkReference(services, func(ref **types.Reference) error {
+			gif, err := e.FindInterface((*ref).Name)
+			if err != nil {
+				return err
+			}
+			*ref = &types.Reference{HREF: gif.Network.HREF, Name: gif.Network.Name, Type: gif.Network.Type}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if vpn := services.GatewayIpsecVpnService; vpn != nil {
+			for _, tunnel := range vpn.Tunnel {
+				if tunnel.SharedSecret != "" {
+					continue
+				}
+				if current.GatewayIpsecVpnService != nil {
+					for _, t := range current.GatewayIpsecVpnService.Tunnel {
+						if t.Name == tunnel.Name {
+							tunnel.SharedSecret, tunnel.SharedSecretEncrypted = t.SharedSecret, t.SharedSecretEncrypted
+						}
+					}
+				}
+				if tunnel.SharedSecret == "" {
+					return fmt.Errorf("IPsec VPN tunnel %s has no shared secret", tunnel.Name)
+				}
+			}
+		}
+
+		if services.FirewallService != nil {
+			current.FirewallService = services.FirewallService
+		}
+		if services.NatService != nil {
+			current.NatService = services.NatService
+		}
+		if services.LoadBalancerService != nil {
+			current.LoadBalancerService = services.LoadBalancerService
+		}
+		if services.GatewayDhcpService != nil {
+			current.GatewayDhcpService = services.GatewayDhcpService
+		}
+		if services.GatewayIpsecVpnService != nil {
+			current.GatewayIpsecVpnService = services.GatewayIpsecVpnService
+		}
+		if services.StaticRoutingService != nil {
+			current.StaticRoutingService = services.StaticRoutingService
+		}
+
+		return nil
+	})
+}
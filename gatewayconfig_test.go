@@ -0,0 +1,163 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+func Test_ExportConfig(t *testing.T) {
+	cc := new(callCounter)
+	state := newEdgeGatewayState(fullEdgegatewayExample)
+
+	ctx, err := setupTestContext(authHandler(state.handler(cc)))
+	if assert.NoError(t, err) {
+
+		edge, err := ctx.VDC.FindEdgeGateway("M916272752-5793")
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+
+			config, err := edge.ExportConfig(false)
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Equal(t, GatewayConfigVersion, config.Version)
+
+				if assert.Len(t, config.Firewall.Rules, 4) {
+					assert.Equal(t, "ssh prd-001", config.Firewall.Rules[0].Description)
+					assert.True(t, config.Firewall.Rules[0].Protocols.TCP)
+				}
+				if assert.Len(t, config.Nat.Rules, 4) {
+					assert.Equal(t, "d2p3-ext", config.Nat.Rules[0].Interface)
+				}
+				assert.Equal(t, "d2p3-ext", config.LoadBalancer.VirtualServers[0].Interface)
+				assert.Equal(t, "M916272752-5793-default-routed", config.Dhcp.Pools[0].Network)
+				assert.Equal(t, "branch", config.StaticRouting.Routes[0].Name)
+
+				if assert.Len(t, config.IpsecVpn.Tunnels, 2) {
+					// sorted by name
+					assert.Equal(t, "JGray VPN", config.IpsecVpn.Tunnels[0].Name)
+					assert.Equal(t, "", config.IpsecVpn.Tunnels[0].SharedSecret)
+				}
+
+				// only settings, in camel case, make it to the document
+				document, err := json.Marshal(config)
+				if assert.NoError(t, err) {
+					for _, wire := range []string{"Xmlns", "xmlns", "href", "HREF", `"id"`, `"Id"`, `"ID"`, "Operational", "ErrorDetails", "IsEnabled"} {
+						assert.NotContains(t, string(document), wire)
+					}
+					assert.Contains(t, string(document), `"destinationPortRange":"Any"`)
+				}
+			}
+
+			config, err = edge.ExportConfig(true)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "Blah1Blah2Blah3Blah1Blah2Blah3Blah1Blah2Blah3", config.IpsecVpn.Tunnels[1].SharedSecret)
+			}
+		}
+	}
+}
+
+func Test_ExportConfigIsStable(t *testing.T) {
+	services := new(types.GatewayFeatures)
+	assert.NoError(t, xml.Unmarshal([]byte(xmlSection(fullEdgegatewayExample, "EdgeGatewayServiceConfiguration")), services))
+
+	first, err := portableConfig(services, nil, true)
+	if !assert.NoError(t, err) {
+		return
+	}
+	expected, _ := json.Marshal(first)
+
+	reordered := new(types.GatewayFeatures)
+	assert.NoError(t, xml.Unmarshal([]byte(xmlSection(fullEdgegatewayExample, "EdgeGatewayServiceConfiguration")), reordered))
+	tunnels := reordered.GatewayIpsecVpnService.Tunnel
+	tunnels[0], tunnels[1] = tunnels[1], tunnels[0]
+	members := reordered.LoadBalancerService.Pool[0].Member
+	members[0], members[1] = members[1], members[0]
+
+	second, err := portableConfig(reordered, nil, true)
+	if assert.NoError(t, err) {
+		actual, _ := json.Marshal(second)
+		assert.Equal(t, string(expected), string(actual))
+	}
+}
+
+func Test_ImportConfig(t *testing.T) {
+	cc := new(callCounter)
+	state := newEdgeGatewayState(fullEdgegatewayExample)
+
+	ctx, err := setupTestContext(authHandler(state.handler(cc)))
+	if assert.NoError(t, err) {
+
+		edge, err := ctx.VDC.FindEdgeGateway("M916272752-5793")
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+
+			exported, err := edge.ExportConfig(false)
+			if !assert.NoError(t, err) {
+				return
+			}
+			document, err := json.MarshalIndent(exported, "", "  ")
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			config, err := ParseGatewayConfig(document)
+			if !assert.NoError(t, err) {
+				return
+			}
+			cc.Pop()
+
+			_, err = edge.ImportConfig(config)
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				imported := state.config()
+				uplink := ctx.Server.URL + "/api/admin/network/6254f107-9876-4d03-986f-8bec7a4bcb3f"
+				assert.Equal(t, uplink, imported.NatService.NatRule[0].GatewayNatRule.Interface.HREF)
+				assert.Equal(t, uplink, imported.LoadBalancerService.VirtualServer[0].Interface.HREF)
+				assert.Equal(t, ctx.Server.URL+"/api/admin/network/cb0f4c9e-1a46-49d4-9fcb-d228000a6bc1", imported.GatewayDhcpService.Pool[0].Network.HREF)
+				// the secrets left out of the document are kept
+				assert.Equal(t, "Blah1Blah2Blah3Blah1Blah2Blah3Blah1Blah2Blah3", imported.GatewayIpsecVpnService.Tunnel[1].SharedSecret)
+			}
+
+			// exporting again gives the same document
+			again, err := edge.ExportConfig(false)
+			if assert.NoError(t, err) {
+				document2, _ := json.MarshalIndent(again, "", "  ")
+				assert.Equal(t, string(document), string(document2))
+			}
+			cc.Pop()
+
+			config.Nat.Rules[0].Interface = "other-ext"
+			_, err = edge.ImportConfig(config)
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+
+			config.Nat = nil
+			config.IpsecVpn.Tunnels = append(config.IpsecVpn.Tunnels, &IpsecVpnTunnelConfig{Name: "new"})
+			_, err = edge.ImportConfig(config)
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+
+			_, err = ParseGatewayConfig([]byte(`{"version": 2}`))
+			assert.Error(t, err)
+		}
+	}
+}
+
+// xmlSection returns the first element named tag of doc
+func xmlSection(doc, tag string) string {
+	start := strings.Index(doc, "<"+tag+">")
+	end := strings.Index(doc, "</"+tag+">") + len("</"+tag+">")
+	return doc[start:end]
+}
+
+// fullEdgegatewayExample is edgegatewayExample with a load balancer, a DHCP
+// pool and a static route
+var fullEdgegatewayExample = strings.NewReplacer(
+	xmlSection(edgegatewayExample, "StaticRoutingService"), xmlSection(routedEdgegatewayExample, "StaticRoutingService"),
+	"<GatewayIpsecVpnService>", xmlSection(dhcpEdgegatewayExample, "GatewayDhcpService")+"\n            <GatewayIpsecVpnService>",
+).Replace(loadbalancedEdgegatewayExample)
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"fmt"
+	"strings"
+
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// The types of edge gateway interfaces
+const (
+	GatewayInterfaceUplink   = "uplink"
+	GatewayInterfaceInternal = "internal"
+)
+
+// Interfaces returns the interfaces of the last known configuration of this
+// gateway, with their type, network, subnet participation and whether they
+// carry the default route
+func (e *EdgeGateway) Interfaces() ([]*types.GatewayInterface, error) {
+
+	if e.EdgeGateway.Configuration == nil || e.EdgeGateway.Configuration.GatewayInterfaces == nil {
+		return nil, fmt.Errorf("edge gateway has no interfaces")
+	}
+
+	return e.EdgeGateway.Configuration.GatewayInterfaces.GatewayInterface, nil
+}
+
+// FindInterface finds the interface of this gateway connected to a network,
+// by name or HREF
+func (e *EdgeGateway) FindInterface(network string) (*types.GatewayInterface, error) {
+
+	interfaces, err := e.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, gif := range interfaces {
+		if matchesNetwork(gif.Network, network) {
+			return gif, nil
+		}
+	}
+
+	return nil, fmt.Errorf("can't find interface for network: %s", network)
+}
+
+// FindUplink finds the uplink interface of this gateway connected to an
+// external network, by name or HREF
+func (e *EdgeGateway) FindUplink(network string) (*types.GatewayInterface, error) {
+
+	gif, err := e.FindInterface(network)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(gif.InterfaceType, GatewayInterfaceUplink) {
+		return nil, fmt.Errorf("interface for network %s is not an uplink", network)
+	}
+
+	return gif, nil
+}
+
+// DefaultUplink returns the uplink interface used for the default route of
+// this gateway, or its only uplink when none is
+func (e *EdgeGateway) DefaultUplink() (*types.GatewayInterface, error) {
+
+	interfaces, err := e.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var uplinks []*types.GatewayInterface
+	for _, gif := range interfaces {
+		if !strings.EqualFold(gif.InterfaceType, GatewayInterfaceUplink) || gif.Network == nil {
+			continue
+		}
+		if gif.UseForDefaultRoute {
+			return gif, nil
+		}
+		uplinks = append(uplinks, gif)
+	}
+
+	switch len(uplinks) {
+	case 0:
+		return nil, fmt.Errorf("edge gateway has no uplink interface")
+	case 1:
+		return uplinks[0], nil
+	default:
+		return nil, fmt.Errorf("edge gateway has %d uplinks and none is used for the default route, pick one explicitly", len(uplinks))
+	}
+}
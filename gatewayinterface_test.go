@@ -0,0 +1,98 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GatewayInterfaces(t *testing.T) {
+	cc := new(callCounter)
+	state := newEdgeGatewayState(edgegatewayExample)
+
+	ctx, err := setupTestContext(authHandler(state.handler(cc)))
+	if assert.NoError(t, err) {
+
+		edge, err := ctx.VDC.FindEdgeGateway("M916272752-5793")
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+
+			interfaces, err := edge.Interfaces()
+			if assert.NoError(t, err) && assert.Len(t, interfaces, 3) {
+				assert.Equal(t, GatewayInterfaceInternal, interfaces[0].InterfaceType)
+				assert.Equal(t, "192.168.108.1", interfaces[0].SubnetParticipation[0].Gateway)
+				assert.True(t, interfaces[2].UseForDefaultRoute)
+			}
+
+			gif, err := edge.FindInterface("JGray Network")
+			if assert.NoError(t, err) {
+				assert.Equal(t, "internal", gif.InterfaceType)
+			}
+
+			_, err = edge.FindUplink("JGray Network")
+			assert.Error(t, err)
+
+			_, err = edge.FindInterface("missing")
+			assert.Error(t, err)
+
+			uplink, err := edge.DefaultUplink()
+			if assert.NoError(t, err) {
+				assert.Equal(t, "d2p3-ext", uplink.Network.Name)
+			}
+
+			_, err = edge.Create1to1MappingOn("d2p3-ext", "192.168.109.20", "23.92.225.73", "web")
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				rules := state.config().NatService.NatRule
+				if assert.Len(t, rules, 6) {
+					assert.Equal(t, uplink.Network.HREF, rules[4].GatewayNatRule.Interface.HREF)
+					assert.Equal(t, uplink.Network.HREF, rules[5].GatewayNatRule.Interface.HREF)
+				}
+			}
+
+			_, err = edge.Create1to1MappingOn("JGray Network", "192.168.109.21", "23.92.225.74", "web")
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+		}
+	}
+}
+
+func Test_DefaultUplinkAmbiguous(t *testing.T) {
+	cc := new(callCounter)
+	state := newEdgeGatewayState(twoUplinksEdgegatewayExample)
+
+	ctx, err := setupTestContext(authHandler(state.handler(cc)))
+	if assert.NoError(t, err) {
+
+		edge, err := ctx.VDC.FindEdgeGateway("M916272752-5793")
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+
+			_, err = edge.DefaultUplink()
+			assert.Error(t, err)
+
+			_, err = edge.Create1to1Mapping("10.0.0.1", "20.0.0.2", "description")
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+
+			_, err = edge.Create1to1MappingOn("JGray Network", "10.0.0.1", "20.0.0.2", "description")
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				rules := state.config().NatService.NatRule
+				assert.Equal(t, ctx.Server.URL+"/api/admin/network/32e903a4-c726-497f-9ec7-e4a43b58bdbc", rules[len(rules)-1].GatewayNatRule.Interface.HREF)
+			}
+		}
+	}
+}
+
+// twoUplinksEdgegatewayExample is edgegatewayExample where the JGray network
+// is a second uplink and neither uplink carries the default route
+var twoUplinksEdgegatewayExample = strings.NewReplacer(
+	`<InterfaceType>internal</InterfaceType>
+                <SubnetParticipation>
+                    <Gateway>192.168.108.1</Gateway>`, `<InterfaceType>uplink</InterfaceType>
+                <SubnetParticipation>
+                    <Gateway>192.168.108.1</Gateway>`,
+	`<UseForDefaultRoute>true</UseForDefaultRoute>`, `<UseForDefaultRoute>false</UseForDefaultRoute>`,
+).Replace(edgegatewayExample)
@@ -0,0 +1,294 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// AllocatedIP is an IP address of an org vdc network in use
+type AllocatedIP struct {
+	IPAddress string
+	// AllocationType is vmAllocated for VMs, vsmAllocated for edge gateways
+	// and natRouted for the external addresses of NAT-routed vApp networks.
+	AllocationType string
+	Deployed       bool
+	// Owner links to the entity the address is allocated to.
+	Owner *types.Link
+	// NIC is the network connection of the VM owning the address, it is nil
+	// when the owner isn't a VM.
+	NIC *types.NetworkConnection
+}
+
+// ipReservations holds the addresses reserved by ReserveIP, by network HREF
+var ipReservations = struct {
+	sync.Mutex
+	ips map[string]map[uint32]bool
+}{ips: make(map[string]map[uint32]bool)}
+
+// allocatedAddresses retrieves the addresses allocated in this network
+func (n *OrgVDCNetwork) allocatedAddresses() (*types.AllocatedIPAddresses, error) {
+
+	var href string
+	for _, l := range n.OrgVDCNetwork.Link {
+		if l.Rel == "down" && l.Type == types.MimeAllocatedNetworkAddress {
+			href = l.HREF
+		}
+	}
+	if href == "" {
+		return nil, fmt.Errorf("can't find the allocated addresses of org vdc network %s", n.OrgVDCNetwork.Name)
+	}
+
+	u, err := url.ParseRequestURI(href)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing allocated addresses href: %s", err)
+	}
+
+	req := n.c.NewRequest(map[string]string{}, types.HTTPGet, u, nil)
+
+	resp, err := checkResp(n.c.DoHTTP(req))
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving allocated addresses: %s", err)
+	}
+
+	allocated := new(types.AllocatedIPAddresses)
+
+	if err = decodeBody(resp, allocated); err != nil {
+		return nil, fmt.Errorf("error decoding allocated addresses response: %s", err)
+	}
+
+	// The request was successful
+	return allocated, nil
+}
+
+// networkConnections retrieves the network connections of the VM at href
+func networkConnections(c Client, href string) ([]*types.NetworkConnection, error) {
+
+	s, err := url.ParseRequestURI(href)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing VM href: %s", err)
+	}
+	s.Path += "/networkConnectionSection/"
+
+	req := c.NewRequest(map[string]string{}, types.HTTPGet, s, nil)
+
+	resp, err := checkResp(c.DoHTTP(req))
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving network connections: %s", err)
+	}
+
+	section := new(types.NetworkConnectionSection)
+
+	if err = decodeBody(resp, section); err != nil {
+		return nil, fmt.Errorf("error decoding network connections response: %s", err)
+	}
+
+	// The request was successful
+	return section.NetworkConnection, nil
+}
+
+// AllocatedIPs lists the IP addresses allocated in this network, with the
+// entity each one is allocated to. The network connection is looked up for
+// every VM owning an address.
+func (n *OrgVDCNetwork) AllocatedIPs() ([]AllocatedIP, error) {
+
+	allocated, err := n.allocatedAddresses()
+	if err != nil {
+		return nil, err
+	}
+
+	connections := make(map[string][]*types.NetworkConnection)
+
+	var ips []AllocatedIP
+	for _, address := range allocated.IPAddress {
+		ip := AllocatedIP{
+			IPAddress:      address.IPAddress,
+			AllocationType: address.AllocationType,
+			Deployed:       address.IsDeployed,
+		}
+
+		// The first link is the owner, the next one its parent
+		if len(address.Link) > 0 {
+			owner := address.Link[0]
+			ip.Owner = &owner
+		}
+
+		if ip.Owner != nil && ip.Owner.Type == types.MimeVM {
+			nics, ok := connections[ip.Owner.HREF]
+			if !ok {
+				if nics, err = networkConnections(n.c, ip.Owner.HREF); err != nil {
+					return nil, err
+				}
+				connections[ip.Owner.HREF] = nics
+			}
+			for _, nic := range nics {
+				if nic.Network == n.OrgVDCNetwork.Name && nic.IPAddress == ip.IPAddress {
+					ip.NIC = nic
+				}
+			}
+		}
+
+		ips = append(ips, ip)
+	}
+
+	return ips, nil
+}
+
+// usedAddresses returns the addresses of this network that are allocated or
+// reserved, along with the IP scope of the network
+func (n *OrgVDCNetwork) usedAddresses() (*types.IPScope, map[uint32]bool, error) {
+
+	if err := n.Refresh(); err != nil {
+		return nil, nil, err
+	}
+
+	scope := networkIPScope(n.OrgVDCNetwork.Configuration)
+	if scope == nil {
+		return nil, nil, fmt.Errorf("org vdc network %s has no IP scope", n.OrgVDCNetwork.Name)
+	}
+
+	allocated, err := n.allocatedAddresses()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	used := make(map[uint32]bool)
+	for _, address := range allocated.IPAddress {
+		if ip, ok := ipToUint32(address.IPAddress); ok {
+			used[ip] = true
+		}
+	}
+	if scope.AllocatedIPAddresses != nil {
+		for _, address := range scope.AllocatedIPAddresses.IPAddress {
+			if ip, ok := ipToUint32(address); ok {
+				used[ip] = true
+			}
+		}
+	}
+
+	ipReservations.Lock()
+	for ip := range ipReservations.ips[n.OrgVDCNetwork.HREF] {
+		used[ip] = true
+	}
+	ipReservations.Unlock()
+
+	return scope, used, nil
+}
+
+// FreeIPs refreshes this network and returns the addresses of its static
+// pools that are neither allocated nor reserved
+func (n *OrgVDCNetwork) FreeIPs() ([]string, error) {
+
+	scope, used, err := n.usedAddresses()
+	if err != nil {
+		return nil, err
+	}
+
+	var free []string
+	if scope.IPRanges == nil {
+		return free, nil
+	}
+
+	for _, r := range scope.IPRanges.IPRange {
+		from, ok1 := ipToUint32(r.StartAddress)
+		to, ok2 := ipToUint32(r.EndAddress)
+		if !ok1 || !ok2 {
+			continue
+		}
+		for ip := from; ip <= to && ip >= from; ip++ {
+			if !used[ip] {
+				free = append(free, uint32ToIP(ip))
+			}
+		}
+	}
+
+	return free, nil
+}
+
+// CheckPoolCapacity returns an error when the static pools of this network
+// have less than count free addresses. ComposeVApp doesn't check the pools,
+// call this first to fail before composing when they are exhausted.
+func (n *OrgVDCNetwork) CheckPoolCapacity(count int) error {
+
+	free, err := n.FreeIPs()
+	if err != nil {
+		return err
+	}
+
+	if len(free) < count {
+		return fmt.Errorf("org vdc network %s has %d free pool addresses, %d needed", n.OrgVDCNetwork.Name, len(free), count)
+	}
+
+	return nil
+}
+
+// ReserveIP reserves ip of this network and returns a network connection
+// with that address, manually assigned, for a new VM. The address must be in
+// the subnet of the network, other than its gateway, network and broadcast
+// addresses, and free. Reservations are only known to this process, they keep
+// ReserveIP and FreeIPs from handing out the address again until ReleaseIP is
+// called.
+func (n *OrgVDCNetwork) ReserveIP(ip string) (*types.NetworkConnection, error) {
+
+	scope, used, err := n.usedAddresses()
+	if err != nil {
+		return nil, err
+	}
+
+	address, ok := ipToUint32(ip)
+	if !ok {
+		return nil, fmt.Errorf("invalid IP address %s", ip)
+	}
+
+	gw, ok1 := ipToUint32(scope.Gateway)
+	mask, ok2 := ipToUint32(scope.Netmask)
+	if !ok1 || !ok2 || address&mask != gw&mask {
+		return nil, fmt.Errorf("IP address %s is outside of the subnet %s/%s", ip, scope.Gateway, scope.Netmask)
+	}
+	if address == gw {
+		return nil, fmt.Errorf("IP address %s is the gateway of org vdc network %s", ip, n.OrgVDCNetwork.Name)
+	}
+	if address&^mask == 0 || address&^mask == ^mask {
+		return nil, fmt.Errorf("IP address %s is the network or broadcast address of the subnet %s/%s", ip, scope.Gateway, scope.Netmask)
+	}
+
+	ipReservations.Lock()
+	defer ipReservations.Unlock()
+
+	reserved := ipReservations.ips[n.OrgVDCNetwork.HREF]
+	if used[address] || reserved[address] {
+		return nil, fmt.Errorf("IP address %s is already in use on org vdc network %s", ip, n.OrgVDCNetwork.Name)
+	}
+
+	if reserved == nil {
+		reserved = make(map[uint32]bool)
+		ipReservations.ips[n.OrgVDCNetwork.HREF] = reserved
+	}
+	reserved[address] = true
+
+	return &types.NetworkConnection{
+		Network:                 n.OrgVDCNetwork.Name,
+		IsConnected:             true,
+		IPAddress:               ip,
+		IPAddressAllocationMode: "MANUAL",
+	}, nil
+}
+
+// ReleaseIP releases the reservation of ip made by ReserveIP
+func (n *OrgVDCNetwork) ReleaseIP(ip string) {
+
+	address, ok := ipToUint32(ip)
+	if !ok {
+		return
+	}
+
+	ipReservations.Lock()
+	delete(ipReservations.ips[n.OrgVDCNetwork.HREF], address)
+	ipReservations.Unlock()
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_OrgVDCNetworkIPAM(t *testing.T) {
+	cc := new(callCounter)
+	responses := map[string]testResponse{
+		"/api/network/cb0f4c9e-1a46-49d4-9fcb-d228000a6bc1":                           {200, nil, orgvdcnetExample},
+		"/api/network/cb0f4c9e-1a46-49d4-9fcb-d228000a6bc1/allocatedAddresses/":       {200, nil, allocatedAddressesExample},
+		"/api/vApp/vm-11111111-1111-1111-1111-111111111111/networkConnectionSection/": {200, nil, networkConnectionSectionExample},
+	}
+
+	ctx, err := setupTestContext(authHandler(testHandler(responses, cc)))
+	if assert.NoError(t, err) {
+		net := NewOrgVDCNetwork(ctx.Client)
+		net.OrgVDCNetwork.HREF = ctx.Server.URL + "/api/network/cb0f4c9e-1a46-49d4-9fcb-d228000a6bc1"
+		if !assert.NoError(t, net.Refresh()) || !assert.Equal(t, 1, cc.Pop()) {
+			return
+		}
+
+		ips, err := net.AllocatedIPs()
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) && assert.Len(t, ips, 3) {
+			assert.Equal(t, "192.168.109.1", ips[0].IPAddress)
+			assert.Equal(t, "vsmAllocated", ips[0].AllocationType)
+			assert.Equal(t, "M916272752-5793", ips[0].Owner.Name)
+			assert.Nil(t, ips[0].NIC)
+
+			assert.Equal(t, "vmAllocated", ips[1].AllocationType)
+			assert.True(t, ips[1].Deployed)
+			assert.Equal(t, "web01", ips[1].Owner.Name)
+			if assert.NotNil(t, ips[1].NIC) {
+				assert.Equal(t, 0, ips[1].NIC.NetworkConnectionIndex)
+				assert.Equal(t, "00:50:56:01:01:10", ips[1].NIC.MACAddress)
+			}
+			if assert.NotNil(t, ips[2].NIC) {
+				assert.Equal(t, 1, ips[2].NIC.NetworkConnectionIndex)
+			}
+		}
+
+		free, err := net.FreeIPs()
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) && assert.Len(t, free, 97) {
+			assert.Equal(t, "192.168.109.4", free[0])
+			assert.Equal(t, "192.168.109.100", free[96])
+		}
+
+		assert.NoError(t, net.CheckPoolCapacity(97))
+		assert.Error(t, net.CheckPoolCapacity(98))
+		cc.Pop()
+
+		_, err = net.ReserveIP("192.168.109.2")
+		assert.Error(t, err)
+		_, err = net.ReserveIP("10.0.0.4")
+		assert.Error(t, err)
+		_, err = net.ReserveIP("192.168.109.1")
+		assert.Error(t, err)
+		_, err = net.ReserveIP("192.168.109.0")
+		assert.Error(t, err)
+		_, err = net.ReserveIP("192.168.109.255")
+		assert.Error(t, err)
+		assert.Equal(t, 10, cc.Pop())
+
+		nic, err := net.ReserveIP("192.168.109.4")
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+			assert.Equal(t, "networkName", nic.Network)
+			assert.Equal(t, "192.168.109.4", nic.IPAddress)
+			assert.Equal(t, "MANUAL", nic.IPAddressAllocationMode)
+		}
+
+		// Reserved addresses are no longer free
+		_, err = net.ReserveIP("192.168.109.4")
+		assert.Error(t, err)
+		free, err = net.FreeIPs()
+		if assert.NoError(t, err) && assert.Len(t, free, 96) {
+			assert.Equal(t, "192.168.109.5", free[0])
+		}
+
+		net.ReleaseIP("192.168.109.4")
+		free, err = net.FreeIPs()
+		if assert.NoError(t, err) && assert.Len(t, free, 97) {
+			assert.Equal(t, "192.168.109.4", free[0])
+		}
+		assert.Equal(t, 6, cc.Pop())
+	}
+}
+
+func Test_PoolExhausted(t *testing.T) {
+	cc := new(callCounter)
+	responses := map[string]testResponse{
+		"/api/network/cb0f4c9e-1a46-49d4-9fcb-d228000a6bc1":                     {200, nil, orgvdcnetExample},
+		"/api/network/cb0f4c9e-1a46-49d4-9fcb-d228000a6bc1/allocatedAddresses/": {200, nil, allocatedAddressesExample},
+	}
+
+	ctx, err := setupTestContext(authHandler(testHandler(responses, cc)))
+	if assert.NoError(t, err) {
+		net := NewOrgVDCNetwork(ctx.Client)
+		net.OrgVDCNetwork.HREF = ctx.Server.URL + "/api/network/cb0f4c9e-1a46-49d4-9fcb-d228000a6bc1"
+
+		// Reserve the whole pool
+		free, err := net.FreeIPs()
+		if assert.NoError(t, err) {
+			for _, ip := range free {
+				_, err := net.ReserveIP(ip)
+				assert.NoError(t, err)
+			}
+		}
+		cc.Pop()
+
+		// The check to run before composing a vApp on the network
+		err = net.CheckPoolCapacity(1)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "0 free pool addresses")
+			assert.Equal(t, 2, cc.Pop())
+		}
+	}
+}
+
+var allocatedAddressesExample = `
+<?xml version="1.0" encoding="UTF-8"?>
+<AllocatedIpAddresses xmlns="http://www.vmware.com/vcloud/v1.5" href="http://localhost:4444/api/network/cb0f4c9e-1a46-49d4-9fcb-d228000a6bc1/allocatedAddresses/" type="application/vnd.vmware.vcloud.allocatedNetworkAddress+xml">
+    <Link rel="up" href="http://localhost:4444/api/network/cb0f4c9e-1a46-49d4-9fcb-d228000a6bc1" type="application/vnd.vmware.vcloud.orgVdcNetwork+xml"/>
+    <IpAddress allocationType="vsmAllocated" isDeployed="true" ipAddress="192.168.109.1">
+        <Link rel="up" href="http://localhost:4444/api/admin/edgeGateway/2a1d4b6a-2ba4-4437-bcc3-e4d9b5bf1e43" name="M916272752-5793" type="application/vnd.vmware.admin.edgeGateway+xml"/>
+    </IpAddress>
+    <IpAddress allocationType="vmAllocated" isDeployed="true" ipAddress="192.168.109.2">
+        <Link rel="up" href="http://localhost:4444/api/vApp/vm-11111111-1111-1111-1111-111111111111" name="web01" type="application/vnd.vmware.vcloud.vm+xml"/>
+        <Link rel="up" href="http://localhost:4444/api/vApp/vapp-00000000-0000-0000-0000-000000000000" name="Test API GO4" type="application/vnd.vmware.vcloud.vApp+xml"/>
+    </IpAddress>
+    <IpAddress allocationType="vmAllocated" isDeployed="true" ipAddress="192.168.109.3">
+        <Link rel="up" href="http://localhost:4444/api/vApp/vm-11111111-1111-1111-1111-111111111111" name="web01" type="application/vnd.vmware.vcloud.vm+xml"/>
+        <Link rel="up" href="http://localhost:4444/api/vApp/vapp-00000000-0000-0000-0000-000000000000" name="Test API GO4" type="application/vnd.vmware.vcloud.vApp+xml"/>
+    </IpAddress>
+</AllocatedIpAddresses>
+	`
+
+var networkConnectionSectionExample = `
+<?xml version="1.0" encoding="UTF-8"?>
+<NetworkConnectionSection xmlns="http://www.vmware.com/vcloud/v1.5" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" href="http://localhost:4444/api/vApp/vm-11111111-1111-1111-1111-111111111111/networkConnectionSection/" type="application/vnd.vmware.vcloud.networkConnectionSection+xml" ovf:required="false">
+    <ovf:Info>Specifies the available VM network connections</ovf:Info>
+    <PrimaryNetworkConnectionIndex>0</PrimaryNetworkConnectionIndex>
+    <NetworkConnection needsCustomization="false" network="networkName">
+        <NetworkConnectionIndex>0</NetworkConnectionIndex>
+        <IpAddress>192.168.109.2</IpAddress>
+        <IsConnected>true</IsConnected>
+        <MACAddress>00:50:56:01:01:10</MACAddress>
+        <IpAddressAllocationMode>POOL</IpAddressAllocationMode>
+    </NetworkConnection>
+    <NetworkConnection needsCustomization="false" network="networkName">
+        <NetworkConnectionIndex>1</NetworkConnectionIndex>
+        <IpAddress>192.168.109.3</IpAddress>
+        <IsConnected>true</IsConnected>
+        <MACAddress>00:50:56:01:01:11</MACAddress>
+        <IpAddressAllocationMode>POOL</IpAddressAllocationMode>
+    </NetworkConnection>
+    <Link rel="edit" href="http://localhost:4444/api/vApp/vm-11111111-1111-1111-1111-111111111111/networkConnectionSection/" type="application/vnd.vmware.vcloud.networkConnectionSection+xml"/>
+</NetworkConnectionSection>
+	`
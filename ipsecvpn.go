@@ -0,0 +1,211 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"fmt"
+
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// The encryption protocols supported by IPsec VPN tunnels
+const (
+	IpsecVpnEncryptionAES       = "AES"
+	IpsecVpnEncryptionAES256    = "AES256"
+	IpsecVpnEncryptionTripleDES = "TRIPLEDES"
+)
+
+// ipsecVpnMinSharedSecret is the shortest shared secret the gateway accepts
+const ipsecVpnMinSharedSecret = 32
+
+// IpsecVpnTunnelStatus is the runtime status of an IPsec VPN tunnel
+type IpsecVpnTunnelStatus struct {
+	Name          string
+	IsEnabled     bool
+	IsOperational bool
+	ErrorDetails  string
+}
+
+func validateIpsecVpnTunnel(tunnel *types.GatewayIpsecVpnTunnel) error {
+
+	if tunnel == nil || tunnel.Name == "" {
+		return fmt.Errorf("IPsec VPN tunnel needs a name")
+	}
+
+	if tunnel.PeerIPAddress == "" || tunnel.LocalIPAddress == "" {
+		return fmt.Errorf("IPsec VPN tunnel %s needs a peer and a local IP address", tunnel.Name)
+	}
+
+	if len(tunnel.LocalSubnet) == 0 || len(tunnel.PeerSubnet) == 0 {
+		return fmt.Errorf("IPsec VPN tunnel %s needs at least one local and one peer subnet", tunnel.Name)
+	}
+
+	if !tunnel.SharedSecretEncrypted && len(tunnel.SharedSecret) < ipsecVpnMinSharedSecret {
+		return fmt.Errorf("IPsec VPN tunnel %s: the shared secret must be at least %d characters", tunnel.Name, ipsecVpnMinSharedSecret)
+	}
+
+	switch tunnel.EncryptionProtocol {
+	case IpsecVpnEncryptionAES, IpsecVpnEncryptionAES256, IpsecVpnEncryptionTripleDES:
+	default:
+		return fmt.Errorf("IPsec VPN tunnel %s: unsupported encryption protocol %s", tunnel.Name, tunnel.EncryptionProtocol)
+	}
+
+	if tunnel.Mtu < 0 || tunnel.Mtu > 1500 {
+		return fmt.Errorf("IPsec VPN tunnel %s: invalid MTU %d", tunnel.Name, tunnel.Mtu)
+	}
+
+	return nil
+}
+
+// configIpsecVpnService returns the IPsec VPN service of config, adding an
+// empty one when the gateway has none
+func configIpsecVpnService(config *types.GatewayFeatures) *types.GatewayIpsecVpnService {
+	if config.GatewayIpsecVpnService == nil {
+		config.GatewayIpsecVpnService = &types.GatewayIpsecVpnService{}
+	}
+	return config.GatewayIpsecVpnService
+}
+
+// IpsecVpnTunnels refreshes this gateway and returns its IPsec VPN tunnels
+func (e *EdgeGateway) IpsecVpnTunnels() ([]*types.GatewayIpsecVpnTunnel, error) {
+
+	if err := e.Refresh(); err != nil {
+		return nil, err
+	}
+
+	if e.EdgeGateway.Configuration == nil || e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration == nil {
+		return nil, fmt.Errorf("edge gateway has no service configuration")
+	}
+
+	vpn := e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration.GatewayIpsecVpnService
+	if vpn == nil {
+		return nil, nil
+	}
+
+	return vpn.Tunnel, nil
+}
+
+// IpsecVpnTunnelStatuses refreshes this gateway and reports whether each of
+// its IPsec VPN tunnels is operational, along with the error details
+// reported by the gateway
+func (e *EdgeGateway) IpsecVpnTunnelStatuses() ([]IpsecVpnTunnelStatus, error) {
+
+	tunnels, err := e.IpsecVpnTunnels()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]IpsecVpnTunnelStatus, 0, len(tunnels))
+	for _, tunnel := range tunnels {
+		statuses = append(statuses, IpsecVpnTunnelStatus{
+			Name:          tunnel.Name,
+			IsEnabled:     tunnel.IsEnabled,
+			IsOperational: tunnel.IsOperational,
+			ErrorDetails:  tunnel.ErrorDetails,
+		})
+	}
+
+	return statuses, nil
+}
+
+// FindIpsecVpnTunnel finds an IPsec VPN tunnel by name in the last known
+// configuration of this gateway
+func (e *EdgeGateway) FindIpsecVpnTunnel(name string) (*types.GatewayIpsecVpnTunnel, error) {
+
+	if e.EdgeGateway.Configuration == nil || e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration == nil {
+		return nil, fmt.Errorf("edge gateway has no service configuration")
+	}
+
+	if vpn := e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration.GatewayIpsecVpnService; vpn != nil {
+		for _, tunnel := range vpn.Tunnel {
+			if tunnel.Name == name {
+				return tunnel, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("can't find IPsec VPN tunnel: %s", name)
+}
+
+// CreateIpsecVpnTunnel adds tunnel to this gateway, enabling the IPsec VPN
+// service when needed. An MTU of 0 defaults to 1500.
+func (e *EdgeGateway) CreateIpsecVpnTunnel(tunnel *types.GatewayIpsecVpnTunnel) (Task, error) {
+
+	if err := validateIpsecVpnTunnel(tunnel); err != nil {
+		return Task{}, err
+	}
+
+	newtunnel := *tunnel
+	if newtunnel.Mtu == 0 {
+		newtunnel.Mtu = 1500
+	}
+	// Read-only status fields
+	newtunnel.IsOperational = false
+	newtunnel.ErrorDetails = ""
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		vpn := configIpsecVpnService(config)
+
+		for _, t := range vpn.Tunnel {
+			if t.Name == tunnel.Name {
+				return fmt.Errorf("IPsec VPN tunnel %s already exists", tunnel.Name)
+			}
+		}
+
+		added := newtunnel
+		vpn.IsEnabled = true
+		vpn.Tunnel = append(vpn.Tunnel, &added)
+
+		return nil
+	})
+}
+
+// UpdateIpsecVpnTunnel replaces the IPsec VPN tunnel with the same name as
+// tunnel
+func (e *EdgeGateway) UpdateIpsecVpnTunnel(tunnel *types.GatewayIpsecVpnTunnel) (Task, error) {
+
+	if err := validateIpsecVpnTunnel(tunnel); err != nil {
+		return Task{}, err
+	}
+
+	newtunnel := *tunnel
+	// Read-only status fields
+	newtunnel.IsOperational = false
+	newtunnel.ErrorDetails = ""
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		vpn := configIpsecVpnService(config)
+
+		for k, t := range vpn.Tunnel {
+			if t.Name == tunnel.Name {
+				updated := newtunnel
+				vpn.Tunnel[k] = &updated
+				return nil
+			}
+		}
+
+		return fmt.Errorf("can't find IPsec VPN tunnel: %s", tunnel.Name)
+	})
+}
+
+// DeleteIpsecVpnTunnel removes the IPsec VPN tunnel with the given name
+func (e *EdgeGateway) DeleteIpsecVpnTunnel(name string) (Task, error) {
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		vpn := configIpsecVpnService(config)
+
+		for k, t := range vpn.Tunnel {
+			if t.Name == name {
+				vpn.Tunnel = append(vpn.Tunnel[:k], vpn.Tunnel[k+1:]...)
+				return nil
+			}
+		}
+
+		return fmt.Errorf("can't find IPsec VPN tunnel: %s", name)
+	})
+}
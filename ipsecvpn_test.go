@@ -0,0 +1,128 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+func Test_IpsecVpnTunnels(t *testing.T) {
+	cc := new(callCounter)
+	config := new(types.GatewayFeatures)
+
+	ctx, err := setupTestContext(authHandler(edgeServicesHandler(vpnEdgegatewayExample, config, cc)))
+	if assert.NoError(t, err) {
+
+		edge, err := ctx.VDC.FindEdgeGateway("M916272752-5793")
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+
+			tunnels, err := edge.IpsecVpnTunnels()
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) && assert.Len(t, tunnels, 2) {
+				assert.Equal(t, "Test VPN Alpha", tunnels[0].Name)
+				if assert.NotNil(t, tunnels[0].IpsecVpnThirdPartyPeer) {
+					assert.Equal(t, "192.168.110.99", tunnels[0].IpsecVpnThirdPartyPeer.PeerID)
+				}
+				assert.Len(t, tunnels[0].LocalSubnet, 1)
+				assert.Len(t, tunnels[1].PeerSubnet, 2)
+			}
+
+			statuses, err := edge.IpsecVpnTunnelStatuses()
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) && assert.Len(t, statuses, 2) {
+				assert.True(t, statuses[0].IsOperational)
+				assert.Equal(t, "", statuses[0].ErrorDetails)
+				assert.False(t, statuses[1].IsOperational)
+				assert.Equal(t, "Phase 1 negotiation failed", statuses[1].ErrorDetails)
+			}
+
+			tunnel := &types.GatewayIpsecVpnTunnel{
+				Name:                   "dc-link",
+				IpsecVpnThirdPartyPeer: &types.IpsecVpnThirdPartyPeer{PeerID: "198.51.100.10"},
+				PeerIPAddress:          "198.51.100.10",
+				PeerID:                 "198.51.100.10",
+				LocalIPAddress:         "23.92.225.51",
+				LocalID:                "23.92.225.51",
+				LocalSubnet: []*types.IpsecVpnSubnet{
+					{Name: "M916272752-5793-default-routed", Gateway: "192.168.109.1", Netmask: "255.255.255.0"},
+					{Name: "JGray Network", Gateway: "192.168.108.1", Netmask: "255.255.255.0"},
+				},
+				PeerSubnet: []*types.IpsecVpnSubnet{
+					{Name: "10.10.0.0/16", Gateway: "10.10.0.0", Netmask: "255.255.0.0"},
+				},
+				SharedSecret:       strings.Repeat("s3cr3t", 6),
+				EncryptionProtocol: IpsecVpnEncryptionAES256,
+				IsEnabled:          true,
+				IsOperational:      true,
+			}
+
+			_, err = edge.CreateIpsecVpnTunnel(tunnel)
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) && assert.Len(t, config.GatewayIpsecVpnService.Tunnel, 3) {
+				created := config.GatewayIpsecVpnService.Tunnel[2]
+				assert.Len(t, created.LocalSubnet, 2)
+				assert.Equal(t, 1500, created.Mtu)
+				assert.False(t, created.IsOperational)
+			}
+
+			tunnel.Name = "Test VPN Alpha"
+			_, err = edge.CreateIpsecVpnTunnel(tunnel)
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+
+			tunnel.SharedSecret = "short"
+			_, err = edge.UpdateIpsecVpnTunnel(tunnel)
+			assert.Error(t, err)
+
+			tunnel.SharedSecret = strings.Repeat("s3cr3t", 6)
+			tunnel.EncryptionProtocol = "DES"
+			_, err = edge.UpdateIpsecVpnTunnel(tunnel)
+			assert.Error(t, err)
+			assert.Equal(t, 0, cc.Pop())
+
+			tunnel.EncryptionProtocol = IpsecVpnEncryptionTripleDES
+			tunnel.Mtu = 1400
+			tunnel.ErrorDetails = "Phase 1 negotiation failed"
+			_, err = edge.UpdateIpsecVpnTunnel(tunnel)
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) && assert.Len(t, config.GatewayIpsecVpnService.Tunnel, 2) {
+				updated := config.GatewayIpsecVpnService.Tunnel[0]
+				assert.Equal(t, "TRIPLEDES", updated.EncryptionProtocol)
+				assert.Equal(t, 1400, updated.Mtu)
+				assert.False(t, updated.IsOperational)
+				assert.Equal(t, "", updated.ErrorDetails)
+				assert.True(t, tunnel.IsOperational)
+			}
+
+			_, err = edge.DeleteIpsecVpnTunnel("JGray VPN")
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) && assert.Len(t, config.GatewayIpsecVpnService.Tunnel, 1) {
+				assert.Equal(t, "Test VPN Alpha", config.GatewayIpsecVpnService.Tunnel[0].Name)
+			}
+
+			_, err = edge.DeleteIpsecVpnTunnel("missing")
+			assert.Error(t, err)
+		}
+	}
+}
+
+// vpnEdgegatewayExample is edgegatewayExample with an operational first
+// tunnel and a failing second tunnel with two peer subnets
+var vpnEdgegatewayExample = strings.NewReplacer(
+	`<IsEnabled>true</IsEnabled>
+                    <IsOperational>false</IsOperational>`, `<IsEnabled>true</IsEnabled>
+                    <IsOperational>true</IsOperational>`,
+	`<PeerSubnet>
+                        <Name>192.168.1.0/24</Name>`, `<PeerSubnet>
+                        <Name>192.168.2.0/24</Name>
+                        <Gateway>192.168.2.0</Gateway>
+                        <Netmask>255.255.255.0</Netmask>
+                    </PeerSubnet>
+                    <PeerSubnet>
+                        <Name>192.168.1.0/24</Name>`,
+	`<IsEnabled>false</IsEnabled>
+                    <IsOperational>false</IsOperational>`, `<IsEnabled>false</IsEnabled>
+                    <IsOperational>false</IsOperational>
+                    <ErrorDetails>Phase 1 negotiation failed</ErrorDetails>`,
+).Replace(edgegatewayExample)
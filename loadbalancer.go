@@ -0,0 +1,402 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"fmt"
+	"strconv"
+
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// The load balancer algorithms, protocols, health check modes and
+// persistence methods understood by the edge gateway
+const (
+	LBAlgorithmIPHash     = "IP_HASH"
+	LBAlgorithmRoundRobin = "ROUND_ROBIN"
+	LBAlgorithmURI        = "URI"
+	LBAlgorithmLeastConn  = "LEAST_CONN"
+
+	LBProtocolHTTP  = "HTTP"
+	LBProtocolHTTPS = "HTTPS"
+	LBProtocolTCP   = "TCP"
+
+	LBHealthCheckTCP  = "TCP"
+	LBHealthCheckHTTP = "HTTP"
+	LBHealthCheckSSL  = "SSL"
+
+	LBPersistenceCookie       = "COOKIE"
+	LBPersistenceSSLSessionID = "SSL_SESSION_ID"
+)
+
+func validateLoadBalancerPool(pool *types.LoadBalancerPool) error {
+
+	if pool == nil || pool.Name == "" {
+		return fmt.Errorf("load balancer pool needs a name")
+	}
+
+	if len(pool.ServicePort) == 0 {
+		return fmt.Errorf("load balancer pool %s has no service port", pool.Name)
+	}
+
+	for _, sp := range pool.ServicePort {
+		switch sp.Protocol {
+		case LBProtocolHTTP, LBProtocolHTTPS, LBProtocolTCP:
+		default:
+			return fmt.Errorf("load balancer pool %s: unsupported protocol %s", pool.Name, sp.Protocol)
+		}
+
+		switch sp.Algorithm {
+		case LBAlgorithmIPHash, LBAlgorithmRoundRobin, LBAlgorithmURI, LBAlgorithmLeastConn:
+		default:
+			return fmt.Errorf("load balancer pool %s: unsupported algorithm %s", pool.Name, sp.Algorithm)
+		}
+
+		if sp.HealthCheck != nil {
+			switch sp.HealthCheck.Mode {
+			case LBHealthCheckTCP, LBHealthCheckHTTP, LBHealthCheckSSL:
+			default:
+				return fmt.Errorf("load balancer pool %s: unsupported health check mode %s", pool.Name, sp.HealthCheck.Mode)
+			}
+		}
+	}
+
+	seen := make(map[string]bool, len(pool.Member))
+	for _, member := range pool.Member {
+		if member.IPAddress == "" {
+			return fmt.Errorf("load balancer pool %s has a member without IP address", pool.Name)
+		}
+		if seen[member.IPAddress] {
+			return fmt.Errorf("load balancer pool %s lists member %s more than once", pool.Name, member.IPAddress)
+		}
+		seen[member.IPAddress] = true
+	}
+
+	return nil
+}
+
+func validateLoadBalancerVirtualServer(vs *types.LoadBalancerVirtualServer) error {
+
+	if vs == nil || vs.Name == "" {
+		return fmt.Errorf("load balancer virtual server needs a name")
+	}
+
+	if vs.Interface == nil || vs.Interface.HREF == "" {
+		return fmt.Errorf("load balancer virtual server %s has no gateway interface", vs.Name)
+	}
+
+	if vs.IPAddress == "" || vs.Pool == "" {
+		return fmt.Errorf("load balancer virtual server %s needs an IP address and a pool", vs.Name)
+	}
+
+	for _, sp := range vs.ServiceProfile {
+		switch sp.Protocol {
+		case LBProtocolHTTP, LBProtocolHTTPS, LBProtocolTCP:
+		default:
+			return fmt.Errorf("load balancer virtual server %s: unsupported protocol %s", vs.Name, sp.Protocol)
+		}
+
+		if sp.Persistence != nil {
+			switch sp.Persistence.Method {
+			case LBPersistenceCookie, LBPersistenceSSLSessionID:
+			default:
+				return fmt.Errorf("load balancer virtual server %s: unsupported persistence method %s", vs.Name, sp.Persistence.Method)
+			}
+		}
+	}
+
+	return nil
+}
+
+// configLoadBalancerService returns the load balancer service of config,
+// adding an empty one when the gateway has none
+func configLoadBalancerService(config *types.GatewayFeatures) *types.LoadBalancerService {
+	if config.LoadBalancerService == nil {
+		config.LoadBalancerService = &types.LoadBalancerService{}
+	}
+	return config.LoadBalancerService
+}
+
+// hasLoadBalancerPool reports whether lb has a pool named name
+func hasLoadBalancerPool(lb *types.LoadBalancerService, name string) bool {
+	for _, pool := range lb.Pool {
+		if pool.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadBalancerPools refreshes this gateway and returns its load balancer pools
+func (e *EdgeGateway) LoadBalancerPools() ([]*types.LoadBalancerPool, error) {
+
+	if err := e.Refresh(); err != nil {
+		return nil, err
+	}
+
+	if e.EdgeGateway.Configuration == nil || e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration == nil {
+		return nil, fmt.Errorf("edge gateway has no service configuration")
+	}
+
+	lb := e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration.LoadBalancerService
+	if lb == nil {
+		return nil, nil
+	}
+
+	return lb.Pool, nil
+}
+
+// FindLoadBalancerPool finds a load balancer pool by name in the last known
+// configuration of this gateway
+func (e *EdgeGateway) FindLoadBalancerPool(name string) (*types.LoadBalancerPool, error) {
+
+	if e.EdgeGateway.Configuration == nil || e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration == nil {
+		return nil, fmt.Errorf("edge gateway has no service configuration")
+	}
+
+	if lb := e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration.LoadBalancerService; lb != nil {
+		for _, pool := range lb.Pool {
+			if pool.Name == name {
+				return pool, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("can't find load balancer pool: %s", name)
+}
+
+// CreateLoadBalancerPool adds pool to this gateway, enabling the load
+// balancer service when needed
+func (e *EdgeGateway) CreateLoadBalancerPool(pool *types.LoadBalancerPool) (Task, error) {
+
+	if err := validateLoadBalancerPool(pool); err != nil {
+		return Task{}, err
+	}
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		lb := configLoadBalancerService(config)
+
+		if hasLoadBalancerPool(lb, pool.Name) {
+			return fmt.Errorf("load balancer pool %s already exists", pool.Name)
+		}
+
+		lb.IsEnabled = true
+		lb.Pool = append(lb.Pool, pool)
+
+		return nil
+	})
+}
+
+// UpdateLoadBalancerPool replaces the load balancer pool with the same name
+// as pool
+func (e *EdgeGateway) UpdateLoadBalancerPool(pool *types.LoadBalancerPool) (Task, error) {
+
+	if err := validateLoadBalancerPool(pool); err != nil {
+		return Task{}, err
+	}
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		lb := configLoadBalancerService(config)
+
+		for k, p := range lb.Pool {
+			if p.Name == pool.Name {
+				lb.Pool[k] = pool
+				return nil
+			}
+		}
+
+		return fmt.Errorf("can't find load balancer pool: %s", pool.Name)
+	})
+}
+
+// DeleteLoadBalancerPool removes the load balancer pool with the given name,
+// it fails while a virtual server still uses the pool
+func (e *EdgeGateway) DeleteLoadBalancerPool(name string) (Task, error) {
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		lb := configLoadBalancerService(config)
+
+		for _, vs := range lb.VirtualServer {
+			if vs.Pool == name {
+				return fmt.Errorf("load balancer pool %s is used by virtual server %s", name, vs.Name)
+			}
+		}
+
+		for k, p := range lb.Pool {
+			if p.Name == name {
+				lb.Pool = append(lb.Pool[:k], lb.Pool[k+1:]...)
+				return nil
+			}
+		}
+
+		return fmt.Errorf("can't find load balancer pool: %s", name)
+	})
+}
+
+// SetLoadBalancerPoolMemberWeight changes the weight of the member with the
+// given IP address in a load balancer pool
+func (e *EdgeGateway) SetLoadBalancerPoolMemberWeight(poolName, ipAddress string, weight int) (Task, error) {
+
+	if weight < 0 {
+		return Task{}, fmt.Errorf("invalid load balancer member weight: %d", weight)
+	}
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		lb := configLoadBalancerService(config)
+
+		for _, pool := range lb.Pool {
+			if pool.Name != poolName {
+				continue
+			}
+
+			for _, member := range pool.Member {
+				if member.IPAddress == ipAddress {
+					member.Weight = strconv.Itoa(weight)
+					return nil
+				}
+			}
+
+			return fmt.Errorf("can't find member %s in load balancer pool %s", ipAddress, poolName)
+		}
+
+		return fmt.Errorf("can't find load balancer pool: %s", poolName)
+	})
+}
+
+// DisableLoadBalancerPoolMember stops sending new traffic to a member of a
+// load balancer pool. vCloud has no enabled flag for pool members, a
+// disabled member has a weight of 0.
+func (e *EdgeGateway) DisableLoadBalancerPoolMember(poolName, ipAddress string) (Task, error) {
+	return e.SetLoadBalancerPoolMemberWeight(poolName, ipAddress, 0)
+}
+
+// EnableLoadBalancerPoolMember puts a disabled member of a load balancer
+// pool back in rotation with the given weight
+func (e *EdgeGateway) EnableLoadBalancerPoolMember(poolName, ipAddress string, weight int) (Task, error) {
+
+	if weight < 1 {
+		return Task{}, fmt.Errorf("an enabled load balancer member needs a positive weight, got %d", weight)
+	}
+
+	return e.SetLoadBalancerPoolMemberWeight(poolName, ipAddress, weight)
+}
+
+// LoadBalancerVirtualServers refreshes this gateway and returns its load
+// balancer virtual servers
+func (e *EdgeGateway) LoadBalancerVirtualServers() ([]*types.LoadBalancerVirtualServer, error) {
+
+	if err := e.Refresh(); err != nil {
+		return nil, err
+	}
+
+	if e.EdgeGateway.Configuration == nil || e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration == nil {
+		return nil, fmt.Errorf("edge gateway has no service configuration")
+	}
+
+	lb := e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration.LoadBalancerService
+	if lb == nil {
+		return nil, nil
+	}
+
+	return lb.VirtualServer, nil
+}
+
+// FindLoadBalancerVirtualServer finds a load balancer virtual server by name
+// in the last known configuration of this gateway
+func (e *EdgeGateway) FindLoadBalancerVirtualServer(name string) (*types.LoadBalancerVirtualServer, error) {
+
+	if e.EdgeGateway.Configuration == nil || e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration == nil {
+		return nil, fmt.Errorf("edge gateway has no service configuration")
+	}
+
+	if lb := e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration.LoadBalancerService; lb != nil {
+		for _, vs := range lb.VirtualServer {
+			if vs.Name == name {
+				return vs, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("can't find load balancer virtual server: %s", name)
+}
+
+// CreateLoadBalancerVirtualServer adds vs to this gateway, the pool it
+// refers to must exist
+func (e *EdgeGateway) CreateLoadBalancerVirtualServer(vs *types.LoadBalancerVirtualServer) (Task, error) {
+
+	if err := validateLoadBalancerVirtualServer(vs); err != nil {
+		return Task{}, err
+	}
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		lb := configLoadBalancerService(config)
+
+		for _, v := range lb.VirtualServer {
+			if v.Name == vs.Name {
+				return fmt.Errorf("load balancer virtual server %s already exists", vs.Name)
+			}
+		}
+
+		if !hasLoadBalancerPool(lb, vs.Pool) {
+			return fmt.Errorf("can't find load balancer pool: %s", vs.Pool)
+		}
+
+		lb.IsEnabled = true
+		lb.VirtualServer = append(lb.VirtualServer, vs)
+
+		return nil
+	})
+}
+
+// UpdateLoadBalancerVirtualServer replaces the load balancer virtual server
+// with the same name as vs
+func (e *EdgeGateway) UpdateLoadBalancerVirtualServer(vs *types.LoadBalancerVirtualServer) (Task, error) {
+
+	if err := validateLoadBalancerVirtualServer(vs); err != nil {
+		return Task{}, err
+	}
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		lb := configLoadBalancerService(config)
+
+		if !hasLoadBalancerPool(lb, vs.Pool) {
+			return fmt.Errorf("can't find load balancer pool: %s", vs.Pool)
+		}
+
+		for k, v := range lb.VirtualServer {
+			if v.Name == vs.Name {
+				lb.VirtualServer[k] = vs
+				return nil
+			}
+		}
+
+		return fmt.Errorf("can't find load balancer virtual server: %s", vs.Name)
+	})
+}
+
+// DeleteLoadBalancerVirtualServer removes the load balancer virtual server
+// with the given name
+func (e *EdgeGateway) DeleteLoadBalancerVirtualServer(name string) (Task, error) {
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		lb := configLoadBalancerService(config)
+
+		for k, v := range lb.VirtualServer {
+			if v.Name == name {
+				lb.VirtualServer = append(lb.VirtualServer[:k], lb.VirtualServer[k+1:]...)
+				return nil
+			}
+		}
+
+		return fmt.Errorf("can't find load balancer virtual server: %s", name)
+	})
+}
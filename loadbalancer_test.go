@@ -0,0 +1,245 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+func Test_LoadBalancerPools(t *testing.T) {
+	cc := new(callCounter)
+	config := new(types.GatewayFeatures)
+
+	ctx, err := setupTestContext(authHandler(edgeServicesHandler(loadbalancedEdgegatewayExample, config, cc)))
+	if assert.NoError(t, err) {
+
+		edge, err := ctx.VDC.FindEdgeGateway("M916272752-5793")
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+
+			pools, err := edge.LoadBalancerPools()
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) && assert.Len(t, pools, 1) {
+				assert.Equal(t, "web", pools[0].Name)
+				assert.Len(t, pools[0].Member, 2)
+				assert.Len(t, pools[0].ServicePort, 2)
+			}
+
+			pool := &types.LoadBalancerPool{
+				Name: "api",
+				ServicePort: []*types.LBPoolServicePort{
+					{
+						IsEnabled: true,
+						Protocol:  LBProtocolHTTP,
+						Algorithm: LBAlgorithmLeastConn,
+						Port:      "8080",
+						HealthCheck: &types.LBPoolHealthCheck{
+							Mode:     LBHealthCheckHTTP,
+							URI:      "/health",
+							Interval: "5",
+							Timeout:  "15",
+						},
+					},
+				},
+				Member: []*types.LBPoolMember{
+					{IPAddress: "192.168.109.20", Weight: "1"},
+					{IPAddress: "192.168.109.21", Weight: "1"},
+					{IPAddress: "192.168.109.22", Weight: "1"},
+				},
+			}
+
+			_, err = edge.CreateLoadBalancerPool(pool)
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				assert.True(t, config.LoadBalancerService.IsEnabled)
+				if assert.Len(t, config.LoadBalancerService.Pool, 2) {
+					created := config.LoadBalancerService.Pool[1]
+					assert.Len(t, created.Member, 3)
+					assert.Equal(t, "/health", created.ServicePort[0].HealthCheck.URI)
+				}
+				assert.Len(t, config.LoadBalancerService.VirtualServer, 1)
+			}
+
+			_, err = edge.CreateLoadBalancerPool(&types.LoadBalancerPool{Name: "web", ServicePort: pool.ServicePort})
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+
+			pool.ServicePort[0].Algorithm = "RANDOM"
+			_, err = edge.CreateLoadBalancerPool(pool)
+			assert.Error(t, err)
+			assert.Equal(t, 0, cc.Pop())
+
+			pool.Name = "web"
+			pool.ServicePort[0].Algorithm = LBAlgorithmRoundRobin
+			_, err = edge.UpdateLoadBalancerPool(pool)
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) && assert.Len(t, config.LoadBalancerService.Pool, 1) {
+				assert.Len(t, config.LoadBalancerService.Pool[0].Member, 3)
+			}
+
+			// the virtual server still uses the pool
+			_, err = edge.DeleteLoadBalancerPool("web")
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+		}
+	}
+}
+
+func Test_LoadBalancerPoolMembers(t *testing.T) {
+	cc := new(callCounter)
+	config := new(types.GatewayFeatures)
+
+	ctx, err := setupTestContext(authHandler(edgeServicesHandler(loadbalancedEdgegatewayExample, config, cc)))
+	if assert.NoError(t, err) {
+
+		edge, err := ctx.VDC.FindEdgeGateway("M916272752-5793")
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+
+			_, err = edge.DisableLoadBalancerPoolMember("web", "192.168.109.11")
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				members := config.LoadBalancerService.Pool[0].Member
+				assert.Equal(t, "1", members[0].Weight)
+				assert.Equal(t, "0", members[1].Weight)
+				// the gateway was refreshed with the applied configuration
+				assert.Equal(t, "0", edge.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration.LoadBalancerService.Pool[0].Member[1].Weight)
+			}
+
+			_, err = edge.EnableLoadBalancerPoolMember("web", "192.168.109.11", 2)
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				assert.Equal(t, "2", config.LoadBalancerService.Pool[0].Member[1].Weight)
+			}
+
+			_, err = edge.EnableLoadBalancerPoolMember("web", "192.168.109.11", 0)
+			assert.Error(t, err)
+
+			_, err = edge.DisableLoadBalancerPoolMember("web", "192.168.109.99")
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+		}
+	}
+}
+
+func Test_LoadBalancerVirtualServers(t *testing.T) {
+	cc := new(callCounter)
+	config := new(types.GatewayFeatures)
+
+	ctx, err := setupTestContext(authHandler(edgeServicesHandler(loadbalancedEdgegatewayExample, config, cc)))
+	if assert.NoError(t, err) {
+
+		edge, err := ctx.VDC.FindEdgeGateway("M916272752-5793")
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+
+			servers, err := edge.LoadBalancerVirtualServers()
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) && assert.Len(t, servers, 1) {
+				assert.Equal(t, "web-vs", servers[0].Name)
+				assert.Equal(t, "COOKIE", servers[0].ServiceProfile[0].Persistence.Method)
+			}
+
+			vs := &types.LoadBalancerVirtualServer{
+				IsEnabled: true,
+				Name:      "web-tls",
+				Interface: &types.Reference{HREF: ctx.Server.URL + "/api/admin/network/6254f107-9876-4d03-986f-8bec7a4bcb3f"},
+				IPAddress: "23.92.225.73",
+				ServiceProfile: []*types.LBVirtualServerServiceProfile{
+					{
+						IsEnabled:   true,
+						Protocol:    LBProtocolHTTPS,
+						Port:        "443",
+						Persistence: &types.LBPersistence{Method: LBPersistenceSSLSessionID},
+					},
+				},
+				Pool: "web",
+			}
+
+			_, err = edge.CreateLoadBalancerVirtualServer(vs)
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) && assert.Len(t, config.LoadBalancerService.VirtualServer, 2) {
+				created := config.LoadBalancerService.VirtualServer[1]
+				assert.Equal(t, vs.Interface.HREF, created.Interface.HREF)
+				assert.Equal(t, "SSL_SESSION_ID", created.ServiceProfile[0].Persistence.Method)
+			}
+
+			vs.Pool = "missing"
+			_, err = edge.CreateLoadBalancerVirtualServer(vs)
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+
+			vs.Pool = "web"
+			vs.Interface = nil
+			_, err = edge.CreateLoadBalancerVirtualServer(vs)
+			assert.Error(t, err)
+			assert.Equal(t, 0, cc.Pop())
+
+			vs.Name = "web-vs"
+			vs.Interface = &types.Reference{HREF: ctx.Server.URL + "/api/admin/network/6254f107-9876-4d03-986f-8bec7a4bcb3f"}
+			_, err = edge.UpdateLoadBalancerVirtualServer(vs)
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) && assert.Len(t, config.LoadBalancerService.VirtualServer, 1) {
+				assert.Equal(t, "23.92.225.73", config.LoadBalancerService.VirtualServer[0].IPAddress)
+			}
+
+			_, err = edge.DeleteLoadBalancerVirtualServer("web-vs")
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				assert.Len(t, config.LoadBalancerService.VirtualServer, 0)
+				assert.Len(t, config.LoadBalancerService.Pool, 1)
+			}
+		}
+	}
+}
+
+// loadbalancedEdgegatewayExample is edgegatewayExample with a load balanced web pool
+var loadbalancedEdgegatewayExample = strings.Replace(edgegatewayExample, `<LoadBalancerService>
+                <IsEnabled>false</IsEnabled>`, `<LoadBalancerService>
+                <IsEnabled>true</IsEnabled>
+                <Pool>
+                    <Id>1</Id>
+                    <Name>web</Name>
+                    <ServicePort>
+                        <IsEnabled>true</IsEnabled>
+                        <Protocol>HTTP</Protocol>
+                        <Algorithm>ROUND_ROBIN</Algorithm>
+                        <Port>80</Port>
+                        <HealthCheckPort>80</HealthCheckPort>
+                        <HealthCheck>
+                            <Mode>HTTP</Mode>
+                            <Uri>/</Uri>
+                            <HealthThreshold>2</HealthThreshold>
+                            <UnhealthThreshold>3</UnhealthThreshold>
+                            <Interval>5</Interval>
+                            <Timeout>15</Timeout>
+                        </HealthCheck>
+                    </ServicePort>
+                    <ServicePort>
+                        <IsEnabled>false</IsEnabled>
+                        <Protocol>HTTPS</Protocol>
+                        <Algorithm>ROUND_ROBIN</Algorithm>
+                        <Port>443</Port>
+                    </ServicePort>
+                    <Member>
+                        <IpAddress>192.168.109.10</IpAddress>
+                        <Weight>1</Weight>
+                    </Member>
+                    <Member>
+                        <IpAddress>192.168.109.11</IpAddress>
+                        <Weight>1</Weight>
+                    </Member>
+                    <Operational>true</Operational>
+                </Pool>
+                <VirtualServer>
+                    <IsEnabled>true</IsEnabled>
+                    <Name>web-vs</Name>
+                    <Interface href="http://localhost:4444/api/admin/network/6254f107-9876-4d03-986f-8bec7a4bcb3f" name="d2p3-ext" type="application/vnd.vmware.admin.network+xml"/>
+                    <IpAddress>23.92.225.51</IpAddress>
+                    <ServiceProfile>
+                        <IsEnabled>true</IsEnabled>
+                        <Protocol>HTTP</Protocol>
+                        <Port>80</Port>
+                        <Persistence>
+                            <Method>COOKIE</Method>
+                            <CookieName>JSESSIONID</CookieName>
+                            <CookieMode>INSERT</CookieMode>
+                        </Persistence>
+                    </ServiceProfile>
+                    <Logging>false</Logging>
+                    <Pool>web</Pool>
+                </VirtualServer>`, 1)
@@ -0,0 +1,294 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// Media a media client, for ISO and floppy images
+type Media struct {
+	Media *types.Media
+	c     Client
+}
+
+// NewMedia creates a new media client
+func NewMedia(c Client) *Media {
+	return &Media{
+		Media: new(types.Media),
+		c:     c,
+	}
+}
+
+// Refresh retrieves this media again
+func (m *Media) Refresh() error {
+
+	if m.Media.HREF == "" {
+		return fmt.Errorf("cannot refresh, Object is empty")
+	}
+
+	u, _ := url.ParseRequestURI(m.Media.HREF)
+
+	req := m.c.NewRequest(map[string]string{}, types.HTTPGet, u, nil)
+
+	resp, err := checkResp(m.c.DoHTTP(req))
+	if err != nil {
+		return fmt.Errorf("error retrieving media: %s", err)
+	}
+
+	// Empty struct before a new unmarshal, otherwise we end up with duplicate
+	// elements in slices.
+	m.Media = &types.Media{}
+
+	if err = decodeBody(resp, m.Media); err != nil {
+		return fmt.Errorf("error decoding media response: %s", err)
+	}
+
+	// The request was successful
+	return nil
+}
+
+// Delete deletes this media, along with the catalog item referring to it
+func (m *Media) Delete() (Task, error) {
+
+	s, err := url.ParseRequestURI(m.Media.HREF)
+	if err != nil {
+		return Task{}, fmt.Errorf("error parsing media href: %s", err)
+	}
+
+	task, err := executeTaskRequest(m.c, types.HTTPDelete, s, "", nil)
+	if err != nil {
+		return Task{}, fmt.Errorf("error deleting media: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
+
+// reference returns a reference to this media
+func (m *Media) reference() *types.Reference {
+	return &types.Reference{
+		HREF: m.Media.HREF,
+		Name: m.Media.Name,
+		Type: types.MimeMedia,
+	}
+}
+
+// upload sends the bytes of the image at isoPath vCloud doesn't have yet,
+// and waits for vCloud to resolve this media, at most opts.Timeout
+func (m *Media) upload(isoPath string, opts UploadOptions) error {
+
+	f, err := os.Open(isoPath)
+	if err != nil {
+		return fmt.Errorf("error opening media image: %s", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("error opening media image: %s", err)
+	}
+	src := io.NewSectionReader(f, 0, info.Size())
+
+	if err := m.Refresh(); err != nil {
+		return err
+	}
+
+	if m.Media.Files == nil || len(m.Media.Files.File) == 0 {
+		return fmt.Errorf("media %s doesn't accept an upload", m.Media.Name)
+	}
+	file := m.Media.Files.File[0]
+
+	progress := UploadProgress{TotalSize: info.Size(), TotalBytes: file.BytesTransferred}
+	if err := uploadChunks(m.c, file, src, &progress, opts); err != nil {
+		return err
+	}
+
+	return waitProcessed("media "+m.Media.Name, opts.Timeout, func() (*types.TasksInProgress, int, error) {
+		err := m.Refresh()
+		return m.Media.Tasks, m.Media.Status, err
+	}, func() bool { return types.VAppStatus(m.Media.Status) == types.VAppStatusResolved })
+}
+
+// ResumeUpload finishes an interrupted upload of the image at isoPath to
+// this media. Only the bytes vCloud doesn't have yet are sent.
+func (m *Media) ResumeUpload(isoPath string, opts UploadOptions) error {
+	return m.upload(isoPath, opts)
+}
+
+// UploadMediaImage uploads the ISO image at isoPath to the storage of vdc as
+// a media named name, adds it to this catalog and waits for vCloud to
+// resolve it. The media is returned along with any error that occurs once
+// it is created, so that the upload can be resumed with Media.ResumeUpload.
+func (c *Catalog) UploadMediaImage(vdc Vdc, isoPath, name, description string, opts UploadOptions) (Media, error) {
+
+	if name == "" {
+		return Media{}, fmt.Errorf("media needs a name")
+	}
+
+	info, err := os.Stat(isoPath)
+	if err != nil {
+		return Media{}, fmt.Errorf("error opening media image: %s", err)
+	}
+
+	var s *url.URL
+	if l := vdc.Vdc.Link.ForType(types.MimeMedia, types.RelAdd); l != nil {
+		s, err = url.ParseRequestURI(l.HREF)
+	} else if s, err = url.ParseRequestURI(vdc.Vdc.HREF); err == nil {
+		s.Path += "/media"
+	}
+	if err != nil {
+		return Media{}, fmt.Errorf("error parsing vdc href: %s", err)
+	}
+
+	params := &types.Media{
+		Xmlns:       types.NsVCloud,
+		Name:        name,
+		Description: description,
+		ImageType:   "iso",
+		Size:        info.Size(),
+	}
+
+	media := NewMedia(c.c)
+
+	if err = executeRequest(c.c, types.HTTPPost, s, types.MimeMedia, params, media.Media); err != nil {
+		return Media{}, fmt.Errorf("error creating media: %s", err)
+	}
+
+	if _, err = c.addCatalogItem(media.Media.Name, description, media.reference()); err != nil {
+		return *media, err
+	}
+
+	return *media, media.upload(isoPath, opts)
+}
+
+// addCatalogItem adds a catalog item named name referring to entity to this
+// catalog
+func (c *Catalog) addCatalogItem(name, description string, entity *types.Reference) (CatalogItem, error) {
+
+	var s *url.URL
+	var err error
+	if l := c.Catalog.Link.ForType(types.MimeCatalogItem, types.RelAdd); l != nil {
+		s, err = url.ParseRequestURI(l.HREF)
+	} else if s, err = url.ParseRequestURI(c.Catalog.HREF); err == nil {
+		s.Path += "/catalogItems"
+	}
+	if err != nil {
+		return CatalogItem{}, fmt.Errorf("error parsing catalog href: %s", err)
+	}
+
+	params := &types.CatalogItem{
+		Xmlns:       types.NsVCloud,
+		Name:        name,
+		Description: description,
+		Entity:      &types.Entity{HREF: entity.HREF, Name: entity.Name, Type: entity.Type},
+	}
+
+	item := NewCatalogItem(c.c)
+
+	if err = executeRequest(c.c, types.HTTPPost, s, types.MimeCatalogItem, params, item.CatalogItem); err != nil {
+		return CatalogItem{}, fmt.Errorf("error adding catalog item: %s", err)
+	}
+
+	// The request was successful
+	return *item, nil
+}
+
+// ListMedia refreshes this vdc and returns references to its media
+func (v *Vdc) ListMedia() ([]*types.ResourceReference, error) {
+
+	if err := v.Refresh(); err != nil {
+		return nil, err
+	}
+
+	var media []*types.ResourceReference
+	for _, entities := range v.Vdc.ResourceEntities {
+		for _, entity := range entities.ResourceEntity {
+			if entity.Type == types.MimeMedia {
+				media = append(media, entity)
+			}
+		}
+	}
+
+	return media, nil
+}
+
+// FindMedia retrieves the media of this vdc named name
+func (v *Vdc) FindMedia(name string) (Media, error) {
+
+	refs, err := v.ListMedia()
+	if err != nil {
+		return Media{}, err
+	}
+
+	for _, ref := range refs {
+		if ref.Name != name {
+			continue
+		}
+
+		media := NewMedia(v.c)
+		media.Media.HREF = ref.HREF
+		if err := media.Refresh(); err != nil {
+			return Media{}, err
+		}
+
+		// The request was successful
+		return *media, nil
+	}
+
+	return Media{}, fmt.Errorf("can't find media: %s", name)
+}
+
+// mediaAction inserts media into or ejects it from the CD-ROM or floppy
+// drive of this VM
+func (v *VM) mediaAction(rel, action string, media Media) (Task, error) {
+
+	var s *url.URL
+	var err error
+	if l := v.VM.Link.Find(func(l *types.Link) bool { return l.Rel == rel }); l != nil {
+		s, err = url.ParseRequestURI(l.HREF)
+	} else if s, err = url.ParseRequestURI(v.VM.HREF); err == nil {
+		s.Path += "/media/action/" + action
+	}
+	if err != nil {
+		return Task{}, fmt.Errorf("error parsing VM href: %s", err)
+	}
+
+	params := &types.MediaInsertOrEjectParams{
+		Xmlns: types.NsVCloud,
+		Media: media.reference(),
+	}
+
+	return executeTaskRequest(v.c, types.HTTPPost, s, types.MimeMediaInsertOrEjectParams, params)
+}
+
+// InsertMedia inserts media into the CD-ROM drive of this VM
+func (v *VM) InsertMedia(media Media) (Task, error) {
+
+	task, err := v.mediaAction(types.RelMediaInsertMedia, "insertMedia", media)
+	if err != nil {
+		return Task{}, fmt.Errorf("error inserting media: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
+
+// EjectMedia ejects media from the CD-ROM drive of this VM
+func (v *VM) EjectMedia(media Media) (Task, error) {
+
+	task, err := v.mediaAction(types.RelMediaEjectMedia, "ejectMedia", media)
+	if err != nil {
+		return Task{}, fmt.Errorf("error ejecting media: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
@@ -0,0 +1,229 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// mediaHandler serves the media being uploaded as far as transfer has
+// received it, the media is resolved once its image is complete
+func mediaHandler(transfer *transferStub, submitted map[string][]byte, cc *callCounter) http.Handler {
+	responses := map[string]map[string]testResponse{
+		"GET": {
+			"/api/vdc/00000000-0000-0000-0000-000000000000": {200, nil, vdcMediaExample},
+		},
+		"POST": {
+			"/api/vdc/00000000-0000-0000-0000-000000000000/media":                        {201, nil, fmt.Sprintf(mediaUploadExample, 0)},
+			"/api/catalog/e8a20fdf-8a78-440c-ac71-0420db59f854/catalogItems":             {201, nil, mediaCatalogItemExample},
+			"/api/vApp/vm-11111111-1111-1111-1111-111111111111/media/action/insertMedia": {202, nil, taskExample},
+			"/api/vApp/vm-11111111-1111-1111-1111-111111111111/media/action/ejectMedia":  {202, nil, taskExample},
+		},
+		"DELETE": {
+			"/api/media/77777777-7777-7777-7777-777777777777": {202, nil, taskExample},
+		},
+	}
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/transfer/"):
+			transfer.ServeHTTP(rw, r)
+		case r.Method == "GET" && r.URL.Path == "/api/media/77777777-7777-7777-7777-777777777777":
+			media := mediaExample
+			if n := transfer.transferred("file"); n < 7000 {
+				media = fmt.Sprintf(mediaUploadExample, n)
+			}
+			testHandler(map[string]testResponse{r.URL.Path: {200, nil, media}}, cc).ServeHTTP(rw, r)
+		default:
+			testMethodHandler(responses, submitted, cc).ServeHTTP(rw, r)
+		}
+	})
+}
+
+func Test_Media(t *testing.T) {
+	defer func(interval time.Duration) { uploadPollInterval = interval }(uploadPollInterval)
+	uploadPollInterval = time.Millisecond
+
+	dir, err := ioutil.TempDir("", "govcloudair")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	iso := bytes.Repeat([]byte("ISO9660"), 1000)
+	isoPath := filepath.Join(dir, "installer.iso")
+	if !assert.NoError(t, ioutil.WriteFile(isoPath, iso, 0644)) {
+		return
+	}
+
+	transfer := newTransferStub()
+	submitted := make(map[string][]byte)
+	ctx, err := setupTestContext(authHandler(mediaHandler(transfer, submitted, new(callCounter))))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	cat := NewCatalog(ctx.Client)
+	cat.Catalog.HREF = ctx.Server.URL + "/api/catalog/e8a20fdf-8a78-440c-ac71-0420db59f854"
+
+	var reports []UploadProgress
+	opts := UploadOptions{
+		ChunkSize: 4000,
+		Progress:  func(p UploadProgress) { reports = append(reports, p) },
+	}
+
+	media, err := cat.UploadMediaImage(*ctx.VDC, isoPath, "installer.iso", "OS installer", opts)
+	if assert.NoError(t, err) {
+		assert.Equal(t, types.VAppStatusResolved, types.VAppStatus(media.Media.Status))
+		created := new(types.Media)
+		if assert.NoError(t, xml.Unmarshal(submitted["POST /api/vdc/00000000-0000-0000-0000-000000000000/media"], created)) {
+			assert.Equal(t, "iso", created.ImageType)
+			assert.Equal(t, int64(7000), created.Size)
+		}
+		assert.Equal(t, iso, transfer.received["file"])
+		item := new(types.CatalogItem)
+		if assert.NoError(t, xml.Unmarshal(submitted["POST /api/catalog/e8a20fdf-8a78-440c-ac71-0420db59f854/catalogItems"], item)) {
+			assert.Equal(t, "installer.iso", item.Name)
+			assert.Equal(t, media.Media.HREF, item.Entity.HREF)
+		}
+		if assert.Len(t, reports, 3) {
+			assert.Equal(t, int64(4000), reports[1].TotalBytes)
+			assert.Equal(t, int64(7000), reports[2].TotalBytes)
+			assert.Equal(t, int64(7000), reports[2].TotalSize)
+		}
+	}
+
+	refs, err := ctx.VDC.ListMedia()
+	if assert.NoError(t, err) && assert.Len(t, refs, 1) {
+		assert.Equal(t, "installer.iso", refs[0].Name)
+	}
+
+	found, err := ctx.VDC.FindMedia("installer.iso")
+	if assert.NoError(t, err) {
+		assert.Equal(t, media.Media.HREF, found.Media.HREF)
+	}
+	_, err = ctx.VDC.FindMedia("INVALID")
+	assert.Error(t, err)
+
+	vm := NewVM(ctx.Client)
+	vm.VM.HREF = ctx.Server.URL + "/api/vApp/vm-11111111-1111-1111-1111-111111111111"
+
+	task, err := vm.InsertMedia(found)
+	if assert.NoError(t, err) {
+		assert.Equal(t, ctx.Server.URL+"/api/task/1b8f926c-eff5-4bea-9b13-4e49bdd50c05", task.Task.HREF)
+		params := new(types.MediaInsertOrEjectParams)
+		if assert.NoError(t, xml.Unmarshal(submitted["POST /api/vApp/vm-11111111-1111-1111-1111-111111111111/media/action/insertMedia"], params)) {
+			assert.Equal(t, media.Media.HREF, params.Media.HREF)
+			assert.Equal(t, types.MimeMedia, params.Media.Type)
+		}
+	}
+
+	_, err = vm.EjectMedia(found)
+	if assert.NoError(t, err) {
+		assert.Contains(t, submitted, "POST /api/vApp/vm-11111111-1111-1111-1111-111111111111/media/action/ejectMedia")
+	}
+
+	_, err = found.Delete()
+	assert.NoError(t, err)
+}
+
+func Test_MediaUploadFailed(t *testing.T) {
+	defer func(interval time.Duration) { uploadPollInterval = interval }(uploadPollInterval)
+	uploadPollInterval = time.Millisecond
+
+	dir, err := ioutil.TempDir("", "govcloudair")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	isoPath := filepath.Join(dir, "installer.iso")
+	if !assert.NoError(t, ioutil.WriteFile(isoPath, bytes.Repeat([]byte("ISO9660"), 1000), 0644)) {
+		return
+	}
+
+	// vCloud has every byte but fails to import the image
+	failed := strings.Replace(fmt.Sprintf(mediaUploadExample, 7000), "<Files>", `<Tasks>
+        <Task status="error" operationName="vdcUploadMedia" name="task" href="http://localhost:4444/api/task/1b8f926c-eff5-4bea-9b13-4e49bdd50c05" type="application/vnd.vmware.vcloud.task+xml">
+            <Error message="The image is not a valid ISO 9660 image." majorErrorCode="400" minorErrorCode="BAD_REQUEST"/>
+        </Task>
+    </Tasks>
+    <Files>`, 1)
+	cc := new(callCounter)
+	ctx, err := setupTestContext(authHandler(testHandler(map[string]testResponse{
+		"/api/media/77777777-7777-7777-7777-777777777777": {200, nil, failed},
+	}, cc)))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	media := NewMedia(ctx.Client)
+	media.Media.HREF = ctx.Server.URL + "/api/media/77777777-7777-7777-7777-777777777777"
+
+	err = media.ResumeUpload(isoPath, UploadOptions{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "not a valid ISO 9660 image")
+	}
+	assert.Equal(t, 2, cc.Pop())
+
+	// an import that never ends times out
+	ctx, err = setupTestContext(authHandler(testHandler(map[string]testResponse{
+		"/api/media/77777777-7777-7777-7777-777777777777": {200, nil, fmt.Sprintf(mediaUploadExample, 7000)},
+	}, cc)))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	media = NewMedia(ctx.Client)
+	media.Media.HREF = ctx.Server.URL + "/api/media/77777777-7777-7777-7777-777777777777"
+
+	err = media.ResumeUpload(isoPath, UploadOptions{Timeout: 20 * time.Millisecond})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "timed out")
+	}
+}
+
+// mediaUploadExample is an ISO image of 7000 bytes, listing the bytes
+// transferred so far
+var mediaUploadExample = `<?xml version="1.0" encoding="UTF-8"?>
+<Media xmlns="http://www.vmware.com/vcloud/v1.5" size="7000" imageType="iso" status="0" name="installer.iso" id="urn:vcloud:media:77777777-7777-7777-7777-777777777777" href="http://localhost:4444/api/media/77777777-7777-7777-7777-777777777777" type="application/vnd.vmware.vcloud.media+xml">
+    <Description>OS installer</Description>
+    <Files>
+        <File size="7000" bytesTransferred="%d" name="file">
+            <Link rel="upload:default" href="http://localhost:4444/transfer/7777/file"/>
+        </File>
+    </Files>
+</Media>
+`
+
+var mediaExample = `<?xml version="1.0" encoding="UTF-8"?>
+<Media xmlns="http://www.vmware.com/vcloud/v1.5" size="7000" imageType="iso" status="1" name="installer.iso" id="urn:vcloud:media:77777777-7777-7777-7777-777777777777" href="http://localhost:4444/api/media/77777777-7777-7777-7777-777777777777" type="application/vnd.vmware.vcloud.media+xml">
+    <Link rel="remove" href="http://localhost:4444/api/media/77777777-7777-7777-7777-777777777777"/>
+    <Description>OS installer</Description>
+</Media>
+`
+
+var mediaCatalogItemExample = `<?xml version="1.0" encoding="UTF-8"?>
+<CatalogItem xmlns="http://www.vmware.com/vcloud/v1.5" size="7000" name="installer.iso" id="urn:vcloud:catalogitem:7f1e2d3c-4b5a-4969-8877-665544332211" type="application/vnd.vmware.vcloud.catalogItem+xml" href="http://localhost:4444/api/catalogItem/7f1e2d3c-4b5a-4969-8877-665544332211">
+    <Link rel="up" type="application/vnd.vmware.vcloud.catalog+xml" href="http://localhost:4444/api/catalog/e8a20fdf-8a78-440c-ac71-0420db59f854"/>
+    <Description>OS installer</Description>
+    <Entity type="application/vnd.vmware.vcloud.media+xml" name="installer.iso" href="http://localhost:4444/api/media/77777777-7777-7777-7777-777777777777"/>
+</CatalogItem>
+`
+
+// vdcMediaExample is vdcExample holding the media
+var vdcMediaExample = strings.Replace(vdcExample, "</ResourceEntities>", `<ResourceEntity href="http://localhost:4444/api/media/77777777-7777-7777-7777-777777777777" name="installer.iso" type="application/vnd.vmware.vcloud.media+xml"/></ResourceEntities>`, 1)
@@ -0,0 +1,211 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"fmt"
+	"strings"
+
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// The NAT rule types and protocols understood by the edge gateway
+const (
+	NatRuleTypeDNAT = "DNAT"
+	NatRuleTypeSNAT = "SNAT"
+
+	NatProtocolAny    = "any"
+	NatProtocolTCP    = "tcp"
+	NatProtocolUDP    = "udp"
+	NatProtocolTCPUDP = "tcpudp"
+	NatProtocolICMP   = "icmp"
+)
+
+// NewDNATRule builds a destination NAT rule on the gateway interface iface,
+// translating traffic for externalIP:externalPort to internalIP:internalPort.
+// Ports can be a single port, a range or "any".
+func NewDNATRule(iface *types.Reference, protocol, externalIP, externalPort, internalIP, internalPort, description string) *types.NatRule {
+	return &types.NatRule{
+		Description: description,
+		RuleType:    NatRuleTypeDNAT,
+		IsEnabled:   true,
+		GatewayNatRule: &types.GatewayNatRule{
+			Interface:      iface,
+			OriginalIP:     externalIP,
+			OriginalPort:   externalPort,
+			TranslatedIP:   internalIP,
+			TranslatedPort: internalPort,
+			Protocol:       protocol,
+		},
+	}
+}
+
+// NewSNATRule builds a source NAT rule on the gateway interface iface,
+// translating traffic from internalIP, which can be a range or CIDR, to
+// externalIP.
+func NewSNATRule(iface *types.Reference, internalIP, externalIP, description string) *types.NatRule {
+	return &types.NatRule{
+		Description: description,
+		RuleType:    NatRuleTypeSNAT,
+		IsEnabled:   true,
+		GatewayNatRule: &types.GatewayNatRule{
+			Interface:    iface,
+			OriginalIP:   internalIP,
+			TranslatedIP: externalIP,
+		},
+	}
+}
+
+func validateNatRule(rule *types.NatRule) error {
+
+	if rule == nil || rule.GatewayNatRule == nil {
+		return fmt.Errorf("NAT rule can't be empty")
+	}
+
+	if rule.RuleType != NatRuleTypeDNAT && rule.RuleType != NatRuleTypeSNAT {
+		return fmt.Errorf("invalid NAT rule type: %s", rule.RuleType)
+	}
+
+	gnr := rule.GatewayNatRule
+
+	if gnr.Interface == nil || gnr.Interface.HREF == "" {
+		return fmt.Errorf("NAT rule %q has no gateway interface", rule.Description)
+	}
+
+	if gnr.OriginalIP == "" || gnr.TranslatedIP == "" {
+		return fmt.Errorf("NAT rule %q needs both an original and a translated IP", rule.Description)
+	}
+
+	switch strings.ToLower(gnr.Protocol) {
+	case "", NatProtocolAny, NatProtocolTCP, NatProtocolUDP, NatProtocolTCPUDP:
+		if gnr.IcmpSubType != "" {
+			return fmt.Errorf("NAT rule %q has an ICMP subtype but protocol %s", rule.Description, gnr.Protocol)
+		}
+	case NatProtocolICMP:
+	default:
+		return fmt.Errorf("unsupported NAT protocol: %s", gnr.Protocol)
+	}
+
+	if rule.RuleType == NatRuleTypeSNAT && (gnr.OriginalPort != "" || gnr.TranslatedPort != "") {
+		return fmt.Errorf("SNAT rule %q can't translate ports", rule.Description)
+	}
+
+	for _, port := range []string{gnr.OriginalPort, gnr.TranslatedPort} {
+		if err := validatePortRange(port); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// configNatService returns the NAT service of config, adding an empty one
+// when the gateway has none
+func configNatService(config *types.GatewayFeatures) *types.NatService {
+	if config.NatService == nil {
+		config.NatService = &types.NatService{}
+	}
+	return config.NatService
+}
+
+// NatRules refreshes this gateway and returns its NAT rules
+func (e *EdgeGateway) NatRules() ([]*types.NatRule, error) {
+
+	if err := e.Refresh(); err != nil {
+		return nil, err
+	}
+
+	if e.EdgeGateway.Configuration == nil || e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration == nil {
+		return nil, fmt.Errorf("edge gateway has no service configuration")
+	}
+
+	nat := e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration.NatService
+	if nat == nil {
+		return nil, nil
+	}
+
+	return nat.NatRule, nil
+}
+
+// FindNatRuleByID finds a NAT rule by ID in the last known configuration of
+// this gateway
+func (e *EdgeGateway) FindNatRuleByID(id string) (*types.NatRule, error) {
+
+	if e.EdgeGateway.Configuration == nil || e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration == nil {
+		return nil, fmt.Errorf("edge gateway has no service configuration")
+	}
+
+	if nat := e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration.NatService; nat != nil {
+		for _, rule := range nat.NatRule {
+			if rule.ID == id {
+				return rule, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("can't find NAT rule: %s", id)
+}
+
+// AddNatRule adds rule to the NAT rules of this gateway, enabling the NAT
+// service when needed. The gateway assigns the rule ID.
+func (e *EdgeGateway) AddNatRule(rule *types.NatRule) (Task, error) {
+
+	if err := validateNatRule(rule); err != nil {
+		return Task{}, err
+	}
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		nat := configNatService(config)
+
+		newrule := *rule
+		newrule.ID = ""
+
+		nat.IsEnabled = true
+		nat.NatRule = append(nat.NatRule, &newrule)
+
+		return nil
+	})
+}
+
+// UpdateNatRule replaces the NAT rule with the same ID as rule
+func (e *EdgeGateway) UpdateNatRule(rule *types.NatRule) (Task, error) {
+
+	if err := validateNatRule(rule); err != nil {
+		return Task{}, err
+	}
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		nat := configNatService(config)
+
+		for k, v := range nat.NatRule {
+			if v.ID == rule.ID {
+				nat.NatRule[k] = rule
+				return nil
+			}
+		}
+
+		return fmt.Errorf("can't find NAT rule: %s", rule.ID)
+	})
+}
+
+// DeleteNatRule removes the NAT rule with the given ID
+func (e *EdgeGateway) DeleteNatRule(id string) (Task, error) {
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		nat := configNatService(config)
+
+		for k, v := range nat.NatRule {
+			if v.ID == id {
+				nat.NatRule = append(nat.NatRule[:k], nat.NatRule[k+1:]...)
+				return nil
+			}
+		}
+
+		return fmt.Errorf("can't find NAT rule: %s", id)
+	})
+}
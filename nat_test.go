@@ -0,0 +1,98 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+func Test_NatRules(t *testing.T) {
+	cc := new(callCounter)
+	config := new(types.GatewayFeatures)
+
+	ctx, err := setupTestContext(authHandler(edgeServicesHandler(edgegatewayExample, config, cc)))
+	if assert.NoError(t, err) {
+
+		edge, err := ctx.VDC.FindEdgeGateway("M916272752-5793")
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+
+			rules, err := edge.NatRules()
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Len(t, rules, 4)
+			}
+
+			rule, err := edge.FindNatRuleByID("65537")
+			if assert.NoError(t, err) {
+				assert.Equal(t, "DNAT", rule.RuleType)
+				assert.Equal(t, "22", rule.GatewayNatRule.TranslatedPort)
+			}
+
+			uplink := &types.Reference{HREF: ctx.Server.URL + "/api/admin/network/6254f107-9876-4d03-986f-8bec7a4bcb3f"}
+
+			dnat := NewDNATRule(uplink, NatProtocolTCP, "23.92.225.51", "443", "192.168.109.8", "8443", "web tier")
+			_, err = edge.AddNatRule(dnat)
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				assert.True(t, config.NatService.IsEnabled)
+				if assert.Len(t, config.NatService.NatRule, 5) {
+					added := config.NatService.NatRule[4]
+					assert.Equal(t, "", added.ID)
+					assert.Equal(t, "web tier", added.Description)
+					assert.Equal(t, "443", added.GatewayNatRule.OriginalPort)
+					assert.Equal(t, "8443", added.GatewayNatRule.TranslatedPort)
+					assert.Equal(t, "tcp", added.GatewayNatRule.Protocol)
+					assert.Equal(t, uplink.HREF, added.GatewayNatRule.Interface.HREF)
+				}
+			}
+
+			snat := NewSNATRule(uplink, "192.168.109.0/24", "23.92.225.51", "outbound")
+			_, err = edge.AddNatRule(snat)
+			assert.NoError(t, err)
+			assert.Equal(t, 5, cc.Pop())
+
+			// invalid rules are rejected before talking to the gateway
+			icmp := NewDNATRule(uplink, NatProtocolTCP, "23.92.225.51", "any", "192.168.109.8", "any", "ping")
+			icmp.GatewayNatRule.IcmpSubType = "echo-request"
+			_, err = edge.AddNatRule(icmp)
+			assert.Error(t, err)
+
+			_, err = edge.AddNatRule(NewDNATRule(nil, NatProtocolTCP, "23.92.225.51", "443", "192.168.109.8", "8443", "no interface"))
+			assert.Error(t, err)
+
+			_, err = edge.AddNatRule(NewDNATRule(uplink, "gre", "23.92.225.51", "443", "192.168.109.8", "8443", "bad protocol"))
+			assert.Error(t, err)
+			assert.Equal(t, 0, cc.Pop())
+
+			icmp.GatewayNatRule.Protocol = NatProtocolICMP
+			icmp.ID = "65540"
+			icmp.IsEnabled = false
+			_, err = edge.UpdateNatRule(icmp)
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				if assert.Len(t, config.NatService.NatRule, 4) {
+					updated := config.NatService.NatRule[3]
+					assert.Equal(t, "65540", updated.ID)
+					assert.False(t, updated.IsEnabled)
+					assert.Equal(t, "echo-request", updated.GatewayNatRule.IcmpSubType)
+				}
+			}
+
+			_, err = edge.DeleteNatRule("65538")
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				var ids []string
+				for _, rule := range config.NatService.NatRule {
+					ids = append(ids, rule.ID)
+				}
+				assert.Equal(t, []string{"65537", "65539", "65540"}, ids)
+				// the firewall is submitted unchanged
+				assert.Len(t, config.FirewallService.FirewallRule, 4)
+			}
+
+			_, err = edge.DeleteNatRule("42")
+			assert.Error(t, err)
+		}
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"path"
+	"strings"
 
 	types "github.com/vmware/govcloudair/types/v56"
 )
@@ -138,3 +140,208 @@ func (o *Org) FindCatalog(catalog string) (Catalog, error) {
 
 	return Catalog{}, fmt.Errorf("can't find catalog: %s", catalog)
 }
+
+// Refresh retrieves this org again, with the links to its catalogs
+func (o *Org) Refresh() error {
+
+	if o.Org.HREF == "" {
+		return fmt.Errorf("cannot refresh, Object is empty")
+	}
+
+	u, _ := url.ParseRequestURI(o.Org.HREF)
+
+	req := o.c.NewRequest(map[string]string{}, types.HTTPGet, u, nil)
+
+	resp, err := checkResp(o.c.DoHTTP(req))
+	if err != nil {
+		return fmt.Errorf("error retrieving org: %s", err)
+	}
+
+	// Empty struct before a new unmarshal, otherwise we end up with duplicate
+	// elements in slices.
+	o.Org = &types.Org{}
+
+	if err = decodeBody(resp, o.Org); err != nil {
+		return fmt.Errorf("error decoding org response: %s", err)
+	}
+
+	// The request was successful
+	return nil
+}
+
+// catalogsURL returns the URL catalogs are added to this org with
+func (o *Org) catalogsURL() (*url.URL, error) {
+
+	if l := o.Org.Link.ForType(types.MimeAdminCatalog, types.RelAdd); l != nil {
+		return url.ParseRequestURI(l.HREF)
+	}
+
+	s, err := url.ParseRequestURI(o.Org.HREF)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing org href: %s", err)
+	}
+	s.Path = strings.Replace(s.Path, "/api/org/", "/api/admin/org/", 1) + "/catalogs"
+
+	return s, nil
+}
+
+// CreateCatalog creates an empty catalog in this org. The catalog is ready
+// once the returned task is done.
+func (o *Org) CreateCatalog(name, description string) (Catalog, Task, error) {
+
+	if name == "" {
+		return Catalog{}, Task{}, fmt.Errorf("catalog needs a name")
+	}
+
+	s, err := o.catalogsURL()
+	if err != nil {
+		return Catalog{}, Task{}, err
+	}
+
+	params := &types.AdminCatalog{
+		Xmlns:       types.NsVCloud,
+		Name:        name,
+		Description: description,
+	}
+
+	cat := NewCatalog(o.c)
+
+	if err = executeRequest(o.c, types.HTTPPost, s, types.MimeAdminCatalog, params, cat.Catalog); err != nil {
+		return Catalog{}, Task{}, fmt.Errorf("error creating catalog: %s", err)
+	}
+
+	if cat.Catalog.Tasks == nil || len(cat.Catalog.Tasks.Task) == 0 {
+		return Catalog{}, Task{}, fmt.Errorf("error creating catalog: no task returned")
+	}
+
+	task := NewTask(o.c)
+	task.Task = cat.Catalog.Tasks.Task[0]
+
+	// The request was successful
+	return *cat, *task, nil
+}
+
+// catalogAccessURL returns the URL the access settings of catalog are read
+// from, or changed with when action is true
+func (o *Org) catalogAccessURL(catalog Catalog, action bool) (*url.URL, error) {
+
+	cu, err := url.ParseRequestURI(catalog.Catalog.HREF)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing catalog href: %s", err)
+	}
+	id := path.Base(cu.Path)
+
+	if l := o.Org.Link.Find(func(l *types.Link) bool {
+		if !strings.Contains(l.HREF, "/catalog/"+id+"/") {
+			return false
+		}
+		if action {
+			return l.Rel == types.RelControlAccess
+		}
+		return l.Rel == types.RelDown && l.Type == types.MimeControlAccess
+	}); l != nil {
+		s, err := url.ParseRequestURI(l.HREF)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing control access href: %s", err)
+		}
+		return s, nil
+	}
+
+	s, err := url.ParseRequestURI(o.Org.HREF)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing org href: %s", err)
+	}
+	if action {
+		s.Path += "/catalog/" + id + "/action/controlAccess"
+	} else {
+		s.Path += "/catalog/" + id + "/controlAccess/"
+	}
+
+	return s, nil
+}
+
+// GetCatalogAccess retrieves who catalog is shared with in this org
+func (o *Org) GetCatalogAccess(catalog Catalog) (*types.ControlAccessParams, error) {
+
+	s, err := o.catalogAccessURL(catalog, false)
+	if err != nil {
+		return nil, err
+	}
+
+	req := o.c.NewRequest(map[string]string{}, types.HTTPGet, s, nil)
+
+	resp, err := checkResp(o.c.DoHTTP(req))
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving catalog access: %s", err)
+	}
+
+	params := new(types.ControlAccessParams)
+
+	if err = decodeBody(resp, params); err != nil {
+		return nil, fmt.Errorf("error decoding catalog access response: %s", err)
+	}
+
+	// The request was successful
+	return params, nil
+}
+
+// SetCatalogAccess replaces who catalog is shared with in this org by params.
+// Users and groups missing from params.AccessSettings lose their access.
+func (o *Org) SetCatalogAccess(catalog Catalog, params *types.ControlAccessParams) error {
+
+	switch params.EveryoneAccessLevel {
+	case "", types.AccessLevelReadOnly, types.AccessLevelChange, types.AccessLevelFullControl:
+	default:
+		return fmt.Errorf("unknown access level %s", params.EveryoneAccessLevel)
+	}
+
+	if params.AccessSettings != nil {
+		for _, setting := range params.AccessSettings.AccessSetting {
+			switch setting.AccessLevel {
+			case types.AccessLevelReadOnly, types.AccessLevelChange, types.AccessLevelFullControl:
+			default:
+				return fmt.Errorf("unknown access level %s", setting.AccessLevel)
+			}
+		}
+	}
+
+	s, err := o.catalogAccessURL(catalog, true)
+	if err != nil {
+		return err
+	}
+
+	newparams := *params
+	newparams.Xmlns = types.NsVCloud
+
+	if err = executeRequest(o.c, types.HTTPPost, s, types.MimeControlAccess, &newparams, new(types.ControlAccessParams)); err != nil {
+		return fmt.Errorf("error sharing catalog: %s", err)
+	}
+
+	// The request was successful
+	return nil
+}
+
+// ShareCatalog shares catalog with every user of this org at accessLevel,
+// one of types.AccessLevelReadOnly, types.AccessLevelChange and
+// types.AccessLevelFullControl. An empty accessLevel stops sharing the
+// catalog with everyone. The access of individual users and groups is read
+// first and kept as it is, use SetCatalogAccess to change it. Use
+// Catalog.Publish to share the catalog with the other orgs.
+func (o *Org) ShareCatalog(catalog Catalog, accessLevel string) error {
+
+	switch accessLevel {
+	case "", types.AccessLevelReadOnly, types.AccessLevelChange, types.AccessLevelFullControl:
+	default:
+		return fmt.Errorf("unknown access level %s", accessLevel)
+	}
+
+	params, err := o.GetCatalogAccess(catalog)
+	if err != nil {
+		return err
+	}
+
+	params.IsSharedToEveryone = accessLevel != ""
+	params.EveryoneAccessLevel = accessLevel
+
+	return o.SetCatalogAccess(catalog, params)
+}
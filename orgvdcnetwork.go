@@ -5,6 +5,11 @@
 package govcloudair
 
 import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+
 	types "github.com/vmware/govcloudair/types/v56"
 )
 
@@ -21,3 +26,378 @@ func NewOrgVDCNetwork(c Client) *OrgVDCNetwork {
 		c:             c,
 	}
 }
+
+// OrgVDCNetworkParams describes an org vdc network to create
+type OrgVDCNetworkParams struct {
+	Name        string
+	Description string
+	// Gateway and Netmask define the subnet of routed and isolated networks,
+	// direct networks inherit the subnet of their external network.
+	Gateway string
+	Netmask string
+	// StaticPools are the ranges of the subnet VMs get their IPs from.
+	StaticPools []*types.IPRange
+	DNS1        string
+	DNS2        string
+	DNSSuffix   string
+	// Shared makes the network available to the other vdcs of the org.
+	Shared bool
+}
+
+// ipScope returns the IP scope described by these params
+func (p OrgVDCNetworkParams) ipScope() *types.IPScope {
+	return &types.IPScope{
+		Gateway:   p.Gateway,
+		Netmask:   p.Netmask,
+		DNS1:      p.DNS1,
+		DNS2:      p.DNS2,
+		DNSSuffix: p.DNSSuffix,
+		IsEnabled: true,
+		IPRanges:  &types.IPRanges{IPRange: p.StaticPools},
+	}
+}
+
+// validateIPScope checks that the static pools of scope are in its subnet,
+// don't overlap and leave out its gateway
+func validateIPScope(scope *types.IPScope) error {
+
+	gw, ok1 := ipToUint32(scope.Gateway)
+	mask, ok2 := ipToUint32(scope.Netmask)
+	if !ok1 || !ok2 {
+		return fmt.Errorf("invalid subnet %s/%s", scope.Gateway, scope.Netmask)
+	}
+
+	for _, dns := range []string{scope.DNS1, scope.DNS2} {
+		if _, ok := ipToUint32(dns); dns != "" && !ok {
+			return fmt.Errorf("invalid DNS server %s", dns)
+		}
+	}
+
+	if scope.IPRanges == nil {
+		return nil
+	}
+
+	for i, r := range scope.IPRanges.IPRange {
+		from, ok1 := ipToUint32(r.StartAddress)
+		to, ok2 := ipToUint32(r.EndAddress)
+		if !ok1 || !ok2 || to < from {
+			return fmt.Errorf("invalid IP range %s-%s", r.StartAddress, r.EndAddress)
+		}
+		if from&mask != gw&mask || to&mask != gw&mask {
+			return fmt.Errorf("IP range %s-%s is outside of the subnet %s/%s", r.StartAddress, r.EndAddress, scope.Gateway, scope.Netmask)
+		}
+		if gw >= from && gw <= to {
+			return fmt.Errorf("IP range %s-%s includes the gateway %s", r.StartAddress, r.EndAddress, scope.Gateway)
+		}
+		if overlapsRanges(from, to, scope.IPRanges.IPRange[:i]) {
+			return fmt.Errorf("IP range %s-%s overlaps another range", r.StartAddress, r.EndAddress)
+		}
+	}
+
+	return nil
+}
+
+// newOrgVDCNetwork returns the org vdc network described by params, without
+// its configuration
+func newOrgVDCNetwork(params OrgVDCNetworkParams) (*types.OrgVDCNetwork, error) {
+
+	if params.Name == "" {
+		return nil, fmt.Errorf("org vdc network needs a name")
+	}
+
+	return &types.OrgVDCNetwork{
+		Xmlns:       types.NsVCloud,
+		Name:        params.Name,
+		Description: params.Description,
+		IsShared:    params.Shared,
+	}, nil
+}
+
+// CreateRoutedNetwork creates an org vdc network connected to the internal
+// interface of an edge gateway of this vdc. It returns the new network along
+// with the task tracking its creation.
+func (v *Vdc) CreateRoutedNetwork(params OrgVDCNetworkParams, edge EdgeGateway) (OrgVDCNetwork, Task, error) {
+
+	if edge.EdgeGateway == nil || edge.EdgeGateway.HREF == "" {
+		return OrgVDCNetwork{}, Task{}, fmt.Errorf("can't create routed network, edge gateway is not valid")
+	}
+
+	network, err := newOrgVDCNetwork(params)
+	if err != nil {
+		return OrgVDCNetwork{}, Task{}, err
+	}
+
+	scope := params.ipScope()
+	if err := validateIPScope(scope); err != nil {
+		return OrgVDCNetwork{}, Task{}, err
+	}
+
+	network.EdgeGateway = &types.Reference{
+		HREF: edge.EdgeGateway.HREF,
+		Name: edge.EdgeGateway.Name,
+		Type: edge.EdgeGateway.Type,
+	}
+	network.Configuration = &types.NetworkConfiguration{
+		IPScopes:  &types.IPScopes{IPScope: []*types.IPScope{scope}},
+		FenceMode: types.FenceModeNAT,
+	}
+
+	return v.createOrgVDCNetwork(network)
+}
+
+// CreateIsolatedNetwork creates an org vdc network that is only reachable
+// from the VMs connected to it. It returns the new network along with the
+// task tracking its creation.
+func (v *Vdc) CreateIsolatedNetwork(params OrgVDCNetworkParams) (OrgVDCNetwork, Task, error) {
+
+	network, err := newOrgVDCNetwork(params)
+	if err != nil {
+		return OrgVDCNetwork{}, Task{}, err
+	}
+
+	scope := params.ipScope()
+	if err := validateIPScope(scope); err != nil {
+		return OrgVDCNetwork{}, Task{}, err
+	}
+
+	network.Configuration = &types.NetworkConfiguration{
+		IPScopes:  &types.IPScopes{IPScope: []*types.IPScope{scope}},
+		FenceMode: types.FenceModeIsolated,
+	}
+
+	return v.createOrgVDCNetwork(network)
+}
+
+// CreateDirectNetwork creates an org vdc network bridged to the external
+// network parent. The network inherits the IP scope of parent, so params
+// can't set one. It returns the new network along with the task tracking its
+// creation.
+func (v *Vdc) CreateDirectNetwork(params OrgVDCNetworkParams, parent *types.Reference) (OrgVDCNetwork, Task, error) {
+
+	if parent == nil || parent.HREF == "" {
+		return OrgVDCNetwork{}, Task{}, fmt.Errorf("can't create direct network, external network is not valid")
+	}
+
+	if params.Gateway != "" || params.Netmask != "" || len(params.StaticPools) > 0 || params.DNS1 != "" || params.DNS2 != "" || params.DNSSuffix != "" {
+		return OrgVDCNetwork{}, Task{}, fmt.Errorf("direct networks inherit the IP scope of their external network")
+	}
+
+	network, err := newOrgVDCNetwork(params)
+	if err != nil {
+		return OrgVDCNetwork{}, Task{}, err
+	}
+
+	network.Configuration = &types.NetworkConfiguration{
+		ParentNetwork: parent,
+		FenceMode:     types.FenceModeBridged,
+	}
+
+	return v.createOrgVDCNetwork(network)
+}
+
+// networksURL returns the URL org vdc networks of this vdc are created at
+func (v *Vdc) networksURL() (*url.URL, error) {
+
+	for _, l := range v.Vdc.Link {
+		if l.Rel == "add" && l.Type == types.MimeOrgVdcNetwork {
+			return url.ParseRequestURI(l.HREF)
+		}
+	}
+
+	// Only the admin view of the vdc has the link
+	s, err := url.ParseRequestURI(v.Vdc.HREF)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing vdc href: %s", err)
+	}
+	s.Path = strings.Replace(s.Path, "/api/vdc/", "/api/admin/vdc/", 1) + "/networks"
+
+	return s, nil
+}
+
+func (v *Vdc) createOrgVDCNetwork(network *types.OrgVDCNetwork) (OrgVDCNetwork, Task, error) {
+
+	s, err := v.networksURL()
+	if err != nil {
+		return OrgVDCNetwork{}, Task{}, err
+	}
+
+	orgnet := NewOrgVDCNetwork(v.c)
+
+	if err = executeRequest(v.c, types.HTTPPost, s, types.MimeOrgVdcNetwork, network, orgnet.OrgVDCNetwork); err != nil {
+		return OrgVDCNetwork{}, Task{}, fmt.Errorf("error creating org vdc network: %s", err)
+	}
+
+	if orgnet.OrgVDCNetwork.Tasks == nil || len(orgnet.OrgVDCNetwork.Tasks.Task) == 0 {
+		return OrgVDCNetwork{}, Task{}, fmt.Errorf("error creating org vdc network: no task returned")
+	}
+
+	task := NewTask(v.c)
+	task.Task = orgnet.OrgVDCNetwork.Tasks.Task[0]
+
+	// The request was successful
+	return *orgnet, *task, nil
+}
+
+// Refresh refreshes this org vdc network
+func (n *OrgVDCNetwork) Refresh() error {
+
+	if n.OrgVDCNetwork.HREF == "" {
+		return fmt.Errorf("cannot refresh, Object is empty")
+	}
+
+	u, _ := url.ParseRequestURI(n.OrgVDCNetwork.HREF)
+
+	req := n.c.NewRequest(map[string]string{}, types.HTTPGet, u, nil)
+
+	resp, err := checkResp(n.c.DoHTTP(req))
+	if err != nil {
+		return fmt.Errorf("error retrieving org vdc network: %s", err)
+	}
+
+	// Empty struct before a new unmarshal, otherwise we end up with duplicate
+	// elements in slices.
+	n.OrgVDCNetwork = &types.OrgVDCNetwork{}
+
+	if err = decodeBody(resp, n.OrgVDCNetwork); err != nil {
+		return fmt.Errorf("error decoding org vdc network response: %s", err)
+	}
+
+	// The request was successful
+	return nil
+}
+
+// adminURL returns the URL of the link rel of this network, networks are
+// changed through their admin view, which the user view doesn't link to
+func (n *OrgVDCNetwork) adminURL(rel string) (*url.URL, error) {
+
+	for _, l := range n.OrgVDCNetwork.Link {
+		if l.Rel == rel {
+			return url.ParseRequestURI(l.HREF)
+		}
+	}
+
+	s, err := url.ParseRequestURI(n.OrgVDCNetwork.HREF)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing org vdc network href: %s", err)
+	}
+	s.Path = strings.Replace(s.Path, "/api/network/", "/api/admin/network/", 1)
+
+	return s, nil
+}
+
+// update refreshes this network and submits a copy of it changed by mutate
+func (n *OrgVDCNetwork) update(mutate func(network *types.OrgVDCNetwork) error) (Task, error) {
+
+	if err := n.Refresh(); err != nil {
+		return Task{}, err
+	}
+
+	s, err := n.adminURL("edit")
+	if err != nil {
+		return Task{}, err
+	}
+
+	data, err := xml.Marshal(n.OrgVDCNetwork)
+	if err != nil {
+		return Task{}, fmt.Errorf("error encoding org vdc network: %s", err)
+	}
+
+	network := new(types.OrgVDCNetwork)
+	if err := xml.Unmarshal(data, network); err != nil {
+		return Task{}, fmt.Errorf("error decoding org vdc network: %s", err)
+	}
+
+	network.Xmlns = types.NsVCloud
+	network.Link, network.Tasks = nil, nil
+
+	if err := mutate(network); err != nil {
+		return Task{}, err
+	}
+
+	task, err := executeTaskRequest(n.c, types.HTTPPut, s, types.MimeOrgVdcNetwork, network)
+	if err != nil {
+		return Task{}, fmt.Errorf("error updating org vdc network: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
+
+// networkIPScope returns the first IP scope of config, or nil when it has
+// none. Org vdc networks have a single IP scope.
+func networkIPScope(config *types.NetworkConfiguration) *types.IPScope {
+
+	if config == nil || config.IPScopes == nil || len(config.IPScopes.IPScope) == 0 {
+		return nil
+	}
+
+	return config.IPScopes.IPScope[0]
+}
+
+// ownIPScope returns the IP scope of network, unless it is inherited
+func ownIPScope(network *types.OrgVDCNetwork) (*types.IPScope, error) {
+
+	scope := networkIPScope(network.Configuration)
+	if scope == nil {
+		return nil, fmt.Errorf("org vdc network %s has no IP scope", network.Name)
+	}
+
+	if scope.IsInherited {
+		return nil, fmt.Errorf("org vdc network %s inherits its IP scope", network.Name)
+	}
+
+	return scope, nil
+}
+
+// SetStaticPools replaces the static IP pools of this network. The pools
+// must be in the subnet of the network.
+func (n *OrgVDCNetwork) SetStaticPools(pools []*types.IPRange) (Task, error) {
+
+	return n.update(func(network *types.OrgVDCNetwork) error {
+
+		scope, err := ownIPScope(network)
+		if err != nil {
+			return err
+		}
+
+		scope.IPRanges = &types.IPRanges{IPRange: pools}
+		scope.AllocatedIPAddresses, scope.SubAllocations = nil, nil
+
+		return validateIPScope(scope)
+	})
+}
+
+// SetDNS replaces the DNS servers and suffix of this network, empty values
+// clear them
+func (n *OrgVDCNetwork) SetDNS(dns1, dns2, suffix string) (Task, error) {
+
+	return n.update(func(network *types.OrgVDCNetwork) error {
+
+		scope, err := ownIPScope(network)
+		if err != nil {
+			return err
+		}
+
+		scope.DNS1, scope.DNS2, scope.DNSSuffix = dns1, dns2, suffix
+		scope.AllocatedIPAddresses, scope.SubAllocations = nil, nil
+
+		return validateIPScope(scope)
+	})
+}
+
+// Delete deletes this org vdc network, it must not be in use by any vApp
+func (n *OrgVDCNetwork) Delete() (Task, error) {
+
+	s, err := n.adminURL("remove")
+	if err != nil {
+		return Task{}, err
+	}
+
+	task, err := executeTaskRequest(n.c, types.HTTPDelete, s, "", nil)
+	if err != nil {
+		return Task{}, fmt.Errorf("error deleting org vdc network: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
@@ -4,6 +4,174 @@
 
 package govcloudair
 
+import (
+	"encoding/xml"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// orgVDCNetworkHandler serves an org vdc network and records the networks
+// posted and put to it
+func orgVDCNetworkHandler(submitted *types.OrgVDCNetwork, cc *callCounter) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			*submitted = types.OrgVDCNetwork{}
+			xml.NewDecoder(r.Body).Decode(submitted)
+			testHandler(map[string]testResponse{
+				"/api/admin/vdc/00000000-0000-0000-0000-000000000000/networks": {201, nil, orgvdcnetCreatedExample},
+			}, cc).ServeHTTP(rw, r)
+		case "PUT", "DELETE":
+			if r.Method == "PUT" {
+				*submitted = types.OrgVDCNetwork{}
+				xml.NewDecoder(r.Body).Decode(submitted)
+			}
+			testHandler(map[string]testResponse{
+				"/api/admin/network/cb0f4c9e-1a46-49d4-9fcb-d228000a6bc1": {202, nil, taskExample},
+			}, cc).ServeHTTP(rw, r)
+		default:
+			testHandler(map[string]testResponse{
+				"/api/network/cb0f4c9e-1a46-49d4-9fcb-d228000a6bc1": {200, nil, orgvdcnetExample},
+			}, cc).ServeHTTP(rw, r)
+		}
+	})
+}
+
+func Test_CreateOrgVDCNetwork(t *testing.T) {
+	cc := new(callCounter)
+	submitted := new(types.OrgVDCNetwork)
+
+	ctx, err := setupTestContext(authHandler(orgVDCNetworkHandler(submitted, cc)))
+	if assert.NoError(t, err) {
+		params := OrgVDCNetworkParams{
+			Name:        "routed",
+			Description: "routed network",
+			Gateway:     "192.168.110.1",
+			Netmask:     "255.255.255.0",
+			StaticPools: []*types.IPRange{{StartAddress: "192.168.110.10", EndAddress: "192.168.110.99"}},
+			DNS1:        "8.8.8.8",
+			DNSSuffix:   "example.com",
+			Shared:      true,
+		}
+		edge := EdgeGateway{EdgeGateway: &types.EdgeGateway{
+			HREF: ctx.Server.URL + "/api/admin/edgeGateway/2a1d4b6a-2ba4-4437-bcc3-e4d9b5bf1e43",
+			Name: "M916272752-5793",
+			Type: types.MimeEdgeGateway,
+		}}
+
+		net, task, err := ctx.VDC.CreateRoutedNetwork(params, edge)
+		if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+			assert.Equal(t, "routed", submitted.Name)
+			assert.True(t, submitted.IsShared)
+			assert.Equal(t, types.FenceModeNAT, submitted.Configuration.FenceMode)
+			assert.Equal(t, edge.EdgeGateway.HREF, submitted.EdgeGateway.HREF)
+			scope := submitted.Configuration.IPScopes.IPScope[0]
+			assert.Equal(t, "192.168.110.1", scope.Gateway)
+			assert.Equal(t, "8.8.8.8", scope.DNS1)
+			assert.Equal(t, "example.com", scope.DNSSuffix)
+			assert.Equal(t, "192.168.110.99", scope.IPRanges.IPRange[0].EndAddress)
+
+			assert.Equal(t, ctx.Server.URL+"/api/network/cb0f4c9e-1a46-49d4-9fcb-d228000a6bc1", net.OrgVDCNetwork.HREF)
+			assert.Equal(t, ctx.Server.URL+"/api/task/1b8f926c-eff5-4bea-9b13-4e49bdd50c05", task.Task.HREF)
+		}
+
+		params.Name = "isolated"
+		_, _, err = ctx.VDC.CreateIsolatedNetwork(params)
+		if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+			assert.Equal(t, types.FenceModeIsolated, submitted.Configuration.FenceMode)
+			assert.Nil(t, submitted.EdgeGateway)
+		}
+
+		parent := &types.Reference{HREF: ctx.Server.URL + "/api/admin/extension/externalnet/0f3c1e2b-7a8d-4f55-9e0b-2c4b6d8a1f33", Name: "external"}
+		_, _, err = ctx.VDC.CreateDirectNetwork(params, parent)
+		assert.Error(t, err)
+		assert.Equal(t, 0, cc.Pop())
+
+		_, _, err = ctx.VDC.CreateDirectNetwork(OrgVDCNetworkParams{Name: "direct"}, parent)
+		if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+			assert.Equal(t, types.FenceModeBridged, submitted.Configuration.FenceMode)
+			assert.Equal(t, parent.HREF, submitted.Configuration.ParentNetwork.HREF)
+			assert.Nil(t, submitted.Configuration.IPScopes)
+		}
+
+		for _, pools := range [][]*types.IPRange{
+			{{StartAddress: "192.168.111.10", EndAddress: "192.168.111.99"}},
+			{{StartAddress: "192.168.110.1", EndAddress: "192.168.110.9"}},
+			{{StartAddress: "192.168.110.99", EndAddress: "192.168.110.10"}},
+			{{StartAddress: "192.168.110.10", EndAddress: "192.168.110.99"}, {StartAddress: "192.168.110.50", EndAddress: "192.168.110.150"}},
+			{{StartAddress: "192.168.110.20", EndAddress: "192.168.110.30"}, {StartAddress: "192.168.110.10", EndAddress: "192.168.110.99"}},
+		} {
+			params.StaticPools = pools
+			_, _, err = ctx.VDC.CreateIsolatedNetwork(params)
+			assert.Error(t, err)
+			assert.Equal(t, 0, cc.Pop())
+		}
+	}
+}
+
+func Test_ValidateIPScope(t *testing.T) {
+	scope := &types.IPScope{
+		Gateway: "10.0.0.1",
+		Netmask: "255.255.255.0",
+		IPRanges: &types.IPRanges{IPRange: []*types.IPRange{
+			{StartAddress: "10.0.0.10", EndAddress: "10.0.0.20"},
+			{StartAddress: "10.0.0.21", EndAddress: "10.0.0.30"},
+		}},
+	}
+	assert.NoError(t, validateIPScope(scope))
+
+	// the second range contains the first one
+	scope.IPRanges.IPRange[1] = &types.IPRange{StartAddress: "10.0.0.5", EndAddress: "10.0.0.30"}
+	assert.Error(t, validateIPScope(scope))
+}
+
+func Test_UpdateOrgVDCNetwork(t *testing.T) {
+	cc := new(callCounter)
+	submitted := new(types.OrgVDCNetwork)
+
+	ctx, err := setupTestContext(authHandler(orgVDCNetworkHandler(submitted, cc)))
+	if assert.NoError(t, err) {
+		net := NewOrgVDCNetwork(ctx.Client)
+		net.OrgVDCNetwork.HREF = ctx.Server.URL + "/api/network/cb0f4c9e-1a46-49d4-9fcb-d228000a6bc1"
+
+		if assert.NoError(t, net.Refresh()) && assert.Equal(t, 1, cc.Pop()) {
+			assert.Equal(t, "networkName", net.OrgVDCNetwork.Name)
+			assert.Equal(t, types.FenceModeNAT, net.OrgVDCNetwork.Configuration.FenceMode)
+		}
+
+		task, err := net.SetStaticPools([]*types.IPRange{
+			{StartAddress: "192.168.109.2", EndAddress: "192.168.109.100"},
+			{StartAddress: "192.168.109.150", EndAddress: "192.168.109.200"},
+		})
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+			assert.Equal(t, "success", task.Task.Status)
+			assert.Equal(t, "networkName", submitted.Name)
+			assert.Empty(t, submitted.Link)
+			assert.Len(t, submitted.Configuration.IPScopes.IPScope[0].IPRanges.IPRange, 2)
+		}
+
+		_, err = net.SetStaticPools([]*types.IPRange{{StartAddress: "10.0.0.2", EndAddress: "10.0.0.100"}})
+		assert.Error(t, err)
+		assert.Equal(t, 1, cc.Pop())
+
+		_, err = net.SetDNS("192.168.109.1", "", "example.com")
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+			scope := submitted.Configuration.IPScopes.IPScope[0]
+			assert.Equal(t, "192.168.109.1", scope.DNS1)
+			assert.Equal(t, "example.com", scope.DNSSuffix)
+			assert.Equal(t, "192.168.109.100", scope.IPRanges.IPRange[0].EndAddress)
+		}
+
+		_, err = net.Delete()
+		if assert.NoError(t, err) {
+			assert.Equal(t, 1, cc.Pop())
+		}
+	}
+}
+
 var orgvdcnetExample = `
 <?xml version="1.0" encoding="UTF-8"?>
 <OrgVdcNetwork xmlns="http://www.vmware.com/vcloud/v1.5" status="1" name="networkName" id="urn:vcloud:network:cb0f4c9e-1a46-49d4-9fcb-d228000a6bc1" href="http://localhost:4444/api/network/cb0f4c9e-1a46-49d4-9fcb-d228000a6bc1" type="application/vnd.vmware.vcloud.orgVdcNetwork+xml" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://www.vmware.com/vcloud/v1.5 http://10.6.32.3/api/v1.5/schema/master.xsd">
@@ -32,3 +200,27 @@ var orgvdcnetExample = `
     <IsShared>false</IsShared>
 </OrgVdcNetwork>
 	`
+
+var orgvdcnetCreatedExample = `
+<?xml version="1.0" encoding="UTF-8"?>
+<OrgVdcNetwork xmlns="http://www.vmware.com/vcloud/v1.5" status="0" name="routed" id="urn:vcloud:network:cb0f4c9e-1a46-49d4-9fcb-d228000a6bc1" href="http://localhost:4444/api/network/cb0f4c9e-1a46-49d4-9fcb-d228000a6bc1" type="application/vnd.vmware.vcloud.orgVdcNetwork+xml">
+    <Link rel="up" href="http://localhost:4444/api/vdc/00000000-0000-0000-0000-000000000000" type="application/vnd.vmware.vcloud.vdc+xml"/>
+    <Description>routed network</Description>
+    <Tasks>
+        <Task cancelRequested="false" expiryTime="2015-02-08T09:09:16.627Z" href="http://localhost:4444/api/task/1b8f926c-eff5-4bea-9b13-4e49bdd50c05" id="urn:vcloud:task:1b8f926c-eff5-4bea-9b13-4e49bdd50c05" name="task" operation="Creating Network routed(cb0f4c9e-1a46-49d4-9fcb-d228000a6bc1)" operationName="networkCreateOrgVdcNetwork" serviceNamespace="com.vmware.vcloud" startTime="2014-11-10T09:09:16.627Z" status="running" type="application/vnd.vmware.vcloud.task+xml"/>
+    </Tasks>
+    <Configuration>
+        <IpScopes>
+            <IpScope>
+                <IsInherited>false</IsInherited>
+                <Gateway>192.168.110.1</Gateway>
+                <Netmask>255.255.255.0</Netmask>
+                <IsEnabled>true</IsEnabled>
+            </IpScope>
+        </IpScopes>
+        <FenceMode>natRouted</FenceMode>
+        <RetainNetInfoAcrossDeployments>false</RetainNetInfoAcrossDeployments>
+    </Configuration>
+    <IsShared>true</IsShared>
+</OrgVdcNetwork>
+	`
@@ -0,0 +1,522 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// The actions of a reconciliation plan
+const (
+	RuleAdd    = "add"
+	RuleUpdate = "update"
+	RuleDelete = "delete"
+)
+
+// The kinds of rules a reconciliation plan changes
+const (
+	RuleKindFirewall                  = "FirewallRule"
+	RuleKindNat                       = "NatRule"
+	RuleKindLoadBalancerPool          = "LoadBalancerPool"
+	RuleKindLoadBalancerVirtualServer = "LoadBalancerVirtualServer"
+	RuleKindLoadBalancerService       = "LoadBalancerService"
+)
+
+// GatewayRules is the desired state of the rules owned by one party on an
+// edge gateway. A rule is owned when its description starts with Owner, and
+// rules that aren't owned are never changed.
+//
+// Firewall and NAT rules are matched against the live ones by description,
+// load balancer pools and virtual servers by name, so these must be unique.
+// An empty list means the owner wants no rules of that kind. A disabled load
+// balancer service is enabled when the owner wants virtual servers.
+type GatewayRules struct {
+	Owner                      string
+	FirewallRules              []*types.FirewallRule
+	NatRules                   []*types.NatRule
+	LoadBalancerPools          []*types.LoadBalancerPool
+	LoadBalancerVirtualServers []*types.LoadBalancerVirtualServer
+}
+
+// RuleChange is a step of a reconciliation plan
+type RuleChange struct {
+	Action string // One of RuleAdd, RuleUpdate, RuleDelete
+	Kind   string // One of the RuleKind constants
+	Key    string // The description of a firewall or NAT rule, the name of a load balancer pool or virtual server, empty for a service
+}
+
+func (c RuleChange) String() string {
+	if c.Key == "" {
+		return fmt.Sprintf("%s %s", c.Action, c.Kind)
+	}
+	return fmt.Sprintf("%s %s %s", c.Action, c.Kind, c.Key)
+}
+
+// PlanRules refreshes this gateway and returns the changes ReconcileRules
+// would make to reach desired, without applying them
+func (e *EdgeGateway) PlanRules(desired GatewayRules) ([]RuleChange, error) {
+
+	if err := desired.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := e.Refresh(); err != nil {
+		return nil, err
+	}
+
+	data, err := e.gatewayServices()
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := copyServices(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return reconcileRules(config, desired)
+}
+
+// ReconcileRules changes the rules owned by desired.Owner on this gateway to
+// match desired, in a single service reconfiguration made through
+// ModifyServices. It returns the changes it made; when there was nothing to do
+// the returned Task is already complete.
+func (e *EdgeGateway) ReconcileRules(desired GatewayRules) ([]RuleChange, Task, error) {
+
+	if err := desired.validate(); err != nil {
+		return nil, Task{}, err
+	}
+
+	var changes []RuleChange
+	task, err := e.ModifyServices(func(config *types.GatewayFeatures) error {
+		var err error
+		changes, err = reconcileRules(config, desired)
+		return err
+	})
+	if err != nil {
+		return nil, Task{}, err
+	}
+
+	return changes, task, nil
+}
+
+func (desired GatewayRules) validate() error {
+
+	if desired.Owner == "" {
+		return fmt.Errorf("desired rules need an owner")
+	}
+
+	owned := func(kind, key, description string, seen map[string]bool) error {
+		if !strings.HasPrefix(description, desired.Owner) {
+			return fmt.Errorf("%s %s: description %q doesn't start with %q", kind, key, description, desired.Owner)
+		}
+		if seen[key] {
+			return fmt.Errorf("%s %s is listed more than once", kind, key)
+		}
+		seen[key] = true
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for _, rule := range desired.FirewallRules {
+		if err := validateFirewallRule(rule); err != nil {
+			return err
+		}
+		if err := owned(RuleKindFirewall, rule.Description, rule.Description, seen); err != nil {
+			return err
+		}
+	}
+
+	seen = make(map[string]bool)
+	for _, rule := range desired.NatRules {
+		if err := validateNatRule(rule); err != nil {
+			return err
+		}
+		if err := owned(RuleKindNat, rule.Description, rule.Description, seen); err != nil {
+			return err
+		}
+	}
+
+	seen = make(map[string]bool)
+	for _, pool := range desired.LoadBalancerPools {
+		if err := validateLoadBalancerPool(pool); err != nil {
+			return err
+		}
+		if err := owned(RuleKindLoadBalancerPool, pool.Name, pool.Description, seen); err != nil {
+			return err
+		}
+	}
+
+	seen = make(map[string]bool)
+	for _, vs := range desired.LoadBalancerVirtualServers {
+		if err := validateLoadBalancerVirtualServer(vs); err != nil {
+			return err
+		}
+		if err := owned(RuleKindLoadBalancerVirtualServer, vs.Name, vs.Description, seen); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// matchOwned matches the keys of the desired items against the keys of the
+// owned live items. It returns the index of the live counterpart of each
+// desired item, or -1, the indexes of the owned live items left over, and the
+// position of the first owned live item where the desired items go.
+func matchOwned(liveKeys []string, owned []bool, desiredKeys []string) (matches []int, leftover []int, insertAt int) {
+
+	insertAt = -1
+	index := make(map[string]int)
+	for i, key := range liveKeys {
+		if !owned[i] {
+			continue
+		}
+		if insertAt < 0 {
+			insertAt = i
+		}
+		if _, ok := index[key]; !ok {
+			index[key] = i
+		}
+	}
+
+	used := make(map[int]bool)
+	for _, key := range desiredKeys {
+		i, ok := index[key]
+		if !ok {
+			i = -1
+		} else {
+			used[i] = true
+		}
+		matches = append(matches, i)
+	}
+
+	for i := range liveKeys {
+		if owned[i] && !used[i] {
+			leftover = append(leftover, i)
+		}
+	}
+
+	if insertAt < 0 {
+		insertAt = len(liveKeys)
+	}
+
+	return matches, leftover, insertAt
+}
+
+// sameRule compares two rules once marshalled
+func sameRule(a, b interface{}) bool {
+	x, errx := xml.Marshal(a)
+	y, erry := xml.Marshal(b)
+	return errx == nil && erry == nil && bytes.Equal(x, y)
+}
+
+// hrefOnly drops the name and type of a reference, which the gateway fills in
+func hrefOnly(ref *types.Reference) *types.Reference {
+	if ref == nil {
+		return nil
+	}
+	return &types.Reference{HREF: ref.HREF}
+}
+
+// reconcileRules changes config to match desired and returns the changes made
+func reconcileRules(config *types.GatewayFeatures, desired GatewayRules) ([]RuleChange, error) {
+
+	changes := reconcileFirewallRules(config, desired)
+	changes = append(changes, reconcileNatRules(config, desired)...)
+
+	lbchanges, err := reconcileLoadBalancer(config, desired)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(changes, lbchanges...), nil
+}
+
+// reconcileFirewallRules puts the desired firewall rules where the first
+// owned rule was in the rule order, or last
+func reconcileFirewallRules(config *types.GatewayFeatures, desired GatewayRules) []RuleChange {
+
+	if config.FirewallService == nil {
+		if len(desired.FirewallRules) == 0 {
+			return nil
+		}
+		config.FirewallService = &types.FirewallService{IsEnabled: true}
+	}
+	fw := config.FirewallService
+
+	var changes []RuleChange
+	owned := func(description string) bool {
+		return strings.HasPrefix(description, desired.Owner)
+	}
+
+	// The gateway fills in the ports and direction a rule leaves out
+	normalizeFirewall := func(rule types.FirewallRule) *types.FirewallRule {
+		rule.ID = ""
+		if rule.Port == 0 {
+			rule.Port = -1
+		}
+		if rule.SourcePort == 0 {
+			rule.SourcePort = -1
+		}
+		if rule.Direction == "" {
+			rule.Direction = "in"
+		}
+		return &rule
+	}
+
+	keys, own := make([]string, len(fw.FirewallRule)), make([]bool, len(fw.FirewallRule))
+	for i, rule := range fw.FirewallRule {
+		keys[i], own[i] = rule.Description, owned(rule.Description)
+	}
+	desiredKeys := make([]string, len(desired.FirewallRules))
+	for i, rule := range desired.FirewallRules {
+		desiredKeys[i] = rule.Description
+	}
+	matches, leftover, insertAt := matchOwned(keys, own, desiredKeys)
+
+	var managedfw []*types.FirewallRule
+	for i, d := range desired.FirewallRules {
+		rule := *d
+		rule.ID = ""
+		if m := matches[i]; m >= 0 {
+			// Unchanged rules stay as the gateway reported them
+			if sameRule(normalizeFirewall(*fw.FirewallRule[m]), normalizeFirewall(rule)) {
+				managedfw = append(managedfw, fw.FirewallRule[m])
+				continue
+			}
+			changes = append(changes, RuleChange{RuleUpdate, RuleKindFirewall, rule.Description})
+			rule.ID = fw.FirewallRule[m].ID
+		} else {
+			changes = append(changes, RuleChange{RuleAdd, RuleKindFirewall, rule.Description})
+		}
+		managedfw = append(managedfw, &rule)
+	}
+	for _, i := range leftover {
+		changes = append(changes, RuleChange{RuleDelete, RuleKindFirewall, keys[i]})
+	}
+
+	var newfw []*types.FirewallRule
+	for i, rule := range fw.FirewallRule {
+		if i == insertAt {
+			newfw = append(newfw, managedfw...)
+		}
+		if !own[i] {
+			newfw = append(newfw, rule)
+		}
+	}
+	if insertAt == len(fw.FirewallRule) {
+		newfw = append(newfw, managedfw...)
+	}
+	fw.FirewallRule = newfw
+
+	return changes
+}
+
+// reconcileNatRules puts the desired NAT rules where the first owned rule
+// was, or last
+func reconcileNatRules(config *types.GatewayFeatures, desired GatewayRules) []RuleChange {
+
+	if config.NatService == nil {
+		if len(desired.NatRules) == 0 {
+			return nil
+		}
+		config.NatService = &types.NatService{IsEnabled: true}
+	}
+	nat := config.NatService
+
+	var changes []RuleChange
+	owned := func(description string) bool {
+		return strings.HasPrefix(description, desired.Owner)
+	}
+
+	// The gateway fills in the interface name and type and capitalises the
+	// protocol
+	normalizeNat := func(rule types.NatRule) *types.NatRule {
+		rule.ID, rule.Xmlns = "", ""
+		if rule.GatewayNatRule != nil {
+			gnr := *rule.GatewayNatRule
+			gnr.Interface = hrefOnly(gnr.Interface)
+			gnr.Protocol = strings.ToLower(gnr.Protocol)
+			rule.GatewayNatRule = &gnr
+		}
+		return &rule
+	}
+
+	keys, own := make([]string, len(nat.NatRule)), make([]bool, len(nat.NatRule))
+	for i, rule := range nat.NatRule {
+		keys[i], own[i] = rule.Description, owned(rule.Description)
+	}
+	desiredKeys := make([]string, len(desired.NatRules))
+	for i, rule := range desired.NatRules {
+		desiredKeys[i] = rule.Description
+	}
+	matches, leftover, insertAt := matchOwned(keys, own, desiredKeys)
+
+	var managednat []*types.NatRule
+	for i, d := range desired.NatRules {
+		rule := *d
+		rule.ID = ""
+		if m := matches[i]; m >= 0 {
+			if sameRule(normalizeNat(*nat.NatRule[m]), normalizeNat(rule)) {
+				managednat = append(managednat, nat.NatRule[m])
+				continue
+			}
+			changes = append(changes, RuleChange{RuleUpdate, RuleKindNat, rule.Description})
+			rule.ID = nat.NatRule[m].ID
+		} else {
+			changes = append(changes, RuleChange{RuleAdd, RuleKindNat, rule.Description})
+		}
+		managednat = append(managednat, &rule)
+	}
+	for _, i := range leftover {
+		changes = append(changes, RuleChange{RuleDelete, RuleKindNat, keys[i]})
+	}
+
+	var newnat []*types.NatRule
+	for i, rule := range nat.NatRule {
+		if i == insertAt {
+			newnat = append(newnat, managednat...)
+		}
+		if !own[i] {
+			newnat = append(newnat, rule)
+		}
+	}
+	if insertAt == len(nat.NatRule) {
+		newnat = append(newnat, managednat...)
+	}
+	nat.NatRule = newnat
+
+	return changes
+}
+
+// reconcileLoadBalancer reconciles the load balancer pools and virtual
+// servers, which are matched by name
+func reconcileLoadBalancer(config *types.GatewayFeatures, desired GatewayRules) ([]RuleChange, error) {
+
+	if config.LoadBalancerService == nil {
+		if len(desired.LoadBalancerPools) == 0 && len(desired.LoadBalancerVirtualServers) == 0 {
+			return nil, nil
+		}
+		config.LoadBalancerService = &types.LoadBalancerService{}
+	}
+	lb := config.LoadBalancerService
+
+	var changes []RuleChange
+	owned := func(description string) bool {
+		return strings.HasPrefix(description, desired.Owner)
+	}
+
+	keys, own := make([]string, len(lb.Pool)), make([]bool, len(lb.Pool))
+	for i, pool := range lb.Pool {
+		keys[i], own[i] = pool.Name, owned(pool.Description)
+	}
+	desiredKeys := make([]string, len(desired.LoadBalancerPools))
+	for i, pool := range desired.LoadBalancerPools {
+		desiredKeys[i] = pool.Name
+	}
+	matches, leftover, _ := matchOwned(keys, own, desiredKeys)
+
+	// Owned pools keep their place, new ones go last
+	newpools := append([]*types.LoadBalancerPool(nil), lb.Pool...)
+	for i, d := range desired.LoadBalancerPools {
+		pool := *d
+		pool.ID, pool.Operational, pool.ErrorDetails = "", false, ""
+		if m := matches[i]; m >= 0 {
+			live := *lb.Pool[m]
+			live.ID, live.Operational, live.ErrorDetails = "", false, ""
+			if !sameRule(&live, &pool) {
+				changes = append(changes, RuleChange{RuleUpdate, RuleKindLoadBalancerPool, pool.Name})
+				pool.ID = lb.Pool[m].ID
+				newpools[m] = &pool
+			}
+		} else {
+			for k := range keys {
+				if keys[k] == pool.Name {
+					return nil, fmt.Errorf("load balancer pool %s already exists and isn't owned by %s", pool.Name, desired.Owner)
+				}
+			}
+			changes = append(changes, RuleChange{RuleAdd, RuleKindLoadBalancerPool, pool.Name})
+			newpools = append(newpools, &pool)
+		}
+	}
+	for _, i := range leftover {
+		changes = append(changes, RuleChange{RuleDelete, RuleKindLoadBalancerPool, keys[i]})
+		newpools[i] = nil
+	}
+	lb.Pool = nil
+	for _, pool := range newpools {
+		if pool != nil {
+			lb.Pool = append(lb.Pool, pool)
+		}
+	}
+
+	keys, own = make([]string, len(lb.VirtualServer)), make([]bool, len(lb.VirtualServer))
+	for i, vs := range lb.VirtualServer {
+		keys[i], own[i] = vs.Name, owned(vs.Description)
+	}
+	desiredKeys = make([]string, len(desired.LoadBalancerVirtualServers))
+	for i, vs := range desired.LoadBalancerVirtualServers {
+		desiredKeys[i] = vs.Name
+	}
+	matches, leftover, _ = matchOwned(keys, own, desiredKeys)
+
+	newservers := append([]*types.LoadBalancerVirtualServer(nil), lb.VirtualServer...)
+	for i, d := range desired.LoadBalancerVirtualServers {
+		vs := *d
+		if m := matches[i]; m >= 0 {
+			live := *lb.VirtualServer[m]
+			live.Interface = hrefOnly(live.Interface)
+			compared := vs
+			compared.Interface = hrefOnly(compared.Interface)
+			if !sameRule(&live, &compared) {
+				changes = append(changes, RuleChange{RuleUpdate, RuleKindLoadBalancerVirtualServer, vs.Name})
+				newservers[m] = &vs
+			}
+		} else {
+			for k := range keys {
+				if keys[k] == vs.Name {
+					return nil, fmt.Errorf("load balancer virtual server %s already exists and isn't owned by %s", vs.Name, desired.Owner)
+				}
+			}
+			changes = append(changes, RuleChange{RuleAdd, RuleKindLoadBalancerVirtualServer, vs.Name})
+			newservers = append(newservers, &vs)
+		}
+	}
+	for _, i := range leftover {
+		changes = append(changes, RuleChange{RuleDelete, RuleKindLoadBalancerVirtualServer, keys[i]})
+		newservers[i] = nil
+	}
+	lb.VirtualServer = nil
+	for _, vs := range newservers {
+		if vs != nil {
+			lb.VirtualServer = append(lb.VirtualServer, vs)
+		}
+	}
+
+	// Every virtual server, owned or not, needs its pool
+	pools := make(map[string]bool, len(lb.Pool))
+	for _, pool := range lb.Pool {
+		pools[pool.Name] = true
+	}
+	for _, vs := range lb.VirtualServer {
+		if !pools[vs.Pool] {
+			return nil, fmt.Errorf("load balancer virtual server %s uses missing pool %s", vs.Name, vs.Pool)
+		}
+	}
+
+	// Virtual servers only serve traffic on an enabled service
+	if len(desired.LoadBalancerVirtualServers) > 0 && !lb.IsEnabled {
+		lb.IsEnabled = true
+		changes = append(changes, RuleChange{RuleUpdate, RuleKindLoadBalancerService, ""})
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,213 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+func Test_ReconcileRules(t *testing.T) {
+	cc := new(callCounter)
+	state := newEdgeGatewayState(ownedEdgegatewayExample)
+
+	ctx, err := setupTestContext(authHandler(state.handler(cc)))
+	if assert.NoError(t, err) {
+
+		edge, err := ctx.VDC.FindEdgeGateway("M916272752-5793")
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+
+			uplink := &types.Reference{HREF: ctx.Server.URL + "/api/admin/network/6254f107-9876-4d03-986f-8bec7a4bcb3f"}
+
+			inet, err := edge.FindFirewallRuleByID("2")
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			desired := GatewayRules{
+				Owner: "tf:",
+				FirewallRules: []*types.FirewallRule{
+					inet,
+					{
+						Description:          "tf:web",
+						IsEnabled:            true,
+						Policy:               FirewallPolicyAllow,
+						Protocols:            &types.FirewallRuleProtocols{TCP: true},
+						DestinationPortRange: "80",
+						DestinationIP:        "23.92.225.51",
+						SourcePortRange:      "Any",
+						SourceIP:             "Any",
+					},
+				},
+				NatRules: []*types.NatRule{
+					NewDNATRule(uplink, NatProtocolTCP, "23.92.225.51", "2222", "192.168.109.8", "22", "tf:ssh"),
+				},
+				LoadBalancerPools: []*types.LoadBalancerPool{
+					{
+						Name:        "tf-web",
+						Description: "tf:web pool",
+						ServicePort: []*types.LBPoolServicePort{
+							{IsEnabled: true, Protocol: LBProtocolHTTP, Algorithm: LBAlgorithmRoundRobin, Port: "80"},
+						},
+						Member: []*types.LBPoolMember{{IPAddress: "192.168.109.8", Weight: "1"}},
+					},
+				},
+				LoadBalancerVirtualServers: []*types.LoadBalancerVirtualServer{
+					{
+						IsEnabled:   true,
+						Name:        "tf-web-vs",
+						Description: "tf:web virtual server",
+						Interface:   uplink,
+						IPAddress:   "23.92.225.52",
+						Pool:        "tf-web",
+					},
+				},
+			}
+
+			plan, err := edge.PlanRules(desired)
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Equal(t, []RuleChange{
+					{RuleAdd, RuleKindFirewall, "tf:web"},
+					{RuleDelete, RuleKindFirewall, "tf:old"},
+					{RuleUpdate, RuleKindNat, "tf:ssh"},
+					{RuleAdd, RuleKindLoadBalancerPool, "tf-web"},
+					{RuleAdd, RuleKindLoadBalancerVirtualServer, "tf-web-vs"},
+					{RuleUpdate, RuleKindLoadBalancerService, ""},
+				}, plan)
+				// a dry run leaves the gateway alone
+				assert.Len(t, state.config().FirewallService.FirewallRule, 4)
+			}
+
+			changes, _, err := edge.ReconcileRules(desired)
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				assert.Equal(t, plan, changes)
+
+				config := state.config()
+				assert.Equal(t, []string{"1", "2", "", "4"}, firewallRuleIDs(config))
+				assert.Equal(t, "tf:web", config.FirewallService.FirewallRule[2].Description)
+				if assert.Len(t, config.NatService.NatRule, 4) {
+					assert.Equal(t, "65537", config.NatService.NatRule[0].ID)
+					assert.Equal(t, "2222", config.NatService.NatRule[0].GatewayNatRule.OriginalPort)
+				}
+				assert.True(t, config.LoadBalancerService.IsEnabled)
+				assert.Len(t, config.LoadBalancerService.Pool, 1)
+				assert.Len(t, config.LoadBalancerService.VirtualServer, 1)
+			}
+
+			// nothing left to do
+			changes, task, err := edge.ReconcileRules(desired)
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Len(t, changes, 0)
+				assert.NoError(t, task.WaitTaskCompletion())
+				assert.Equal(t, 0, cc.Pop())
+			}
+
+			desired.LoadBalancerPools = nil
+			_, _, err = edge.ReconcileRules(desired)
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+
+			desired.FirewallRules[1].Description = "web"
+			_, err = edge.PlanRules(desired)
+			assert.Error(t, err)
+
+			_, err = edge.PlanRules(GatewayRules{})
+			assert.Error(t, err)
+			assert.Equal(t, 0, cc.Pop())
+
+			changes, _, err = edge.ReconcileRules(GatewayRules{Owner: "tf:"})
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				assert.Len(t, changes, 5)
+
+				config := state.config()
+				assert.Equal(t, []string{"1", "4"}, firewallRuleIDs(config))
+				assert.Len(t, config.NatService.NatRule, 3)
+				assert.Len(t, config.LoadBalancerService.Pool, 0)
+				assert.Len(t, config.LoadBalancerService.VirtualServer, 0)
+			}
+		}
+	}
+}
+
+func Test_ReconcileRulesUnchanged(t *testing.T) {
+	cc := new(callCounter)
+	state := newEdgeGatewayState(ownedEdgegatewayExample)
+
+	ctx, err := setupTestContext(authHandler(state.handler(cc)))
+	if assert.NoError(t, err) {
+
+		edge, err := ctx.VDC.FindEdgeGateway("M916272752-5793")
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+
+			uplink := &types.Reference{HREF: ctx.Server.URL + "/api/admin/network/6254f107-9876-4d03-986f-8bec7a4bcb3f"}
+
+			// the owned rules as written by their owner, leaving out what
+			// the gateway fills in
+			desired := GatewayRules{
+				Owner: "tf:",
+				FirewallRules: []*types.FirewallRule{
+					{
+						Description:          "tf:inet prd-001",
+						IsEnabled:            true,
+						Policy:               FirewallPolicyAllow,
+						Protocols:            &types.FirewallRuleProtocols{Any: true},
+						DestinationPortRange: "Any",
+						DestinationIP:        "Any",
+						SourcePortRange:      "Any",
+						SourceIP:             "192.168.109.8",
+					},
+					{
+						Description:          "tf:old",
+						IsEnabled:            true,
+						Policy:               FirewallPolicyAllow,
+						Protocols:            &types.FirewallRuleProtocols{Any: true},
+						DestinationPortRange: "Any",
+						DestinationIP:        "23.92.224.255",
+						SourcePortRange:      "Any",
+						SourceIP:             "Any",
+					},
+				},
+				NatRules: []*types.NatRule{
+					NewDNATRule(uplink, NatProtocolTCP, "23.92.225.51", "999", "192.168.109.8", "22", "tf:ssh"),
+				},
+			}
+
+			plan, err := edge.PlanRules(desired)
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Len(t, plan, 0)
+			}
+
+			changes, task, err := edge.ReconcileRules(desired)
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Len(t, changes, 0)
+				assert.NoError(t, task.WaitTaskCompletion())
+			}
+		}
+	}
+}
+
+// ownedEdgegatewayExample is edgegatewayExample where a firewall rule, an
+// obsolete firewall rule and a NAT rule are owned by "tf:"
+var ownedEdgegatewayExample = strings.NewReplacer(
+	`<Description>inet prd-001</Description>`, `<Description>tf:inet prd-001</Description>`,
+	`<Id>3</Id>
+                    <IsEnabled>true</IsEnabled>
+                    <MatchOnTranslate>false</MatchOnTranslate>
+                    <Description>suppah megah rulez creati0nz</Description>`, `<Id>3</Id>
+                    <IsEnabled>true</IsEnabled>
+                    <MatchOnTranslate>false</MatchOnTranslate>
+                    <Description>tf:old</Description>`,
+	`<NatRule>
+                    <RuleType>DNAT</RuleType>
+                    <IsEnabled>true</IsEnabled>
+                    <Id>65537</Id>`, `<NatRule>
+                    <Description>tf:ssh</Description>
+                    <RuleType>DNAT</RuleType>
+                    <IsEnabled>true</IsEnabled>
+                    <Id>65537</Id>`,
+).Replace(edgegatewayExample)
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"fmt"
+	"net/url"
+
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// Snapshots are supported on both vApps and VMs through the same set of
+// actions, the helpers below take the HREF of the entity to act on.
+
+func createSnapshot(c Client, href, name, description string, memory, quiesce bool) (Task, error) {
+
+	s, err := url.ParseRequestURI(href)
+	if err != nil {
+		return Task{}, fmt.Errorf("error parsing entity href: %s", err)
+	}
+	s.Path += "/action/createSnapshot"
+
+	params := &types.CreateSnapshotParams{
+		Xmlns:       types.NsVCloud,
+		Name:        name,
+		Memory:      memory,
+		Quiesce:     quiesce,
+		Description: description,
+	}
+
+	task, err := executeTaskRequest(c, types.HTTPPost, s, types.MimeCreateSnapshotParams, params)
+	if err != nil {
+		return Task{}, fmt.Errorf("error creating snapshot: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
+
+func revertToCurrentSnapshot(c Client, href string) (Task, error) {
+
+	s, err := url.ParseRequestURI(href)
+	if err != nil {
+		return Task{}, fmt.Errorf("error parsing entity href: %s", err)
+	}
+	s.Path += "/action/revertToCurrentSnapshot"
+
+	task, err := executeTaskRequest(c, types.HTTPPost, s, "", nil)
+	if err != nil {
+		return Task{}, fmt.Errorf("error reverting to current snapshot: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
+
+func removeAllSnapshots(c Client, href string) (Task, error) {
+
+	s, err := url.ParseRequestURI(href)
+	if err != nil {
+		return Task{}, fmt.Errorf("error parsing entity href: %s", err)
+	}
+	s.Path += "/action/removeAllSnapshots"
+
+	task, err := executeTaskRequest(c, types.HTTPPost, s, "", nil)
+	if err != nil {
+		return Task{}, fmt.Errorf("error removing snapshots: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
+
+func getSnapshotSection(c Client, href string) (*types.SnapshotSection, error) {
+
+	s, err := url.ParseRequestURI(href)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing entity href: %s", err)
+	}
+	s.Path += "/snapshotSection"
+
+	req := c.NewRequest(map[string]string{}, types.HTTPGet, s, nil)
+
+	resp, err := checkResp(c.DoHTTP(req))
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving snapshot section: %s", err)
+	}
+
+	section := new(types.SnapshotSection)
+
+	if err = decodeBody(resp, section); err != nil {
+		return nil, fmt.Errorf("error decoding snapshot section response: %s", err)
+	}
+
+	// The request was successful
+	return section, nil
+}
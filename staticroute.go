@@ -0,0 +1,157 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"fmt"
+	"net"
+
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// The interface kinds a static route can be bound to
+const (
+	StaticRouteInterfaceInternal = "Internal"
+	StaticRouteInterfaceExternal = "External"
+)
+
+func validateStaticRoute(route *types.StaticRoute) error {
+
+	if route == nil || route.Name == "" {
+		return fmt.Errorf("static route needs a name")
+	}
+
+	if _, _, err := net.ParseCIDR(route.Network); err != nil {
+		return fmt.Errorf("static route %s: invalid network %s", route.Name, route.Network)
+	}
+
+	if net.ParseIP(route.NextHopIP) == nil {
+		return fmt.Errorf("static route %s: invalid next hop %s", route.Name, route.NextHopIP)
+	}
+
+	switch route.Interface {
+	case "", StaticRouteInterfaceInternal, StaticRouteInterfaceExternal:
+	default:
+		return fmt.Errorf("static route %s: unsupported interface %s", route.Name, route.Interface)
+	}
+
+	if route.Interface == "" && (route.GatewayInterface == nil || route.GatewayInterface.HREF == "") {
+		return fmt.Errorf("static route %s needs an interface", route.Name)
+	}
+
+	return nil
+}
+
+// configStaticRoutingService returns the static routing service of config,
+// adding an empty one when the gateway has none
+func configStaticRoutingService(config *types.GatewayFeatures) *types.StaticRoutingService {
+	if config.StaticRoutingService == nil {
+		config.StaticRoutingService = &types.StaticRoutingService{}
+	}
+	return config.StaticRoutingService
+}
+
+// StaticRoutes refreshes this gateway and returns its static routes
+func (e *EdgeGateway) StaticRoutes() ([]*types.StaticRoute, error) {
+
+	if err := e.Refresh(); err != nil {
+		return nil, err
+	}
+
+	if e.EdgeGateway.Configuration == nil || e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration == nil {
+		return nil, fmt.Errorf("edge gateway has no service configuration")
+	}
+
+	routing := e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration.StaticRoutingService
+	if routing == nil {
+		return nil, nil
+	}
+
+	return routing.StaticRoute, nil
+}
+
+// FindStaticRoute finds a static route by name in the last known
+// configuration of this gateway
+func (e *EdgeGateway) FindStaticRoute(name string) (*types.StaticRoute, error) {
+
+	if e.EdgeGateway.Configuration == nil || e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration == nil {
+		return nil, fmt.Errorf("edge gateway has no service configuration")
+	}
+
+	if routing := e.EdgeGateway.Configuration.EdgeGatewayServiceConfiguration.StaticRoutingService; routing != nil {
+		for _, route := range routing.StaticRoute {
+			if route.Name == name {
+				return route, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("can't find static route: %s", name)
+}
+
+// CreateStaticRoute adds a static route to this gateway, enabling the static
+// routing service when needed
+func (e *EdgeGateway) CreateStaticRoute(route *types.StaticRoute) (Task, error) {
+
+	if err := validateStaticRoute(route); err != nil {
+		return Task{}, err
+	}
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		routing := configStaticRoutingService(config)
+
+		for _, r := range routing.StaticRoute {
+			if r.Name == route.Name {
+				return fmt.Errorf("static route %s already exists", route.Name)
+			}
+		}
+
+		routing.IsEnabled = true
+		routing.StaticRoute = append(routing.StaticRoute, route)
+
+		return nil
+	})
+}
+
+// UpdateStaticRoute replaces the static route with the same name as route
+func (e *EdgeGateway) UpdateStaticRoute(route *types.StaticRoute) (Task, error) {
+
+	if err := validateStaticRoute(route); err != nil {
+		return Task{}, err
+	}
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		routing := configStaticRoutingService(config)
+
+		for k, r := range routing.StaticRoute {
+			if r.Name == route.Name {
+				routing.StaticRoute[k] = route
+				return nil
+			}
+		}
+
+		return fmt.Errorf("can't find static route: %s", route.Name)
+	})
+}
+
+// DeleteStaticRoute removes the static route with the given name
+func (e *EdgeGateway) DeleteStaticRoute(name string) (Task, error) {
+
+	return e.ModifyServices(func(config *types.GatewayFeatures) error {
+
+		routing := configStaticRoutingService(config)
+
+		for k, r := range routing.StaticRoute {
+			if r.Name == name {
+				routing.StaticRoute = append(routing.StaticRoute[:k], routing.StaticRoute[k+1:]...)
+				return nil
+			}
+		}
+
+		return fmt.Errorf("can't find static route: %s", name)
+	})
+}
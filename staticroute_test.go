@@ -0,0 +1,88 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+func Test_StaticRoutes(t *testing.T) {
+	cc := new(callCounter)
+	config := new(types.GatewayFeatures)
+
+	ctx, err := setupTestContext(authHandler(edgeServicesHandler(routedEdgegatewayExample, config, cc)))
+	if assert.NoError(t, err) {
+
+		edge, err := ctx.VDC.FindEdgeGateway("M916272752-5793")
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+
+			routes, err := edge.StaticRoutes()
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) && assert.Len(t, routes, 1) {
+				assert.Equal(t, "branch", routes[0].Name)
+				assert.Equal(t, "10.20.0.0/16", routes[0].Network)
+			}
+
+			route := &types.StaticRoute{
+				Name:      "lab",
+				Network:   "172.16.0.0/12",
+				NextHopIP: "192.168.108.254",
+				Interface: StaticRouteInterfaceInternal,
+			}
+
+			_, err = edge.CreateStaticRoute(route)
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) && assert.Len(t, config.StaticRoutingService.StaticRoute, 2) {
+				assert.True(t, config.StaticRoutingService.IsEnabled)
+				assert.Equal(t, "Internal", config.StaticRoutingService.StaticRoute[1].Interface)
+			}
+
+			route.Name = "branch"
+			_, err = edge.CreateStaticRoute(route)
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+
+			route.Network = "172.16.0.0"
+			_, err = edge.UpdateStaticRoute(route)
+			assert.Error(t, err)
+
+			route.Network = "172.16.0.0/12"
+			route.Interface = ""
+			_, err = edge.UpdateStaticRoute(route)
+			assert.Error(t, err)
+			assert.Equal(t, 0, cc.Pop())
+
+			route.GatewayInterface = &types.Reference{HREF: ctx.Server.URL + "/api/admin/network/32e903a4-c726-497f-9ec7-e4a43b58bdbc"}
+			_, err = edge.UpdateStaticRoute(route)
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) && assert.Len(t, config.StaticRoutingService.StaticRoute, 1) {
+				assert.Equal(t, "172.16.0.0/12", config.StaticRoutingService.StaticRoute[0].Network)
+				assert.Equal(t, route.GatewayInterface.HREF, config.StaticRoutingService.StaticRoute[0].GatewayInterface.HREF)
+			}
+
+			_, err = edge.DeleteStaticRoute("branch")
+			if assert.NoError(t, err) && assert.Equal(t, 5, cc.Pop()) {
+				assert.Len(t, config.StaticRoutingService.StaticRoute, 0)
+			}
+
+			_, err = edge.DeleteStaticRoute("missing")
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+		}
+	}
+}
+
+// routedEdgegatewayExample is edgegatewayExample with a static route to a
+// branch network
+var routedEdgegatewayExample = strings.Replace(edgegatewayExample, `<StaticRoutingService>
+                <IsEnabled>false</IsEnabled>`, `<StaticRoutingService>
+                <IsEnabled>true</IsEnabled>
+                <StaticRoute>
+                    <Name>branch</Name>
+                    <Network>10.20.0.0/16</Network>
+                    <NextHopIp>23.92.224.10</NextHopIp>
+                    <Interface>External</Interface>
+                </StaticRoute>`, 1)
@@ -26,6 +26,15 @@ func NewTask(c Client) *Task {
 	}
 }
 
+// completedTask returns a task that already finished successfully, for
+// operations that had nothing to do on the server
+func completedTask(c Client) Task {
+	return Task{
+		Task: &types.Task{Status: "success"},
+		c:    c,
+	}
+}
+
 // Refresh this task
 func (t *Task) Refresh() error {
 
@@ -61,6 +70,11 @@ func (t *Task) WaitTaskCompletion() error {
 		return fmt.Errorf("cannot refresh, Object is empty")
 	}
 
+	// A completed task that never went to the server has nothing to refresh
+	if t.Task.HREF == "" && t.Task.Status == "success" {
+		return nil
+	}
+
 	for {
 		err := t.Refresh()
 		if err != nil {
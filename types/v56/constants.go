@@ -35,6 +35,16 @@ const (
 	MimeCatalog = "application/vnd.vmware.vcloud.catalog+xml"
 	// MimeCatalogItem mime for catalog item
 	MimeCatalogItem = "application/vnd.vmware.vcloud.catalogItem+xml"
+	// MimeCopyOrMoveCatalogItemParams mime for copy or move catalog item params
+	MimeCopyOrMoveCatalogItemParams = "application/vnd.vmware.vcloud.copyOrMoveCatalogItemParams+xml"
+	// MimeMetadata mime for metadata
+	MimeMetadata = "application/vnd.vmware.vcloud.metadata+xml"
+	// MimeAdminCatalog mime for the admin view of a catalog
+	MimeAdminCatalog = "application/vnd.vmware.admin.catalog+xml"
+	// MimePublishCatalogParams mime for publish catalog params
+	MimePublishCatalogParams = "application/vnd.vmware.admin.publishCatalogParams+xml"
+	// MimeControlAccess mime for control access params
+	MimeControlAccess = "application/vnd.vmware.vcloud.controlAccess+xml"
 	// MimeVDC mime for a VDC
 	MimeVDC = "application/vnd.vmware.vcloud.vdc+xml"
 	// MimeVAppTemplate mime for a vapp template
@@ -59,6 +69,113 @@ const (
 	MimeError = "application/vnd.vmware.vcloud.error+xml"
 	// MimeNetwork mime for a network
 	MimeNetwork = "application/vnd.vmware.vcloud.network+xml"
+	// MimeOrgVdcNetwork mime for an org vdc network
+	MimeOrgVdcNetwork = "application/vnd.vmware.vcloud.orgVdcNetwork+xml"
+	// MimeAllocatedNetworkAddress mime for the allocated addresses of a network
+	MimeAllocatedNetworkAddress = "application/vnd.vmware.vcloud.allocatedNetworkAddress+xml"
+	// MimeMedia mime for a media object
+	MimeMedia = "application/vnd.vmware.vcloud.media+xml"
+	// MimeMediaInsertOrEjectParams mime for media insert or eject params
+	MimeMediaInsertOrEjectParams = "application/vnd.vmware.vcloud.mediaInsertOrEjectParams+xml"
+	// MimeDisk mime for an independent disk
+	MimeDisk = "application/vnd.vmware.vcloud.disk+xml"
+	// MimeDiskCreateParams mime for disk create params
+	MimeDiskCreateParams = "application/vnd.vmware.vcloud.diskCreateParams+xml"
+	// MimeDiskAttachOrDetachParams mime for disk attach or detach params
+	MimeDiskAttachOrDetachParams = "application/vnd.vmware.vcloud.diskAttachOrDetachParams+xml"
+	// MimeVM mime for a VM
+	MimeVM = "application/vnd.vmware.vcloud.vm+xml"
+	// MimeDeployVAppParams mime for deploy vApp params
+	MimeDeployVAppParams = "application/vnd.vmware.vcloud.deployVAppParams+xml"
+	// MimeUndeployVAppParams mime for undeploy vApp params
+	MimeUndeployVAppParams = "application/vnd.vmware.vcloud.undeployVAppParams+xml"
+	// MimeEdgeGateway mime for an edge gateway
+	MimeEdgeGateway = "application/vnd.vmware.admin.edgeGateway+xml"
+	// MimeEdgeGatewayServiceConfiguration mime for an edge gateway service configuration
+	MimeEdgeGatewayServiceConfiguration = "application/vnd.vmware.admin.edgeGatewayServiceConfiguration+xml"
+	// MimeCloneVAppParams mime for clone vApp params
+	MimeCloneVAppParams = "application/vnd.vmware.vcloud.cloneVAppParams+xml"
+	// MimeCaptureVAppParams mime for capture vApp params
+	MimeCaptureVAppParams = "application/vnd.vmware.vcloud.captureVAppParams+xml"
+	// MimeUploadVAppTemplateParams mime for upload vApp template params
+	MimeUploadVAppTemplateParams = "application/vnd.vmware.vcloud.uploadVAppTemplateParams+xml"
+	// MimeLeaseSettingsSection mime for a lease settings section
+	MimeLeaseSettingsSection = "application/vnd.vmware.vcloud.leaseSettingsSection+xml"
+	// MimeCustomizationSection mime for a vApp template customization section
+	MimeCustomizationSection = "application/vnd.vmware.vcloud.customizationSection+xml"
+	// MimeNetworkConfigSection mime for a network config section
+	MimeNetworkConfigSection = "application/vnd.vmware.vcloud.networkConfigSection+xml"
+	// MimeCreateSnapshotParams mime for create snapshot params
+	MimeCreateSnapshotParams = "application/vnd.vmware.vcloud.createSnapshotParams+xml"
+	// MimeSnapshotSection mime for a snapshot section
+	MimeSnapshotSection = "application/vnd.vmware.vcloud.snapshotSection+xml"
+)
+
+const (
+	// UndeployPowerActionPowerOff powers off the virtual machines
+	UndeployPowerActionPowerOff = "powerOff"
+	// UndeployPowerActionSuspend suspends the virtual machines
+	UndeployPowerActionSuspend = "suspend"
+	// UndeployPowerActionShutdown shuts down the virtual machines
+	UndeployPowerActionShutdown = "shutdown"
+	// UndeployPowerActionForce attempts to power off the virtual machines, ignoring failures
+	UndeployPowerActionForce = "force"
+	// UndeployPowerActionDefault uses the actions, order and delay of the startup section
+	UndeployPowerActionDefault = "default"
+)
+
+const (
+	// DiskBusTypeIDE the disk is attached to an IDE controller
+	DiskBusTypeIDE = "5"
+	// DiskBusTypeSCSI the disk is attached to a SCSI controller
+	DiskBusTypeSCSI = "6"
+	// DiskBusSubTypeIDE IDE controller
+	DiskBusSubTypeIDE = "ide"
+	// DiskBusSubTypeBusLogic BusLogic parallel SCSI controller
+	DiskBusSubTypeBusLogic = "buslogic"
+	// DiskBusSubTypeLsiLogic LSI Logic parallel SCSI controller
+	DiskBusSubTypeLsiLogic = "lsilogic"
+	// DiskBusSubTypeLsiLogicSAS LSI Logic SAS controller
+	DiskBusSubTypeLsiLogicSAS = "lsilogicsas"
+	// DiskBusSubTypeParaVirtual VMware paravirtual SCSI controller
+	DiskBusSubTypeParaVirtual = "VirtualSCSI"
+)
+
+const (
+	// ResourceTypeProcessor virtual CPUs
+	ResourceTypeProcessor = 3
+	// ResourceTypeMemory memory
+	ResourceTypeMemory = 4
+	// ResourceTypeIDEController IDE controller
+	ResourceTypeIDEController = 5
+	// ResourceTypeSCSIController SCSI controller
+	ResourceTypeSCSIController = 6
+	// ResourceTypeEthernetAdapter network adapter
+	ResourceTypeEthernetAdapter = 10
+	// ResourceTypeFloppyDrive floppy drive
+	ResourceTypeFloppyDrive = 14
+	// ResourceTypeCDDrive CD/DVD drive
+	ResourceTypeCDDrive = 15
+	// ResourceTypeDisk hard disk
+	ResourceTypeDisk = 17
+)
+
+const (
+	// AccessLevelReadOnly allows reading the resource
+	AccessLevelReadOnly = "ReadOnly"
+	// AccessLevelChange allows reading and changing the resource
+	AccessLevelChange = "Change"
+	// AccessLevelFullControl allows reading, changing and deleting the resource
+	AccessLevelFullControl = "FullControl"
+)
+
+const (
+	// FenceModeIsolated the network is not connected to any other network
+	FenceModeIsolated = "isolated"
+	// FenceModeBridged the network is connected directly to its parent network
+	FenceModeBridged = "bridged"
+	// FenceModeNAT the network is connected to its parent network through NAT and routing
+	FenceModeNAT = "natRouted"
 )
 
 const (
@@ -6,6 +6,7 @@ package types
 
 import (
 	"encoding/xml"
+	"strconv"
 )
 
 // Maps status Attribute Values for VAppTemplate, VApp, Vm, and Media Objects
@@ -33,6 +34,42 @@ var VAppStatuses = map[int]string{
 	19: "VAPP_PARTIALLY_DEPLOYED",
 }
 
+// VAppStatus is the status attribute value for VAppTemplate, VApp, Vm, and Media Objects
+type VAppStatus int
+
+// Status attribute values for VAppTemplate, VApp, Vm, and Media Objects
+const (
+	VAppStatusFailedCreation        VAppStatus = -1
+	VAppStatusUnresolved            VAppStatus = 0
+	VAppStatusResolved              VAppStatus = 1
+	VAppStatusDeployed              VAppStatus = 2
+	VAppStatusSuspended             VAppStatus = 3
+	VAppStatusPoweredOn             VAppStatus = 4
+	VAppStatusWaitingForInput       VAppStatus = 5
+	VAppStatusUnknown               VAppStatus = 6
+	VAppStatusUnrecognized          VAppStatus = 7
+	VAppStatusPoweredOff            VAppStatus = 8
+	VAppStatusInconsistentState     VAppStatus = 9
+	VAppStatusMixed                 VAppStatus = 10
+	VAppStatusDescriptorPending     VAppStatus = 11
+	VAppStatusCopyingContents       VAppStatus = 12
+	VAppStatusDiskContentsPending   VAppStatus = 13
+	VAppStatusQuarantined           VAppStatus = 14
+	VAppStatusQuarantineExpired     VAppStatus = 15
+	VAppStatusRejected              VAppStatus = 16
+	VAppStatusTransferTimeout       VAppStatus = 17
+	VAppStatusVAppUndeployed        VAppStatus = 18
+	VAppStatusVAppPartiallyDeployed VAppStatus = 19
+)
+
+// String returns the name of the status as listed in VAppStatuses
+func (s VAppStatus) String() string {
+	if name, ok := VAppStatuses[int(s)]; ok {
+		return name
+	}
+	return "VAppStatus(" + strconv.Itoa(int(s)) + ")"
+}
+
 // Maps status Attribute Values for VDC Objects
 var VDCStatuses = map[int]string{
 	-1: "FAILED_CREATION",
@@ -59,6 +96,9 @@ type DefaultStorageProfileSection struct {
 // Description: Represents a vApp template customization settings.
 // Since: 1.0
 type CustomizationSection struct {
+	// Extends OVF Section_Type
+	Ovf   string `xml:"xmlns:ovf,attr,omitempty"`
+	Xmlns string `xml:"xmlns,attr,omitempty"`
 	// FIXME: OVF Section needs to be laid down correctly
 	Info string `xml:"ovf:Info"`
 	//
@@ -75,13 +115,19 @@ type CustomizationSection struct {
 // Description: Represents vApp lease settings.
 // Since: 0.9
 type LeaseSettingsSection struct {
+	// Extends OVF Section_Type
+	Ovf   string `xml:"xmlns:ovf,attr,omitempty"`
+	Xmlns string `xml:"xmlns,attr,omitempty"`
+	// FIXME: Fix the OVF section
+	Info string `xml:"ovf:Info"`
+	//
 	HREF                      string `xml:"href,attr,omitempty"`
 	Type                      string `xml:"type,attr,omitempty"`
-	DeploymentLeaseExpiration string `xml:"DeploymentLeaseExpiration,omitempty"`
-	DeploymentLeaseInSeconds  int    `xml:"DeploymentLeaseInSeconds,omitempty"`
+	DeploymentLeaseExpiration string `xml:"DeploymentLeaseExpiration,omitempty"` // Read-only expiration date and time of the runtime lease.
+	DeploymentLeaseInSeconds  *int   `xml:"DeploymentLeaseInSeconds,omitempty"`  // Runtime lease in seconds. A value of 0 means the lease never expires, nil leaves it out.
 	Link                      *Link  `xml:"Link,omitempty"`
-	StorageLeaseExpiration    string `xml:"StorageLeaseExpiration,omitempty"`
-	StorageLeaseInSeconds     int    `xml:"StorageLeaseInSeconds,omitempty"`
+	StorageLeaseExpiration    string `xml:"StorageLeaseExpiration,omitempty"` // Read-only expiration date and time of the storage lease.
+	StorageLeaseInSeconds     *int   `xml:"StorageLeaseInSeconds,omitempty"`  // Storage lease in seconds. A value of 0 means the lease never expires, nil leaves it out.
 }
 
 // IPRange represents a range of IP addresses, start and end inclusive.
@@ -132,7 +178,32 @@ type NetworkFeatures struct {
 // Description: A list of IP addresses.
 // Since: 0.9
 type IPAddresses struct {
-	IPAddress string `xml:"IpAddress,omitempty"` // An IP address.
+	IPAddress []string `xml:"IpAddress,omitempty"` // An IP address.
+}
+
+// AllocatedIPAddresses a list of IP addresses allocated in a network
+// Type: AllocatedIpAddressesType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: A list of IP addresses allocated in a network.
+// Since: 5.1
+type AllocatedIPAddresses struct {
+	XMLName   xml.Name              `xml:"AllocatedIpAddresses"`
+	HREF      string                `xml:"href,attr,omitempty"`
+	Type      string                `xml:"type,attr,omitempty"`
+	Link      []Link                `xml:"Link,omitempty"`
+	IPAddress []*AllocatedIPAddress `xml:"IpAddress,omitempty"` // An allocated IP address.
+}
+
+// AllocatedIPAddress an IP address allocated in a network
+// Type: AllocatedIpAddressType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: Specifies an allocated IP address and the entity it is allocated to.
+// Since: 5.1
+type AllocatedIPAddress struct {
+	AllocationType string `xml:"allocationType,attr,omitempty"` // How the address got allocated: vmAllocated, vsmAllocated or natRouted.
+	IsDeployed     bool   `xml:"isDeployed,attr"`               // True if the entity owning the address is deployed.
+	IPAddress      string `xml:"ipAddress,attr"`                // The IP address.
+	Link           []Link `xml:"Link,omitempty"`                // Links to the entity owning the address and its parent.
 }
 
 // IPRanges representsa list of IP ranges.
@@ -172,8 +243,8 @@ type SubAllocations struct {
 	HREF string `xml:"href,attr,omitempty"` // The URI of the entity.
 	Type string `xml:"type,attr,omitempty"` // The MIME type of the entity.
 	// Elements
-	Link          LinkList        `xml:"Link,omitempty"`          // A reference to an entity or operation associated with this object.
-	SubAllocation *SubAllocation `xml:"SubAllocation,omitempty"` // IP Range sub allocated to a edge gateway.
+	Link          LinkList         `xml:"Link,omitempty"`          // A reference to an entity or operation associated with this object.
+	SubAllocation []*SubAllocation `xml:"SubAllocation,omitempty"` // IP Range sub allocated to a edge gateway.
 }
 
 // SubAllocation IP range sub allocated to an edge gateway.
@@ -192,7 +263,7 @@ type SubAllocation struct {
 // Description: Represents a list of IP scopes.
 // Since: 5.1
 type IPScopes struct {
-	IPScope IPScope `xml:"IpScope"` // IP scope.
+	IPScope []*IPScope `xml:"IpScope"` // IP scope.
 }
 
 // NetworkConfiguration the configuration applied to a network. This is an abstract base type. The concrete types include thos for vApp and Organization wide networks.
@@ -202,11 +273,11 @@ type IPScopes struct {
 // Since: 0.9
 type NetworkConfiguration struct {
 	BackwardCompatibilityMode      bool             `xml:"BackwardCompatibilityMode"`
-	Features                       *NetworkFeatures `xml:"Features,omitempty"`
+	IPScopes                       *IPScopes        `xml:"IpScopes,omitempty"`
 	ParentNetwork                  *Reference       `xml:"ParentNetwork,omitempty"`
 	FenceMode                      string           `xml:"FenceMode"`
-	IPScopes                       *IPScopes        `xml:"IpScopes,omitempty"`
 	RetainNetInfoAcrossDeployments bool             `xml:"RetainNetInfoAcrossDeployments"`
+	Features                       *NetworkFeatures `xml:"Features,omitempty"`
 	// TODO: Not Implemented
 	// RouterInfo                     RouterInfo           `xml:"RouterInfo,omitempty"`
 	// SyslogServerSettings           SyslogServerSettings `xml:"SyslogServerSettings,omitempty"`
@@ -222,10 +293,10 @@ type VAppNetworkConfiguration struct {
 	Type        string `xml:"type,attr,omitempty"`
 	NetworkName string `xml:"networkName,attr"`
 
-	Configuration *NetworkConfiguration `xml:"Configuration"`
+	Link          []Link                `xml:"Link,omitempty"`
 	Description   string                `xml:"Description,omitempty"`
+	Configuration *NetworkConfiguration `xml:"Configuration"`
 	IsDeployed    bool                  `xml:"IsDeployed"`
-	Link          *Link                 `xml:"Link,omitempty"`
 }
 
 // NetworkConfigSection is container for vApp networks.
@@ -235,13 +306,15 @@ type VAppNetworkConfiguration struct {
 // Since: 0.9
 type NetworkConfigSection struct {
 	// Extends OVF Section_Type
+	Ovf   string `xml:"xmlns:ovf,attr,omitempty"`
+	Xmlns string `xml:"xmlns,attr,omitempty"`
 	// FIXME: Fix the OVF section
 	Info string `xml:"ovf:Info"`
 	//
-	HREF          string                    `xml:"href,attr,omitempty"`
-	Type          string                    `xml:"type,attr,omitempty"`
-	Link          *Link                     `xml:"Link,omitempty"`
-	NetworkConfig *VAppNetworkConfiguration `xml:"NetworkConfig,omitempty"`
+	HREF          string                      `xml:"href,attr,omitempty"`
+	Type          string                      `xml:"type,attr,omitempty"`
+	Link          *Link                       `xml:"Link,omitempty"`
+	NetworkConfig []*VAppNetworkConfiguration `xml:"NetworkConfig,omitempty"`
 }
 
 // NetworkConnection represents a network connection in the virtual machine.
@@ -270,11 +343,11 @@ type NetworkConnectionSection struct {
 	// FIXME: Fix the OVF section
 	Info string `xml:"ovf:Info"`
 	//
-	HREF                          string             `xml:"href,attr,omitempty"`
-	Type                          string             `xml:"type,attr,omitempty"`
-	Link                          *Link              `xml:"Link,omitempty"`
-	PrimaryNetworkConnectionIndex int                `xml:"PrimaryNetworkConnectionIndex"`
-	NetworkConnection             *NetworkConnection `xml:"NetworkConnection,omitempty"`
+	HREF                          string               `xml:"href,attr,omitempty"`
+	Type                          string               `xml:"type,attr,omitempty"`
+	Link                          *Link                `xml:"Link,omitempty"`
+	PrimaryNetworkConnectionIndex int                  `xml:"PrimaryNetworkConnectionIndex"`
+	NetworkConnection             []*NetworkConnection `xml:"NetworkConnection,omitempty"`
 }
 
 // InstantiationParams is a container for ovf:Section_Type elements that specify vApp configuration on instantiate, compose, or recompose.
@@ -300,19 +373,20 @@ type InstantiationParams struct {
 // Since: 5.1
 type OrgVDCNetwork struct {
 	XMLName       xml.Name              `xml:"OrgVdcNetwork"`
+	Xmlns         string                `xml:"xmlns,attr,omitempty"`
 	HREF          string                `xml:"href,attr,omitempty"`
 	Type          string                `xml:"type,attr,omitempty"`
 	ID            string                `xml:"id,attr,omitempty"`
 	OperationKey  string                `xml:"operationKey,attr,omitempty"`
 	Name          string                `xml:"name,attr"`
 	Status        string                `xml:"status,attr,omitempty"`
-	Configuration *NetworkConfiguration `xml:"Configuration,omitempty"`
+	Link          []Link                `xml:"Link,omitempty"`
 	Description   string                `xml:"Description,omitempty"`
+	Tasks         *TasksInProgress      `xml:"Tasks,omitempty"`
+	Configuration *NetworkConfiguration `xml:"Configuration,omitempty"`
 	EdgeGateway   *Reference            `xml:"EdgeGateway,omitempty"`
-	IsShared      bool                  `xml:"IsShared"`
-	Link          []Link                `xml:"Link"`
 	ServiceConfig *GatewayFeatures      `xml:"ServiceConfig,omitempty"` // Specifies the service configuration for an isolated Org vDC networks
-	Tasks         TasksInProgress       `xml:"Tasks,omitempty"`
+	IsShared      bool                  `xml:"IsShared"`
 }
 
 // SupportedHardwareVersions contains a list of VMware virtual hardware versions supported in this vDC.
@@ -506,6 +580,7 @@ type Org struct {
 // Description: Contains a reference to a VappTemplate or Media object and related metadata.
 // Since: 0.9
 type CatalogItem struct {
+	Xmlns         string           `xml:"xmlns,attr,omitempty"`
 	HREF          string           `xml:"href,attr,omitempty"`
 	Type          string           `xml:"type,attr,omitempty"`
 	ID            string           `xml:"id,attr,omitempty"`
@@ -566,6 +641,157 @@ type Catalog struct {
 	VersionNumber int64            `xml:"VersionNumber"`
 }
 
+// AdminCatalog represents the Admin view of a Catalog object.
+// Type: AdminCatalogType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: Represents the Admin view of a Catalog object.
+// Since: 0.9
+type AdminCatalog struct {
+	XMLName      xml.Name         `xml:"AdminCatalog"`
+	Xmlns        string           `xml:"xmlns,attr,omitempty"`
+	HREF         string           `xml:"href,attr,omitempty"`
+	Type         string           `xml:"type,attr,omitempty"`
+	ID           string           `xml:"id,attr,omitempty"`
+	OperationKey string           `xml:"operationKey,attr,omitempty"`
+	Name         string           `xml:"name,attr"`
+	Link         LinkList         `xml:"Link,omitempty"`
+	Description  string           `xml:"Description,omitempty"`
+	Tasks        *TasksInProgress `xml:"Tasks,omitempty"`
+	Owner        *Owner           `xml:"Owner,omitempty"`
+	IsPublished  bool             `xml:"IsPublished,omitempty"`
+}
+
+// PublishCatalogParams parameters for publishing a catalog to all the organizations of the cloud.
+// Type: PublishCatalogParamsType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: Parameters for publishing a catalog.
+// Since: 1.5
+type PublishCatalogParams struct {
+	XMLName     xml.Name `xml:"PublishCatalogParams"`
+	Xmlns       string   `xml:"xmlns,attr,omitempty"`
+	IsPublished bool     `xml:"IsPublished"` // True if the catalog is to be published.
+}
+
+// ControlAccessParams specifies access controls for a resource.
+// Type: ControlAccessParamsType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: Used to control access to resources.
+// Since: 0.9
+type ControlAccessParams struct {
+	XMLName             xml.Name        `xml:"ControlAccessParams"`
+	Xmlns               string          `xml:"xmlns,attr,omitempty"`
+	IsSharedToEveryone  bool            `xml:"IsSharedToEveryone"`            // If true, the resource is shared with everyone in the organization.
+	EveryoneAccessLevel string          `xml:"EveryoneAccessLevel,omitempty"` // If IsSharedToEveryone is true, the access level for everyone: ReadOnly, Change or FullControl.
+	AccessSettings      *AccessSettings `xml:"AccessSettings,omitempty"`      // The access settings of individual users and groups.
+}
+
+// AccessSettings a list of access settings.
+// Type: AccessSettingsType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: A list of access settings for a resource.
+// Since: 0.9
+type AccessSettings struct {
+	AccessSetting []*AccessSetting `xml:"AccessSetting,omitempty"`
+}
+
+// AccessSetting specifies who can access the resource and in what way.
+// Type: AccessSettingType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: Specifies who can access the resource and in what way.
+// Since: 0.9
+type AccessSetting struct {
+	Subject     *Reference `xml:"Subject"`     // Reference to a user or group.
+	AccessLevel string     `xml:"AccessLevel"` // The access level for the subject: ReadOnly, Change or FullControl.
+}
+
+// Media represents a media object, an ISO or floppy image.
+// Type: MediaType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: Represents a media object.
+// Since: 0.9
+type Media struct {
+	XMLName xml.Name `xml:"Media"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	// Attributes
+	HREF         string `xml:"href,attr,omitempty"`         // The URI of the entity.
+	Type         string `xml:"type,attr,omitempty"`         // The MIME type of the entity.
+	ID           string `xml:"id,attr,omitempty"`           // The entity identifier, expressed in URN format.
+	OperationKey string `xml:"operationKey,attr,omitempty"` // Optional unique identifier to support idempotent semantics for create and delete operations.
+	Name         string `xml:"name,attr"`                   // The name of the entity.
+	Status       int    `xml:"status,attr,omitempty"`       // Creation status of the media object.
+	ImageType    string `xml:"imageType,attr"`              // Media image type. One of: iso, floppy.
+	Size         int64  `xml:"size,attr"`                   // Size of the media file, in bytes.
+	// Elements
+	Link              LinkList         `xml:"Link,omitempty"`              // A reference to an entity or operation associated with this object.
+	Description       string           `xml:"Description,omitempty"`       // Optional description.
+	Tasks             *TasksInProgress `xml:"Tasks,omitempty"`             // A list of queued, running, or recently completed tasks associated with this entity.
+	Files             *FilesList       `xml:"Files,omitempty"`             // Represents a list of files to be transferred (uploaded or downloaded).
+	Owner             *Owner           `xml:"Owner,omitempty"`             // Media owner.
+	VdcStorageProfile *Reference       `xml:"VdcStorageProfile,omitempty"` // A reference to a storage profile to be used for this object.
+}
+
+// MediaInsertOrEjectParams represents parameters for inserting and ejecting virtual media.
+// Type: MediaInsertOrEjectParamsType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: Parameters for inserting and ejecting virtual media.
+// Since: 0.9
+type MediaInsertOrEjectParams struct {
+	XMLName xml.Name   `xml:"MediaInsertOrEjectParams"`
+	Xmlns   string     `xml:"xmlns,attr,omitempty"`
+	Media   *Reference `xml:"Media"` // Reference to the media object to insert or eject.
+}
+
+// Disk represents an independent disk.
+// Type: DiskType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: Represents an independent disk.
+// Since: 5.1
+type Disk struct {
+	XMLName xml.Name `xml:"Disk"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	// Attributes
+	HREF         string `xml:"href,attr,omitempty"`         // The URI of the entity.
+	Type         string `xml:"type,attr,omitempty"`         // The MIME type of the entity.
+	ID           string `xml:"id,attr,omitempty"`           // The entity identifier, expressed in URN format.
+	OperationKey string `xml:"operationKey,attr,omitempty"` // Optional unique identifier to support idempotent semantics for create and delete operations.
+	Name         string `xml:"name,attr"`                   // The name of the entity.
+	Status       int    `xml:"status,attr,omitempty"`       // Creation status of the disk.
+	Size         int64  `xml:"size,attr"`                   // Size of the disk, in bytes.
+	BusType      string `xml:"busType,attr,omitempty"`      // Disk bus type: 5 for IDE, 6 for SCSI.
+	BusSubType   string `xml:"busSubType,attr,omitempty"`   // Disk bus sub-type, the controller of the disk.
+	// Elements
+	Link           LinkList         `xml:"Link,omitempty"`           // A reference to an entity or operation associated with this object.
+	Description    string           `xml:"Description,omitempty"`    // Optional description.
+	Tasks          *TasksInProgress `xml:"Tasks,omitempty"`          // A list of queued, running, or recently completed tasks associated with this entity.
+	StorageProfile *Reference       `xml:"StorageProfile,omitempty"` // Storage profile of the disk.
+	Owner          *Owner           `xml:"Owner,omitempty"`          // Disk owner.
+}
+
+// DiskCreateParams represents parameters for creating an independent disk.
+// Type: DiskCreateParamsType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: Parameters for creating an independent disk.
+// Since: 5.1
+type DiskCreateParams struct {
+	XMLName  xml.Name   `xml:"DiskCreateParams"`
+	Xmlns    string     `xml:"xmlns,attr,omitempty"`
+	Disk     *Disk      `xml:"Disk"`               // Parameters for creating the disk.
+	Locality *Reference `xml:"Locality,omitempty"` // A VM the disk should be placed near.
+}
+
+// DiskAttachOrDetachParams represents parameters for attaching or detaching an independent disk.
+// Type: DiskAttachOrDetachParamsType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: Parameters for attaching or detaching an independent disk.
+// Since: 5.1
+type DiskAttachOrDetachParams struct {
+	XMLName    xml.Name   `xml:"DiskAttachOrDetachParams"`
+	Xmlns      string     `xml:"xmlns,attr,omitempty"`
+	Disk       *Reference `xml:"Disk"`                 // Reference to the disk to attach or detach.
+	BusNumber  *int       `xml:"BusNumber,omitempty"`  // Bus number on which to place the disk controller.
+	UnitNumber *int       `xml:"UnitNumber,omitempty"` // Unit number (slot) on the bus specified by BusNumber.
+}
+
 // Owner represents the owner of this entity.
 // Type: OwnerType
 // Namespace: http://www.vmware.com/vcloud/v1.5
@@ -625,8 +851,12 @@ type FilesList struct {
 // Description: Parameters to an undeploy vApp request.
 // Since: 0.9
 type UndeployVAppParams struct {
-	Xmlns               string `xml:"xmlns,attr"`
-	UndeployPowerAction string `xml:"UndeployPowerAction,omitempty"`
+	XMLName xml.Name `xml:"UndeployVAppParams"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	// Attributes
+	SaveState bool `xml:"saveState,attr,omitempty"` // Deprecated since 1.5, use UndeployPowerAction instead. True if the state of the vApp should be saved (suspended) before undeploying.
+	// Elements
+	UndeployPowerAction string `xml:"UndeployPowerAction,omitempty"` // The specified action is applied to all virtual machines in the vApp. All values other than default ignore actions, order, and delay specified in the StartupSection. One of: powerOff (Power off the virtual machines. This is the default action if this attribute is missing or empty), suspend (Suspend the virtual machines), shutdown (Shut down the virtual machines), force (Attempt to power off the virtual machines. Failures in undeploying the virtual machine or associated networks are ignored. All references to the vApp and its virtual machines are removed from the database), default (Use the actions, order, and delay specified in the StartupSection).
 }
 
 // VMCapabilities allows you to specify certain capabilities of this virtual machine.
@@ -681,6 +911,116 @@ type ComposeVAppParams struct {
 	AllEULAsAccepted    bool                         `xml:"AllEULAsAccepted,omitempty"`    // True confirms acceptance of all EULAs in a vApp template. Instantiation fails if this element is missing, empty, or set to false and one or more EulaSection elements are present.
 }
 
+// CloneVAppParams represents parameters for copying a vApp and optionally deleting the source.
+// Type: CloneVAppParamsType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: Parameters for copying a vApp and optionally deleting the source.
+// Since: 0.9
+type CloneVAppParams struct {
+	XMLName xml.Name `xml:"CloneVAppParams"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	// Attributes
+	Name    string `xml:"name,attr,omitempty"` // Typically used to name or identify the subject of the request. For example, the name of the object being created or modified.
+	Deploy  bool   `xml:"deploy,attr"`         // True if the vApp should be deployed at instantiation. Defaults to true.
+	PowerOn bool   `xml:"powerOn,attr"`        // True if the vApp should be powered-on at instantiation. Defaults to true.
+	// Elements
+	Description    string     `xml:"Description,omitempty"`    // Optional description.
+	VAppParent     *Reference `xml:"VAppParent,omitempty"`     // Reserved. Unimplemented.
+	Source         *Reference `xml:"Source"`                   // A reference to a source vApp.
+	IsSourceDelete bool       `xml:"IsSourceDelete,omitempty"` // True if the source vApp should be deleted after cloning is complete.
+}
+
+// CopyOrMoveCatalogItemParams represents parameters for copying a catalog item and optionally deleting the source.
+// Type: CopyOrMoveCatalogItemParamsType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: Parameters for a copyCatalogItem or moveCatalogItem request.
+// Since: 5.1
+type CopyOrMoveCatalogItemParams struct {
+	XMLName xml.Name `xml:"CopyOrMoveCatalogItemParams"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	// Attributes
+	Name string `xml:"name,attr,omitempty"` // Typically used to name or identify the subject of the request. For example, the name of the object being created or modified.
+	// Elements
+	Description string     `xml:"Description,omitempty"` // Optional description.
+	Source      *Reference `xml:"Source"`                // Reference to the catalog item to copy or move.
+}
+
+// Metadata represents user-defined metadata associated with an object.
+// Type: MetadataType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: User-defined metadata associated with an object.
+// Since: 1.5
+type Metadata struct {
+	XMLName xml.Name `xml:"Metadata"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	// Attributes
+	HREF string `xml:"href,attr,omitempty"` // The URI of the entity.
+	Type string `xml:"type,attr,omitempty"` // The MIME type of the entity.
+	// Elements
+	Link          LinkList         `xml:"Link,omitempty"`          // A reference to an entity or operation associated with this object.
+	MetadataEntry []*MetadataEntry `xml:"MetadataEntry,omitempty"` // A metadata entry.
+}
+
+// MetadataEntry represents a single metadata key and value.
+// Type: MetadataEntryType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: A single metadata key and value.
+// Since: 1.5
+type MetadataEntry struct {
+	// Attributes
+	HREF string `xml:"href,attr,omitempty"` // The URI of the entity.
+	Type string `xml:"type,attr,omitempty"` // The MIME type of the entity.
+	// Elements
+	Link       LinkList    `xml:"Link,omitempty"`       // A reference to an entity or operation associated with this object.
+	Key        string      `xml:"Key"`                  // An arbitrary key name.
+	Value      string      `xml:"Value,omitempty"`      // Deprecated since 5.1, the value of the entry as a string.
+	TypedValue *TypedValue `xml:"TypedValue,omitempty"` // One of: MetadataStringValue, MetadataNumberValue, MetadataBooleanValue, MetadataDateTimeValue.
+}
+
+// TypedValue represents the value of a metadata entry along with its type.
+// Type: MetadataTypedValue
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: The value of a metadata entry, the xsi:type attribute gives its type.
+// Since: 5.1
+type TypedValue struct {
+	XsiType string `xml:"type,attr,omitempty"` // The xsi:type of the value, for example MetadataStringValue.
+	Value   string `xml:"Value"`               // The value, formatted according to its type.
+}
+
+// UploadVAppTemplateParams represents parameters for uploading an OVF package as a vApp template.
+// Type: UploadVAppTemplateParamsType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: Parameters for uploading an OVF package as a vApp template.
+// Since: 0.9
+type UploadVAppTemplateParams struct {
+	XMLName xml.Name `xml:"UploadVAppTemplateParams"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	// Attributes
+	Name             string `xml:"name,attr"`                       // Typically used to name or identify the subject of the request. For example, the name of the object being created or modified.
+	ManifestRequired bool   `xml:"manifestRequired,attr,omitempty"` // True if the OVF package must include a manifest.
+	TransferFormat   string `xml:"transferFormat,attr,omitempty"`   // Reserved. Unimplemented.
+	// Elements
+	Description string `xml:"Description,omitempty"` // Optional description.
+}
+
+// CaptureVAppParams represents parameters for capturing a vApp to a vApp template.
+// Type: CaptureVAppParamsType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: Represents parameters for capturing a vApp to a vApp template.
+// Since: 0.9
+type CaptureVAppParams struct {
+	XMLName xml.Name `xml:"CaptureVAppParams"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Ovf     string   `xml:"xmlns:ovf,attr"`
+	// Attributes
+	Name string `xml:"name,attr,omitempty"` // Typically used to name or identify the subject of the request. For example, the name of the object being created or modified.
+	// Elements
+	Description          string                `xml:"Description,omitempty"`          // Optional description.
+	Source               *Reference            `xml:"Source"`                         // A reference to the vApp to capture.
+	CustomizationSection *CustomizationSection `xml:"CustomizationSection,omitempty"` // CustomizationSection for vApp template.
+	TargetCatalogItem    *Reference            `xml:"TargetCatalogItem,omitempty"`    // To overwrite an existing vApp template with the one created by this capture, place a reference to the existing template here. Otherwise, the operation creates a new vApp template.
+}
+
 // SourcedCompositionItemParam represents a vApp, vApp template or Vm to include in a composed vApp.
 // Type: SourcedCompositionItemParamType
 // Namespace: http://www.vmware.com/vcloud/v1.5
@@ -759,6 +1099,10 @@ type VApp struct {
 	Owner             *Owner        `xml:"Owner,omitempty"`             // vApp owner.
 	InMaintenanceMode bool          `xml:"InMaintenanceMode,omitempty"` // True if this vApp is in maintenance mode. Prevents users from changing vApp metadata.
 	Children          *VAppChildren `xml:"Children,omitempty"`          // Container for virtual machines included in this vApp.
+	// OVF sections returned with the vApp
+	NetworkConfigSection *NetworkConfigSection `xml:"NetworkConfigSection,omitempty"` // Networks of the vApp and their configuration.
+	LeaseSettingsSection *LeaseSettingsSection `xml:"LeaseSettingsSection,omitempty"` // Runtime and storage leases of the vApp.
+	SnapshotSection      *SnapshotSection      `xml:"SnapshotSection,omitempty"`      // Snapshots of the vApp.
 }
 
 // VAppChildren is a container for virtual machines included in this vApp.
@@ -823,10 +1167,55 @@ type VAppTemplate struct {
 	NetworkConnectionSection *NetworkConnectionSection `xml:"NetworkConnectionSection,omitempty"`
 	LeaseSettingsSection     *LeaseSettingsSection     `xml:"LeaseSettingsSection,omitempty"`
 	CustomizationSection     *CustomizationSection     `xml:"CustomizationSection,omitempty"`
+	VirtualHardwareSection   *VirtualHardwareSection   `xml:"VirtualHardwareSection,omitempty"`
 	// OVF Section needs to be added
 	// Section               Section              `xml:"Section,omitempty"`
 }
 
+// VirtualHardwareSection represents the virtual hardware of a virtual machine.
+// Type: VirtualHardwareSection_Type
+// Namespace: http://schemas.dmtf.org/ovf/envelope/1
+// Description: Specifies virtual hardware requirements for a virtual machine.
+// Since: 0.9
+type VirtualHardwareSection struct {
+	// FIXME: Fix the OVF section
+	Info string `xml:"ovf:Info"`
+	//
+	Item []*VirtualHardwareItem `xml:"Item,omitempty"` // A virtual hardware device, see the ResourceType constants.
+}
+
+// VirtualHardwareItem represents a virtual hardware device.
+// Type: RASD_Type
+// Namespace: http://schemas.dmtf.org/ovf/envelope/1
+// Description: A resource allocation setting of a virtual machine.
+// Since: 0.9
+type VirtualHardwareItem struct {
+	Address         string                         `xml:"Address,omitempty"`         // Address of the device, the MAC address of a network adapter or the bus number of a controller.
+	AddressOnParent string                         `xml:"AddressOnParent,omitempty"` // Address of the device on its controller.
+	AllocationUnits string                         `xml:"AllocationUnits,omitempty"` // Units of VirtualQuantity.
+	Connection      []string                       `xml:"Connection,omitempty"`      // Networks a network adapter connects to.
+	Description     string                         `xml:"Description,omitempty"`     // Description of the device.
+	ElementName     string                         `xml:"ElementName,omitempty"`     // Name of the device.
+	HostResource    []*VirtualHardwareHostResource `xml:"HostResource,omitempty"`    // Backing of the device.
+	InstanceID      int                            `xml:"InstanceID"`                // Identifier of the device in the virtual machine.
+	Parent          int                            `xml:"Parent,omitempty"`          // InstanceID of the controller of the device.
+	ResourceSubType string                         `xml:"ResourceSubType,omitempty"` // Model of the device.
+	ResourceType    int                            `xml:"ResourceType"`              // Kind of the device.
+	VirtualQuantity int64                          `xml:"VirtualQuantity,omitempty"` // Number of CPUs or amount of memory.
+}
+
+// VirtualHardwareHostResource represents the backing of a virtual hard disk.
+// Type: CIM_ResourceAllocationSettingData HostResource
+// Namespace: http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData
+// Description: The backing of a virtual device, with vCloud extension attributes for hard disks.
+// Since: 0.9
+type VirtualHardwareHostResource struct {
+	Capacity           int64  `xml:"capacity,attr,omitempty"`           // Capacity of the hard disk in megabytes.
+	BusType            string `xml:"busType,attr,omitempty"`            // Bus type of the hard disk, see the DiskBusType constants.
+	BusSubType         string `xml:"busSubType,attr,omitempty"`         // Bus sub type of the hard disk, see the DiskBusSubType constants.
+	StorageProfileHREF string `xml:"storageProfileHref,attr,omitempty"` // Storage profile of the hard disk.
+}
+
 // VM represents a virtual machine
 // Type: VmType
 // Namespace: http://www.vmware.com/vcloud/v1.5
@@ -860,6 +1249,8 @@ type VM struct {
 	// FIXME: Upstream bug? Missing NetworkConnectionSection
 	NetworkConnectionSection *NetworkConnectionSection `xml:"NetworkConnectionSection,omitempty"`
 
+	SnapshotSection *SnapshotSection `xml:"SnapshotSection,omitempty"` // Snapshots of the virtual machine.
+
 	VAppScopedLocalID string `xml:"VAppScopedLocalId,omitempty"` // A unique identifier for the virtual machine in the scope of the vApp.
 
 	// TODO: OVF Sections to be implemented
@@ -939,6 +1330,49 @@ type GuestCustomizationSection struct {
 	Link                  LinkList `xml:"Link,omitempty"`                  // A link to an operation on this section.
 }
 
+// CreateSnapshotParams represents snapshot parameters.
+// Type: CreateSnapshotParamsType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: Represents snapshot parameters.
+// Since: 5.1
+type CreateSnapshotParams struct {
+	XMLName xml.Name `xml:"CreateSnapshotParams"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	// Attributes
+	Name    string `xml:"name,attr,omitempty"` // Typically used to name or identify the subject of the request. For example, the name of the object being created or modified.
+	Memory  bool   `xml:"memory,attr"`         // True if the snapshot should include the virtual machine's memory.
+	Quiesce bool   `xml:"quiesce,attr"`        // True if the file system of the virtual machine should be quiesced before the snapshot is created. Requires VMware tools to be installed on the virtual machine.
+	// Elements
+	Description string `xml:"Description,omitempty"` // Optional description.
+}
+
+// SnapshotSection represents a snapshot section.
+// Type: SnapshotSectionType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: Snapshot information section.
+// Since: 5.1
+type SnapshotSection struct {
+	// Extends OVF Section_Type
+	// FIXME: Fix the OVF section
+	Info string `xml:"ovf:Info"`
+	//
+	HREF     string      `xml:"href,attr,omitempty"`
+	Type     string      `xml:"type,attr,omitempty"`
+	Link     LinkList    `xml:"Link,omitempty"`
+	Snapshot []*Snapshot `xml:"Snapshot,omitempty"` // Information about a snapshot.
+}
+
+// Snapshot represents information about a snapshot.
+// Type: SnapshotType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: Information about a snapshot.
+// Since: 5.1
+type Snapshot struct {
+	Created   string `xml:"created,attr,omitempty"`   // Creation date/time of the snapshot.
+	PoweredOn bool   `xml:"poweredOn,attr,omitempty"` // True if the virtual machine was powered on when the snapshot was created.
+	Size      int64  `xml:"size,attr"`                // Size of the snapshot in bytes.
+}
+
 // InstantiateVAppTemplateParams represents vApp template instantiation parameters.
 // Type: InstantiateVAppTemplateParamsType
 // Namespace: http://www.vmware.com/vcloud/v1.5
@@ -971,6 +1405,8 @@ type InstantiateVAppTemplateParams struct {
 // Description: Represents a gateway.
 // Since: 5.1
 type EdgeGateway struct {
+	XMLName xml.Name `xml:"EdgeGateway"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
 	// Attributes
 	HREF         string `xml:"href,attr,omitempty"`         // The URI of the entity.
 	Type         string `xml:"type,attr,omitempty"`         // The MIME type of the entity.
@@ -1016,15 +1452,15 @@ type GatewayInterfaces struct {
 // Description: Gateway Interface configuration.
 // Since: 5.1
 type GatewayInterface struct {
-	Name                string               `xml:"Name,omitempty"`                // Internally generated name for the Gateway Interface.
-	DisplayName         string               `xml:"DisplayName,omitempty"`         // Gateway Interface display name.
-	Network             *Reference           `xml:"Network"`                       // A reference to the network connected to the gateway interface.
-	InterfaceType       string               `xml:"InterfaceType"`                 // The type of interface: One of: Uplink, Internal
-	SubnetParticipation *SubnetParticipation `xml:"SubnetParticipation,omitempty"` // IP allocation per subnet.
-	ApplyRateLimit      bool                 `xml:"ApplyRateLimit,omitempty"`      // True if rate limiting is applied on this interface.
-	InRateLimit         float64              `xml:"InRateLimit,omitempty"`         // Incoming rate limit expressed as Gbps.
-	OutRateLimit        float64              `xml:"OutRateLimit,omitempty"`        // Outgoing rate limit expressed as Gbps.
-	UseForDefaultRoute  bool                 `xml:"UseForDefaultRoute,omitempty"`  // True if this network is default route for the gateway.
+	Name                string                 `xml:"Name,omitempty"`                // Internally generated name for the Gateway Interface.
+	DisplayName         string                 `xml:"DisplayName,omitempty"`         // Gateway Interface display name.
+	Network             *Reference             `xml:"Network"`                       // A reference to the network connected to the gateway interface.
+	InterfaceType       string                 `xml:"InterfaceType"`                 // The type of interface: One of: Uplink, Internal
+	SubnetParticipation []*SubnetParticipation `xml:"SubnetParticipation,omitempty"` // IP allocation per subnet.
+	ApplyRateLimit      bool                   `xml:"ApplyRateLimit,omitempty"`      // True if rate limiting is applied on this interface.
+	InRateLimit         float64                `xml:"InRateLimit,omitempty"`         // Incoming rate limit expressed as Gbps.
+	OutRateLimit        float64                `xml:"OutRateLimit,omitempty"`        // Outgoing rate limit expressed as Gbps.
+	UseForDefaultRoute  bool                   `xml:"UseForDefaultRoute,omitempty"`  // True if this network is default route for the gateway.
 }
 
 // SubnetParticipation allows to chose which subnets a gateway can be a part of
@@ -1062,8 +1498,8 @@ type GatewayFeatures struct {
 // Description: Represents Static Routing network service.
 // Since: 1.5
 type StaticRoutingService struct {
-	IsEnabled   bool         `xml:"IsEnabled"`             // Enable or disable the service using this flag
-	StaticRoute *StaticRoute `xml:"StaticRoute,omitempty"` // Details of each Static Route.
+	IsEnabled   bool           `xml:"IsEnabled"`             // Enable or disable the service using this flag
+	StaticRoute []*StaticRoute `xml:"StaticRoute,omitempty"` // Details of each Static Route.
 }
 
 // StaticRoute represents a static route entry
@@ -1085,9 +1521,9 @@ type StaticRoute struct {
 // Description: Represents gateway load balancer service.
 // Since: 5.1
 type LoadBalancerService struct {
-	IsEnabled     bool                       `xml:"IsEnabled"`               // Enable or disable the service using this flag
-	Pool          *LoadBalancerPool          `xml:"Pool,omitempty"`          // List of load balancer pools.
-	VirtualServer *LoadBalancerVirtualServer `xml:"VirtualServer,omitempty"` // List of load balancer virtual servers.
+	IsEnabled     bool                         `xml:"IsEnabled"`               // Enable or disable the service using this flag
+	Pool          []*LoadBalancerPool          `xml:"Pool,omitempty"`          // List of load balancer pools.
+	VirtualServer []*LoadBalancerVirtualServer `xml:"VirtualServer,omitempty"` // List of load balancer virtual servers.
 }
 
 // LoadBalancerPool represents a load balancer pool.
@@ -1096,13 +1532,13 @@ type LoadBalancerService struct {
 // Description: Represents a load balancer pool.
 // Since: 5.1
 type LoadBalancerPool struct {
-	ID           string             `xml:"Id,omitempty"`           // Load balancer pool id.
-	Name         string             `xml:"Name"`                   // Load balancer pool name.
-	Description  string             `xml:"Description,omitempty"`  // Load balancer pool description.
-	ServicePort  *LBPoolServicePort `xml:"ServicePort"`            // Load balancer pool service port.
-	Member       *LBPoolMember      `xml:"Member"`                 // Load balancer pool member.
-	Operational  bool               `xml:"Operational,omitempty"`  // True if the load balancer pool is operational.
-	ErrorDetails string             `xml:"ErrorDetails,omitempty"` // Error details for this pool.
+	ID           string               `xml:"Id,omitempty"`           // Load balancer pool id.
+	Name         string               `xml:"Name"`                   // Load balancer pool name.
+	Description  string               `xml:"Description,omitempty"`  // Load balancer pool description.
+	ServicePort  []*LBPoolServicePort `xml:"ServicePort"`            // Load balancer pool service port.
+	Member       []*LBPoolMember      `xml:"Member"`                 // Load balancer pool member.
+	Operational  bool                 `xml:"Operational,omitempty"`  // True if the load balancer pool is operational.
+	ErrorDetails string               `xml:"ErrorDetails,omitempty"` // Error details for this pool.
 }
 
 // LBPoolServicePort represents a service port in a load balancer pool.
@@ -1139,9 +1575,9 @@ type LBPoolHealthCheck struct {
 // Description: Represents a member in a load balancer pool.
 // Since: 5.1
 type LBPoolMember struct {
-	IPAddress   string             `xml:"IpAddress"`             // Ip Address for load balancer member.
-	Weight      string             `xml:"Weight"`                // Weight of this member.
-	ServicePort *LBPoolServicePort `xml:"ServicePort,omitempty"` // Load balancer member service port.
+	IPAddress   string               `xml:"IpAddress"`             // Ip Address for load balancer member.
+	Weight      string               `xml:"Weight"`                // Weight of this member.
+	ServicePort []*LBPoolServicePort `xml:"ServicePort,omitempty"` // Load balancer member service port.
 }
 
 // LoadBalancerVirtualServer represents a load balancer virtual server.
@@ -1150,15 +1586,15 @@ type LBPoolMember struct {
 // Description: Represents a load balancer virtual server.
 // Since: 5.1
 type LoadBalancerVirtualServer struct {
-	IsEnabled             bool                           `xml:"IsEnabled,omitempty"`             // True if this virtual server is enabled.
-	Name                  string                         `xml:"Name"`                            // Load balancer virtual server name.
-	Description           string                         `xml:"Description,omitempty"`           // Load balancer virtual server description.
-	Interface             *Reference                     `xml:"Interface"`                       // Gateway Interface to which Load Balancer Virtual Server is bound.
-	IPAddress             string                         `xml:"IpAddress"`                       // Load balancer virtual server Ip Address.
-	ServiceProfile        *LBVirtualServerServiceProfile `xml:"ServiceProfile"`                  // Load balancer virtual server service profiles.
-	Logging               bool                           `xml:"Logging,omitempty"`               // Enable logging for this virtual server.
-	Pool                  string                         `xml:"Pool"`                            // Name of Load balancer pool associated with this virtual server.
-	LoadBalancerTemplates *VendorTemplate                `xml:"LoadBalancerTemplates,omitempty"` // Service template related attributes.
+	IsEnabled             bool                             `xml:"IsEnabled,omitempty"`             // True if this virtual server is enabled.
+	Name                  string                           `xml:"Name"`                            // Load balancer virtual server name.
+	Description           string                           `xml:"Description,omitempty"`           // Load balancer virtual server description.
+	Interface             *Reference                       `xml:"Interface"`                       // Gateway Interface to which Load Balancer Virtual Server is bound.
+	IPAddress             string                           `xml:"IpAddress"`                       // Load balancer virtual server Ip Address.
+	ServiceProfile        []*LBVirtualServerServiceProfile `xml:"ServiceProfile"`                  // Load balancer virtual server service profiles.
+	Logging               bool                             `xml:"Logging,omitempty"`               // Enable logging for this virtual server.
+	Pool                  string                           `xml:"Pool"`                            // Name of Load balancer pool associated with this virtual server.
+	LoadBalancerTemplates *VendorTemplate                  `xml:"LoadBalancerTemplates,omitempty"` // Service template related attributes.
 }
 
 // LBVirtualServerServiceProfile represents service profile for a load balancing virtual server.
@@ -1223,14 +1659,17 @@ type GatewayIpsecVpnEndpoint struct {
 type GatewayIpsecVpnTunnel struct {
 	Name        string `xml:"Name"`                  // The name of the tunnel.
 	Description string `xml:"Description,omitempty"` // A description of the tunnel.
-	// TODO: Fix this in a better way
-	IpsecVpnThirdPartyPeer *IpsecVpnThirdPartyPeer `xml:"IpsecVpnThirdPartyPeer,omitempty"` // Details about the peer network.
+	// IpsecVpnPeer is an abstract element substituted by one of the following,
+	// exactly one of them is set.
+	IpsecVpnThirdPartyPeer *IpsecVpnThirdPartyPeer `xml:"IpsecVpnThirdPartyPeer,omitempty"` // Details about a third party peer network.
+	IpsecVpnLocalPeer      *IpsecVpnLocalPeer      `xml:"IpsecVpnLocalPeer,omitempty"`      // Details about a peer network in the same vCloud.
+	IpsecVpnRemotePeer     *IpsecVpnRemotePeer     `xml:"IpsecVpnRemotePeer,omitempty"`     // Details about a peer network in a remote vCloud.
 	PeerIPAddress          string                  `xml:"PeerIpAddress"`                    // IP address of the peer endpoint.
 	PeerID                 string                  `xml:"PeerId"`                           // Id for the peer end point
 	LocalIPAddress         string                  `xml:"LocalIpAddress"`                   // Address of the local network.
 	LocalID                string                  `xml:"LocalId"`                          // Id for local end point
-	LocalSubnet            *IpsecVpnSubnet         `xml:"LocalSubnet"`                      // List of local subnets in the tunnel.
-	PeerSubnet             *IpsecVpnSubnet         `xml:"PeerSubnet"`                       // List of peer subnets in the tunnel.
+	LocalSubnet            []*IpsecVpnSubnet       `xml:"LocalSubnet"`                      // List of local subnets in the tunnel.
+	PeerSubnet             []*IpsecVpnSubnet       `xml:"PeerSubnet"`                       // List of peer subnets in the tunnel.
 	SharedSecret           string                  `xml:"SharedSecret"`                     // Shared secret used for authentication.
 	SharedSecretEncrypted  bool                    `xml:"SharedSecretEncrypted,omitempty"`  // True if shared secret is encrypted.
 	EncryptionProtocol     string                  `xml:"EncryptionProtocol"`               // Encryption protocol to be used. One of: AES, AES256, TRIPLEDES
@@ -1241,10 +1680,37 @@ type GatewayIpsecVpnTunnel struct {
 }
 
 // IpsecVpnThirdPartyPeer represents details about a peer network
+// Type: IpsecVpnThirdPartyPeerType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: Details about a third party peer network.
+// Since: 5.1
 type IpsecVpnThirdPartyPeer struct {
 	PeerID string `xml:"PeerId,omitempty"` // Id for the peer end point
 }
 
+// IpsecVpnLocalPeer represents details about a peer network in the same vCloud
+// Type: IpsecVpnLocalPeerType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: Details about a local peer network.
+// Since: 5.1
+type IpsecVpnLocalPeer struct {
+	ID   string `xml:"Id"`   // Id for the peer end point
+	Name string `xml:"Name"` // Name for the peer
+}
+
+// IpsecVpnRemotePeer represents details about a peer network in a remote vCloud
+// Type: IpsecVpnRemotePeerType
+// Namespace: http://www.vmware.com/vcloud/v1.5
+// Description: Details about a remote peer network.
+// Since: 5.1
+type IpsecVpnRemotePeer struct {
+	ID              string `xml:"Id"`              // Id for the peer end point
+	Name            string `xml:"Name"`            // Name for the peer
+	VcdURL          string `xml:"VcdUrl"`          // URL of the remote vCloud
+	VcdOrganization string `xml:"VcdOrganization"` // Organization of the remote vCloud
+	VcdUsername     string `xml:"VcdUsername"`     // Username for the remote vCloud
+}
+
 // IpsecVpnSubnet represents subnet details.
 // Type: IpsecVpnSubnetType
 // Namespace: http://www.vmware.com/vcloud/v1.5
@@ -1262,8 +1728,8 @@ type IpsecVpnSubnet struct {
 // Description: Represents Gateway DHCP service.
 // Since: 5.1
 type GatewayDhcpService struct {
-	IsEnabled bool             `xml:"IsEnabled,omitempty"` // Enable or disable the service using this flag
-	Pool      *DhcpPoolService `xml:"Pool,omitempty"`      // A DHCP pool.
+	IsEnabled bool               `xml:"IsEnabled,omitempty"` // Enable or disable the service using this flag
+	Pool      []*DhcpPoolService `xml:"Pool,omitempty"`      // A DHCP pool.
 }
 
 // DhcpPoolService represents DHCP pool service.
@@ -0,0 +1,556 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// DefaultUploadChunkSize is the size of the requests files are uploaded with
+// when UploadOptions doesn't set one
+const DefaultUploadChunkSize = 16 * 1024 * 1024
+
+// DefaultUploadTimeout is how long an upload waits for vCloud to process
+// what it received when UploadOptions doesn't set a timeout
+const DefaultUploadTimeout = 30 * time.Minute
+
+// ovfDescriptorName is the name of the OVF descriptor among the files of a
+// vApp template
+const ovfDescriptorName = "descriptor.ovf"
+
+// uploadPollInterval is the time between two checks of a vApp template or
+// media waiting for vCloud to process an upload
+var uploadPollInterval = 3 * time.Second
+
+// UploadProgress is the state of an upload
+type UploadProgress struct {
+	// File is the name of the file being uploaded.
+	File      string
+	FileBytes int64
+	FileSize  int64
+	// TotalBytes and TotalSize count every file of the upload but the OVF
+	// descriptor.
+	TotalBytes int64
+	TotalSize  int64
+}
+
+// UploadOptions controls OVF, OVA and media uploads
+type UploadOptions struct {
+	// ChunkSize is the size of each upload request, DefaultUploadChunkSize
+	// when zero.
+	ChunkSize int64
+	// Progress when set is called before each file with the bytes vCloud
+	// already has, which are not sent again, and after each chunk.
+	Progress func(UploadProgress)
+	// ManifestRequired makes vCloud reject OVF packages without a manifest.
+	ManifestRequired bool
+	// Timeout is how long to wait for vCloud to process the descriptor and
+	// the files it received, DefaultUploadTimeout when zero.
+	Timeout time.Duration
+}
+
+// ovfEnvelope is the part of an OVF descriptor listing the files of a package
+type ovfEnvelope struct {
+	XMLName    xml.Name `xml:"Envelope"`
+	References struct {
+		File []struct {
+			HREF string `xml:"href,attr"`
+			Size int64  `xml:"size,attr"`
+		} `xml:"File"`
+	} `xml:"References"`
+}
+
+// ovfPackage is an OVF descriptor along with the files it references, by
+// name
+type ovfPackage struct {
+	descriptor []byte
+	files      map[string]*io.SectionReader
+	closers    []io.Closer
+}
+
+// Close closes the files of the package
+func (p *ovfPackage) Close() error {
+
+	var err error
+	for _, c := range p.closers {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// ovfReference is a file referenced by an OVF descriptor, Size is 0 when the
+// descriptor doesn't give one
+type ovfReference struct {
+	Name string
+	Size int64
+}
+
+// parseOVFDescriptor returns the files referenced by descriptor, in order
+func parseOVFDescriptor(descriptor []byte) ([]ovfReference, error) {
+
+	envelope := new(ovfEnvelope)
+	if err := xml.Unmarshal(descriptor, envelope); err != nil {
+		return nil, fmt.Errorf("error decoding OVF descriptor: %s", err)
+	}
+
+	var refs []ovfReference
+	for _, f := range envelope.References.File {
+		name := path.Clean(f.HREF)
+		if f.HREF == "" || path.IsAbs(name) || strings.Contains(name, "://") || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("OVF descriptor references a file outside of the package: %s", f.HREF)
+		}
+		refs = append(refs, ovfReference{Name: name, Size: f.Size})
+	}
+
+	return refs, nil
+}
+
+// checkOVFPackage checks that p has every file its descriptor references,
+// with the size given by the descriptor
+func checkOVFPackage(p *ovfPackage) error {
+
+	refs, err := parseOVFDescriptor(p.descriptor)
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range refs {
+		f, ok := p.files[ref.Name]
+		if !ok {
+			return fmt.Errorf("OVF package has no file %s", ref.Name)
+		}
+		if ref.Size > 0 && f.Size() != ref.Size {
+			return fmt.Errorf("file %s of the OVF package has %d bytes, the descriptor says %d", ref.Name, f.Size(), ref.Size)
+		}
+	}
+
+	return nil
+}
+
+// openOVF opens the OVF descriptor at ovfPath and the files it references,
+// which are relative to the directory of the descriptor
+func openOVF(ovfPath string) (*ovfPackage, error) {
+
+	descriptor, err := ioutil.ReadFile(ovfPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading OVF descriptor: %s", err)
+	}
+
+	refs, err := parseOVFDescriptor(descriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &ovfPackage{descriptor: descriptor, files: make(map[string]*io.SectionReader)}
+	for _, ref := range refs {
+		f, err := os.Open(filepath.Join(filepath.Dir(ovfPath), filepath.FromSlash(ref.Name)))
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("error opening OVF package file: %s", err)
+		}
+		p.closers = append(p.closers, f)
+
+		info, err := f.Stat()
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("error opening OVF package file: %s", err)
+		}
+		p.files[ref.Name] = io.NewSectionReader(f, 0, info.Size())
+	}
+
+	if err := checkOVFPackage(p); err != nil {
+		p.Close()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// openOVA opens the OVA archive at ovaPath. The files of the archive are
+// read in place, the first .ovf file is the descriptor.
+func openOVA(ovaPath string) (*ovfPackage, error) {
+
+	f, err := os.Open(ovaPath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening OVA archive: %s", err)
+	}
+
+	p := &ovfPackage{files: make(map[string]*io.SectionReader), closers: []io.Closer{f}}
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("error reading OVA archive: %s", err)
+		}
+
+		if !hdr.FileInfo().Mode().IsRegular() {
+			continue
+		}
+
+		name := path.Clean(hdr.Name)
+		if p.descriptor == nil && strings.HasSuffix(name, ".ovf") {
+			if p.descriptor, err = ioutil.ReadAll(tr); err != nil {
+				p.Close()
+				return nil, fmt.Errorf("error reading OVA archive: %s", err)
+			}
+			continue
+		}
+
+		// The tar reader leaves the archive at the start of the file content
+		offset, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("error reading OVA archive: %s", err)
+		}
+		p.files[name] = io.NewSectionReader(f, offset, hdr.Size)
+	}
+
+	if p.descriptor == nil {
+		p.Close()
+		return nil, fmt.Errorf("OVA archive %s has no OVF descriptor", ovaPath)
+	}
+
+	if err := checkOVFPackage(p); err != nil {
+		p.Close()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// createUploadTemplate creates the empty vApp template an OVF package is
+// uploaded to, and the catalog item referring to it
+func (c *Catalog) createUploadTemplate(name, description string, opts UploadOptions) (VAppTemplate, error) {
+
+	if name == "" {
+		return VAppTemplate{}, fmt.Errorf("vApp template needs a name")
+	}
+
+	var s *url.URL
+	var err error
+	if l := c.Catalog.Link.ForType(types.MimeUploadVAppTemplateParams, types.RelAdd); l != nil {
+		s, err = url.ParseRequestURI(l.HREF)
+	} else if s, err = url.ParseRequestURI(c.Catalog.HREF); err == nil {
+		s.Path += "/action/upload"
+	}
+	if err != nil {
+		return VAppTemplate{}, fmt.Errorf("error parsing catalog href: %s", err)
+	}
+
+	params := &types.UploadVAppTemplateParams{
+		Xmlns:            types.NsVCloud,
+		Name:             name,
+		Description:      description,
+		ManifestRequired: opts.ManifestRequired,
+	}
+
+	item := NewCatalogItem(c.c)
+
+	if err = executeRequest(c.c, types.HTTPPost, s, types.MimeUploadVAppTemplateParams, params, item.CatalogItem); err != nil {
+		return VAppTemplate{}, fmt.Errorf("error creating vApp template: %s", err)
+	}
+
+	if item.CatalogItem.Entity == nil {
+		return VAppTemplate{}, fmt.Errorf("error creating vApp template: no vApp template returned")
+	}
+
+	return item.GetVAppTemplate()
+}
+
+// uploadPackage uploads p as a new vApp template of this catalog
+func (c *Catalog) uploadPackage(p *ovfPackage, name, description string, opts UploadOptions) (VAppTemplate, error) {
+
+	vapptemplate, err := c.createUploadTemplate(name, description, opts)
+	if err != nil {
+		return VAppTemplate{}, err
+	}
+
+	return vapptemplate, vapptemplate.upload(p, opts)
+}
+
+// UploadOVF uploads the OVF package described by the descriptor at ovfPath
+// as a new vApp template named name, and waits for vCloud to resolve it. The
+// vApp template is returned along with any error that occurs once it is
+// created, so that the upload can be resumed with ResumeUploadOVF.
+func (c *Catalog) UploadOVF(ovfPath, name, description string, opts UploadOptions) (VAppTemplate, error) {
+
+	p, err := openOVF(ovfPath)
+	if err != nil {
+		return VAppTemplate{}, err
+	}
+	defer p.Close()
+
+	return c.uploadPackage(p, name, description, opts)
+}
+
+// UploadOVA uploads the OVA archive at ovaPath as a new vApp template named
+// name, and waits for vCloud to resolve it. The vApp template is returned
+// along with any error that occurs once it is created, so that the upload
+// can be resumed with ResumeUploadOVA.
+func (c *Catalog) UploadOVA(ovaPath, name, description string, opts UploadOptions) (VAppTemplate, error) {
+
+	p, err := openOVA(ovaPath)
+	if err != nil {
+		return VAppTemplate{}, err
+	}
+	defer p.Close()
+
+	return c.uploadPackage(p, name, description, opts)
+}
+
+// ResumeUploadOVF finishes an interrupted upload of the OVF package
+// described by the descriptor at ovfPath to this vApp template. Only the
+// bytes vCloud doesn't have yet are sent.
+func (v *VAppTemplate) ResumeUploadOVF(ovfPath string, opts UploadOptions) error {
+
+	p, err := openOVF(ovfPath)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	return v.upload(p, opts)
+}
+
+// ResumeUploadOVA finishes an interrupted upload of the OVA archive at
+// ovaPath to this vApp template. Only the bytes vCloud doesn't have yet are
+// sent.
+func (v *VAppTemplate) ResumeUploadOVA(ovaPath string, opts UploadOptions) error {
+
+	p, err := openOVA(ovaPath)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	return v.upload(p, opts)
+}
+
+// uploadFiles returns the files of this vApp template, without the OVF
+// descriptor
+func (v *VAppTemplate) uploadFiles() []*types.File {
+
+	var files []*types.File
+	if v.VAppTemplate.Files != nil {
+		for _, f := range v.VAppTemplate.Files.File {
+			if f.Name != ovfDescriptorName {
+				files = append(files, f)
+			}
+		}
+	}
+
+	return files
+}
+
+// uploadURL returns the URL file is uploaded to
+func uploadURL(file *types.File) (*url.URL, error) {
+
+	l := file.Link.Find(func(l *types.Link) bool { return l.Rel == types.RelUploadDefault })
+	if l == nil {
+		return nil, fmt.Errorf("can't find the upload link of file: %s", file.Name)
+	}
+
+	return url.ParseRequestURI(l.HREF)
+}
+
+// waitProcessed calls refresh, which returns the tasks and the status of the
+// entity being uploaded, until done returns true. It fails when one of the
+// tasks or the creation of the entity fails, or once timeout has expired.
+func waitProcessed(entity string, timeout time.Duration, refresh func() (*types.TasksInProgress, int, error), done func() bool) error {
+
+	if timeout <= 0 {
+		timeout = DefaultUploadTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		tasks, status, err := refresh()
+		if err != nil {
+			return err
+		}
+
+		if tasks != nil {
+			for _, t := range tasks.Task {
+				if t.Status == "error" {
+					if t.Error != nil {
+						return fmt.Errorf("upload of %s failed: %s", entity, t.Error.Message)
+					}
+					return fmt.Errorf("upload of %s failed: %s", entity, t.Description)
+				}
+			}
+		}
+
+		if types.VAppStatus(status) == types.VAppStatusFailedCreation {
+			return fmt.Errorf("upload of %s failed", entity)
+		}
+
+		if done() {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for vCloud to process the upload of %s", timeout, entity)
+		}
+
+		time.Sleep(uploadPollInterval)
+	}
+}
+
+// waitUpload refreshes this vApp template until done returns true, it fails
+// when vCloud gives up on the upload or opts.Timeout expires
+func (v *VAppTemplate) waitUpload(opts UploadOptions, done func() bool) error {
+	return waitProcessed("vApp template "+v.VAppTemplate.Name, opts.Timeout, func() (*types.TasksInProgress, int, error) {
+		err := v.Refresh()
+		return v.VAppTemplate.Tasks, v.VAppTemplate.Status, err
+	}, done)
+}
+
+// upload sends the OVF descriptor of p unless vCloud already has it, then
+// the part of each file vCloud doesn't have, and waits for the vApp template
+// to be resolved
+func (v *VAppTemplate) upload(p *ovfPackage, opts UploadOptions) error {
+
+	if err := v.Refresh(); err != nil {
+		return err
+	}
+
+	if v.VAppTemplate.OvfDescriptorUploaded != "true" {
+		var descriptor *types.File
+		if v.VAppTemplate.Files != nil {
+			for _, f := range v.VAppTemplate.Files.File {
+				if f.Name == ovfDescriptorName {
+					descriptor = f
+				}
+			}
+		}
+		if descriptor == nil {
+			return fmt.Errorf("vApp template %s doesn't accept an OVF descriptor", v.VAppTemplate.Name)
+		}
+
+		u, err := uploadURL(descriptor)
+		if err != nil {
+			return err
+		}
+
+		req := v.c.NewRequest(map[string]string{}, types.HTTPPut, u, bytes.NewReader(p.descriptor))
+		req.Header.Add("Content-Type", "text/xml")
+
+		resp, err := checkResp(v.c.DoHTTP(req))
+		if err != nil {
+			return fmt.Errorf("error uploading OVF descriptor: %s", err)
+		}
+		resp.Body.Close()
+
+		// The files of the package are listed once vCloud has processed the
+		// descriptor
+		if err := v.waitUpload(opts, func() bool { return v.VAppTemplate.OvfDescriptorUploaded == "true" }); err != nil {
+			return err
+		}
+	}
+
+	files := v.uploadFiles()
+
+	var progress UploadProgress
+	for _, f := range files {
+		progress.TotalSize += f.Size
+		progress.TotalBytes += f.BytesTransferred
+	}
+
+	for _, f := range files {
+		if err := v.uploadFile(p, f, &progress, opts); err != nil {
+			return err
+		}
+	}
+
+	return v.waitUpload(opts, func() bool { return types.VAppStatus(v.VAppTemplate.Status) == types.VAppStatusResolved })
+}
+
+// uploadFile sends the content of file from p in chunks, starting after the
+// bytes vCloud already has
+func (v *VAppTemplate) uploadFile(p *ovfPackage, file *types.File, progress *UploadProgress, opts UploadOptions) error {
+
+	src, ok := p.files[file.Name]
+	if !ok {
+		return fmt.Errorf("OVF package has no file %s", file.Name)
+	}
+
+	return uploadChunks(v.c, file, src, progress, opts)
+}
+
+// uploadChunks sends the content of file from src in chunks, starting after
+// the bytes vCloud already has
+func uploadChunks(c Client, file *types.File, src *io.SectionReader, progress *UploadProgress, opts UploadOptions) error {
+
+	size := src.Size()
+	if file.Size > 0 && file.Size != size {
+		return fmt.Errorf("file %s has %d bytes, vCloud expects %d", file.Name, size, file.Size)
+	}
+
+	chunk := opts.ChunkSize
+	if chunk <= 0 {
+		chunk = DefaultUploadChunkSize
+	}
+
+	progress.File, progress.FileBytes, progress.FileSize = file.Name, file.BytesTransferred, size
+	if opts.Progress != nil {
+		opts.Progress(*progress)
+	}
+
+	if file.BytesTransferred >= size {
+		return nil
+	}
+
+	u, err := uploadURL(file)
+	if err != nil {
+		return err
+	}
+
+	for offset := file.BytesTransferred; offset < size; {
+		n := chunk
+		if size-offset < n {
+			n = size - offset
+		}
+
+		req := c.NewRequest(map[string]string{}, types.HTTPPut, u, io.NewSectionReader(src, offset, n))
+		req.ContentLength = n
+		req.Header.Add("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+n-1, size))
+
+		resp, err := checkResp(c.DoHTTP(req))
+		if err != nil {
+			return fmt.Errorf("error uploading %s: %s", file.Name, err)
+		}
+		resp.Body.Close()
+
+		offset += n
+		progress.FileBytes += n
+		progress.TotalBytes += n
+		if opts.Progress != nil {
+			opts.Progress(*progress)
+		}
+	}
+
+	return nil
+}
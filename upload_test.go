@@ -0,0 +1,316 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// transferStub stands in for the transfer service of vCloud. It keeps what
+// is put to each file, refusing chunks that don't start where the previous
+// one ended, so that uploads can be interrupted and resumed.
+type transferStub struct {
+	sync.Mutex
+	received map[string][]byte
+	ranges   []string
+}
+
+func newTransferStub() *transferStub {
+	return &transferStub{received: make(map[string][]byte)}
+}
+
+func (s *transferStub) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+
+	s.Lock()
+	defer s.Unlock()
+
+	if r.Method != "PUT" {
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	name := path.Base(r.URL.Path)
+	if cr := r.Header.Get("Content-Range"); cr != "" {
+		s.ranges = append(s.ranges, cr)
+		var start int
+		fmt.Sscanf(cr, "bytes %d-", &start)
+		if start != len(s.received[name]) {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	data, _ := ioutil.ReadAll(r.Body)
+	s.received[name] = append(s.received[name], data...)
+}
+
+// transferred returns the number of bytes received for the file name
+func (s *transferStub) transferred(name string) int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.received[name])
+}
+
+// uploadHandler serves the vApp template being uploaded as far as transfer
+// has received it, the template is resolved once the disk is complete
+func uploadHandler(transfer *transferStub, cc *callCounter) http.Handler {
+	responses := map[string]testResponse{
+		"/api/catalog/e8a20fdf-8a78-440c-ac71-0420db59f854/action/upload": {201, nil, uploadCatalogItemExample},
+	}
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/transfer/"):
+			transfer.ServeHTTP(rw, r)
+		case r.URL.Path == "/api/vAppTemplate/vappTemplate-55555555-5555-5555-5555-555555555555":
+			tmpl := uploadTemplateExample
+			if transfer.transferred(ovfDescriptorName) > 0 {
+				status, n := types.VAppStatusUnresolved, transfer.transferred("disk1.vmdk")
+				if n == 10000 {
+					status = types.VAppStatusResolved
+				}
+				tmpl = fmt.Sprintf(uploadedTemplateExample, status, n)
+			}
+			testHandler(map[string]testResponse{r.URL.Path: {200, nil, tmpl}}, cc).ServeHTTP(rw, r)
+		default:
+			testHandler(responses, cc).ServeHTTP(rw, r)
+		}
+	})
+}
+
+// testOVFPackage writes an OVF descriptor referencing a 10000 bytes disk to
+// dir and returns the descriptor and the disk content
+func testOVFPackage(t *testing.T, dir string) (string, []byte) {
+
+	disk := bytes.Repeat([]byte("0123456789"), 1000)
+	descriptor := fmt.Sprintf(ovfDescriptorExample, len(disk))
+
+	ovfPath := filepath.Join(dir, "test.ovf")
+	assert.NoError(t, ioutil.WriteFile(ovfPath, []byte(descriptor), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "disk1.vmdk"), disk, 0644))
+
+	return ovfPath, disk
+}
+
+func Test_UploadOVF(t *testing.T) {
+	defer func(interval time.Duration) { uploadPollInterval = interval }(uploadPollInterval)
+	uploadPollInterval = time.Millisecond
+
+	dir, err := ioutil.TempDir("", "govcloudair")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	ovfPath, disk := testOVFPackage(t, dir)
+
+	transfer := newTransferStub()
+	ctx, err := setupTestContext(authHandler(uploadHandler(transfer, new(callCounter))))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	cat := NewCatalog(ctx.Client)
+	cat.Catalog.HREF = ctx.Server.URL + "/api/catalog/e8a20fdf-8a78-440c-ac71-0420db59f854"
+
+	var reports []UploadProgress
+	opts := UploadOptions{
+		ChunkSize: 4096,
+		Progress:  func(p UploadProgress) { reports = append(reports, p) },
+	}
+
+	vapptemplate, err := cat.UploadOVF(ovfPath, "uploaded", "", opts)
+	if assert.NoError(t, err) {
+		assert.Equal(t, types.VAppStatusResolved, types.VAppStatus(vapptemplate.VAppTemplate.Status))
+		assert.Equal(t, ctx.Server.URL+"/api/vAppTemplate/vappTemplate-55555555-5555-5555-5555-555555555555", vapptemplate.VAppTemplate.HREF)
+	}
+
+	assert.Contains(t, string(transfer.received[ovfDescriptorName]), `ovf:href="disk1.vmdk"`)
+	assert.Equal(t, disk, transfer.received["disk1.vmdk"])
+	assert.Equal(t, []string{"bytes 0-4095/10000", "bytes 4096-8191/10000", "bytes 8192-9999/10000"}, transfer.ranges)
+
+	if assert.Len(t, reports, 4) {
+		assert.Equal(t, UploadProgress{File: "disk1.vmdk", FileSize: 10000, TotalSize: 10000}, reports[0])
+		assert.Equal(t, int64(4096), reports[1].TotalBytes)
+		assert.Equal(t, int64(10000), reports[3].FileBytes)
+		assert.Equal(t, int64(10000), reports[3].TotalBytes)
+	}
+}
+
+func Test_ResumeUploadOVA(t *testing.T) {
+	defer func(interval time.Duration) { uploadPollInterval = interval }(uploadPollInterval)
+	uploadPollInterval = time.Millisecond
+
+	dir, err := ioutil.TempDir("", "govcloudair")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	ovfPath, disk := testOVFPackage(t, dir)
+
+	// Pack the descriptor and the disk as an OVA archive
+	ovaPath := filepath.Join(dir, "test.ova")
+	ova, err := os.Create(ovaPath)
+	if !assert.NoError(t, err) {
+		return
+	}
+	tw := tar.NewWriter(ova)
+	for _, name := range []string{"test.ovf", "disk1.vmdk"} {
+		data, _ := ioutil.ReadFile(filepath.Join(dir, name))
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg})
+		tw.Write(data)
+	}
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, ova.Close())
+
+	// vCloud already has the descriptor and the start of the disk
+	transfer := newTransferStub()
+	transfer.received[ovfDescriptorName], _ = ioutil.ReadFile(ovfPath)
+	transfer.received["disk1.vmdk"] = disk[:6000]
+
+	ctx, err := setupTestContext(authHandler(uploadHandler(transfer, new(callCounter))))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	vapptemplate := NewVAppTemplate(ctx.Client)
+	vapptemplate.VAppTemplate.HREF = ctx.Server.URL + "/api/vAppTemplate/vappTemplate-55555555-5555-5555-5555-555555555555"
+
+	var reports []UploadProgress
+	opts := UploadOptions{Progress: func(p UploadProgress) { reports = append(reports, p) }}
+
+	if assert.NoError(t, vapptemplate.ResumeUploadOVA(ovaPath, opts)) {
+		assert.Equal(t, types.VAppStatusResolved, types.VAppStatus(vapptemplate.VAppTemplate.Status))
+	}
+
+	assert.Equal(t, disk, transfer.received["disk1.vmdk"])
+	assert.Equal(t, []string{"bytes 6000-9999/10000"}, transfer.ranges)
+	if assert.Len(t, reports, 2) {
+		assert.Equal(t, int64(6000), reports[0].FileBytes)
+		assert.Equal(t, int64(6000), reports[0].TotalBytes)
+		assert.Equal(t, int64(10000), reports[1].TotalBytes)
+	}
+}
+
+func Test_UploadTimeout(t *testing.T) {
+	defer func(interval time.Duration) { uploadPollInterval = interval }(uploadPollInterval)
+	uploadPollInterval = time.Millisecond
+
+	dir, err := ioutil.TempDir("", "govcloudair")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	ovfPath, _ := testOVFPackage(t, dir)
+
+	// vCloud has every byte but never resolves the template
+	cc := new(callCounter)
+	ctx, err := setupTestContext(authHandler(testHandler(map[string]testResponse{
+		"/api/vAppTemplate/vappTemplate-55555555-5555-5555-5555-555555555555": {200, nil, fmt.Sprintf(uploadedTemplateExample, types.VAppStatusUnresolved, 10000)},
+	}, cc)))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	vapptemplate := NewVAppTemplate(ctx.Client)
+	vapptemplate.VAppTemplate.HREF = ctx.Server.URL + "/api/vAppTemplate/vappTemplate-55555555-5555-5555-5555-555555555555"
+
+	err = vapptemplate.ResumeUploadOVF(ovfPath, UploadOptions{Timeout: 20 * time.Millisecond})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "timed out")
+	}
+	assert.True(t, cc.Pop() > 2)
+}
+
+func Test_OpenOVFPackage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "govcloudair")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	ovfPath, _ := testOVFPackage(t, dir)
+
+	p, err := openOVF(ovfPath)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(10000), p.files["disk1.vmdk"].Size())
+		assert.NoError(t, p.Close())
+	}
+
+	// The disk doesn't have the size given by the descriptor
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "disk1.vmdk"), []byte("short"), 0644))
+	_, err = openOVF(ovfPath)
+	assert.Error(t, err)
+
+	// The descriptor references a file that doesn't exist
+	assert.NoError(t, os.Remove(filepath.Join(dir, "disk1.vmdk")))
+	_, err = openOVF(ovfPath)
+	assert.Error(t, err)
+
+	_, err = parseOVFDescriptor([]byte(strings.Replace(ovfDescriptorExample, "disk1.vmdk", "../disk1.vmdk", 1)))
+	assert.Error(t, err)
+
+	_, err = openOVA(ovfPath)
+	assert.Error(t, err)
+}
+
+var ovfDescriptorExample = `<?xml version="1.0" encoding="UTF-8"?>
+<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData">
+    <References>
+        <File ovf:href="disk1.vmdk" ovf:id="file1" ovf:size="%d"/>
+    </References>
+    <DiskSection>
+        <Info>Virtual disk information</Info>
+        <Disk ovf:capacity="1" ovf:capacityAllocationUnits="byte * 2^30" ovf:diskId="vmdisk1" ovf:fileRef="file1" ovf:format="http://www.vmware.com/interfaces/specifications/vmdk.html#streamOptimized"/>
+    </DiskSection>
+    <VirtualSystem ovf:id="test">
+        <Info>A virtual machine</Info>
+        <Name>test</Name>
+    </VirtualSystem>
+</Envelope>
+`
+
+var uploadCatalogItemExample = `<?xml version="1.0" encoding="UTF-8"?>
+<CatalogItem xmlns="http://www.vmware.com/vcloud/v1.5" size="0" name="uploaded" id="urn:vcloud:catalogitem:5f3c4b2a-7d21-4a4b-9d3e-2b1f0c9e8a77" type="application/vnd.vmware.vcloud.catalogItem+xml" href="http://localhost:4444/api/catalogItem/5f3c4b2a-7d21-4a4b-9d3e-2b1f0c9e8a77">
+    <Link rel="up" type="application/vnd.vmware.vcloud.catalog+xml" href="http://localhost:4444/api/catalog/e8a20fdf-8a78-440c-ac71-0420db59f854"/>
+    <Description/>
+    <Entity type="application/vnd.vmware.vcloud.vAppTemplate+xml" name="uploaded" href="http://localhost:4444/api/vAppTemplate/vappTemplate-55555555-5555-5555-5555-555555555555"/>
+</CatalogItem>
+`
+
+// uploadTemplateExample is a vApp template waiting for its OVF descriptor
+var uploadTemplateExample = `<?xml version="1.0" encoding="UTF-8"?>
+<VAppTemplate xmlns="http://www.vmware.com/vcloud/v1.5" ovfDescriptorUploaded="false" goldMaster="false" status="0" name="uploaded" id="urn:vcloud:vapptemplate:55555555-5555-5555-5555-555555555555" href="http://localhost:4444/api/vAppTemplate/vappTemplate-55555555-5555-5555-5555-555555555555" type="application/vnd.vmware.vcloud.vAppTemplate+xml">
+    <Files>
+        <File size="-1" bytesTransferred="0" name="descriptor.ovf">
+            <Link rel="upload:default" href="http://localhost:4444/transfer/5555/descriptor.ovf"/>
+        </File>
+    </Files>
+</VAppTemplate>
+`
+
+// uploadedTemplateExample is a vApp template with the given status, listing
+// the bytes of its disk transferred so far
+var uploadedTemplateExample = `<?xml version="1.0" encoding="UTF-8"?>
+<VAppTemplate xmlns="http://www.vmware.com/vcloud/v1.5" ovfDescriptorUploaded="true" goldMaster="false" status="%d" name="uploaded" id="urn:vcloud:vapptemplate:55555555-5555-5555-5555-555555555555" href="http://localhost:4444/api/vAppTemplate/vappTemplate-55555555-5555-5555-5555-555555555555" type="application/vnd.vmware.vcloud.vAppTemplate+xml">
+    <Files>
+        <File size="10000" bytesTransferred="%d" name="disk1.vmdk">
+            <Link rel="upload:default" href="http://localhost:4444/transfer/5555/disk1.vmdk"/>
+        </File>
+    </Files>
+</VAppTemplate>
+`
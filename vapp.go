@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"os"
 	"strconv"
+	"time"
 
 	types "github.com/vmware/govcloudair/types/v56"
 )
@@ -57,6 +58,48 @@ func (v *VApp) Refresh() error {
 	return nil
 }
 
+// FindVMByName finds a VM by name in this vApp
+func (v *VApp) FindVMByName(vm string) (VM, error) {
+
+	err := v.Refresh()
+	if err != nil {
+		return VM{}, fmt.Errorf("error refreshing vapp: %s", err)
+	}
+
+	if v.VApp.Children == nil {
+		return VM{}, fmt.Errorf("vApp doesn't contain any children")
+	}
+
+	for _, child := range v.VApp.Children.VM {
+		if child.Name == vm {
+
+			u, err := url.ParseRequestURI(child.HREF)
+
+			if err != nil {
+				return VM{}, fmt.Errorf("error decoding vapp response: %s", err)
+			}
+
+			// Querying the VM
+			req := v.c.NewRequest(map[string]string{}, "GET", u, nil)
+
+			resp, err := checkResp(v.c.DoHTTP(req))
+			if err != nil {
+				return VM{}, fmt.Errorf("error retrieving VM: %s", err)
+			}
+
+			newvm := NewVM(v.c)
+
+			if err = decodeBody(resp, newvm.VM); err != nil {
+				return VM{}, fmt.Errorf("error decoding VM response: %s", err)
+			}
+
+			return *newvm, nil
+
+		}
+	}
+	return VM{}, fmt.Errorf("can't find VM: %s", vm)
+}
+
 // ComposeVApp composes a new vapp
 func (v *VApp) ComposeVApp(orgvdcnetwork OrgVDCNetwork, vapptemplate VAppTemplate, name string, description string) (Task, error) {
 
@@ -64,6 +107,11 @@ func (v *VApp) ComposeVApp(orgvdcnetwork OrgVDCNetwork, vapptemplate VAppTemplat
 		return Task{}, fmt.Errorf("can't compose a new vApp, objects passed are not valid")
 	}
 
+	connectionIndex := 0
+	if connections := vapptemplate.VAppTemplate.Children.VM[0].NetworkConnectionSection.NetworkConnection; len(connections) > 0 {
+		connectionIndex = connections[0].NetworkConnectionIndex
+	}
+
 	// Build request XML
 	vcomp := &types.ComposeVAppParams{
 		Ovf:         "http://schemas.dmtf.org/ovf/envelope/1",
@@ -76,14 +124,16 @@ func (v *VApp) ComposeVApp(orgvdcnetwork OrgVDCNetwork, vapptemplate VAppTemplat
 		InstantiationParams: &types.InstantiationParams{
 			NetworkConfigSection: &types.NetworkConfigSection{
 				Info: "Configuration parameters for logical networks",
-				NetworkConfig: &types.VAppNetworkConfiguration{
-					NetworkName: orgvdcnetwork.OrgVDCNetwork.Name,
-					Configuration: &types.NetworkConfiguration{
-						FenceMode: "bridged",
-						ParentNetwork: &types.Reference{
-							HREF: orgvdcnetwork.OrgVDCNetwork.HREF,
-							Name: orgvdcnetwork.OrgVDCNetwork.Name,
-							Type: orgvdcnetwork.OrgVDCNetwork.Type,
+				NetworkConfig: []*types.VAppNetworkConfiguration{
+					{
+						NetworkName: orgvdcnetwork.OrgVDCNetwork.Name,
+						Configuration: &types.NetworkConfiguration{
+							FenceMode: "bridged",
+							ParentNetwork: &types.Reference{
+								HREF: orgvdcnetwork.OrgVDCNetwork.HREF,
+								Name: orgvdcnetwork.OrgVDCNetwork.Name,
+								Type: orgvdcnetwork.OrgVDCNetwork.Type,
+							},
 						},
 					},
 				},
@@ -100,11 +150,13 @@ func (v *VApp) ComposeVApp(orgvdcnetwork OrgVDCNetwork, vapptemplate VAppTemplat
 					HREF: vapptemplate.VAppTemplate.Children.VM[0].NetworkConnectionSection.HREF,
 					Info: "Network config for sourced item",
 					PrimaryNetworkConnectionIndex: vapptemplate.VAppTemplate.Children.VM[0].NetworkConnectionSection.PrimaryNetworkConnectionIndex,
-					NetworkConnection: &types.NetworkConnection{
-						Network:                 orgvdcnetwork.OrgVDCNetwork.Name,
-						NetworkConnectionIndex:  vapptemplate.VAppTemplate.Children.VM[0].NetworkConnectionSection.NetworkConnection.NetworkConnectionIndex,
-						IsConnected:             true,
-						IPAddressAllocationMode: "POOL",
+					NetworkConnection: []*types.NetworkConnection{
+						{
+							Network:                 orgvdcnetwork.OrgVDCNetwork.Name,
+							NetworkConnectionIndex:  connectionIndex,
+							IsConnected:             true,
+							IPAddressAllocationMode: "POOL",
+						},
 					},
 				},
 			},
@@ -152,9 +204,153 @@ func (v *VApp) ComposeVApp(orgvdcnetwork OrgVDCNetwork, vapptemplate VAppTemplat
 
 }
 
-// PowerOn powers this vApp on
+// CloneOptions controls the state of a vApp created by Clone
+type CloneOptions struct {
+	Deploy  bool
+	PowerOn bool
+}
+
+// Clone copies this vApp to a new vApp named name in vdc, which can be the
+// VDC this vApp lives in. It returns the new vApp along with the task
+// tracking the copy.
+func (v *VApp) Clone(vdc Vdc, name, description string, opts CloneOptions) (VApp, Task, error) {
+	return v.cloneVApp(vdc, name, description, opts, false)
+}
+
+// Move moves this vApp to vdc, keeping its name. The vApp must be powered
+// off. It returns the vApp in its new location along with the task tracking
+// the move.
+func (v *VApp) Move(vdc Vdc) (VApp, Task, error) {
+	return v.cloneVApp(vdc, v.VApp.Name, v.VApp.Description, CloneOptions{}, true)
+}
+
+func (v *VApp) cloneVApp(vdc Vdc, name, description string, opts CloneOptions, deleteSource bool) (VApp, Task, error) {
+
+	if v.VApp.HREF == "" || vdc.Vdc == nil || vdc.Vdc.HREF == "" {
+		return VApp{}, Task{}, fmt.Errorf("can't clone vApp, objects passed are not valid")
+	}
+
+	s, err := url.ParseRequestURI(vdc.Vdc.HREF)
+	if err != nil {
+		return VApp{}, Task{}, fmt.Errorf("error parsing vdc href: %s", err)
+	}
+	s.Path += "/action/cloneVApp"
+
+	params := &types.CloneVAppParams{
+		Xmlns:       types.NsVCloud,
+		Name:        name,
+		Deploy:      opts.Deploy,
+		PowerOn:     opts.PowerOn,
+		Description: description,
+		Source: &types.Reference{
+			HREF: v.VApp.HREF,
+			Name: v.VApp.Name,
+			Type: types.MimeVApp,
+		},
+		IsSourceDelete: deleteSource,
+	}
+
+	newvapp := NewVApp(v.c)
+
+	if err = executeRequest(v.c, types.HTTPPost, s, types.MimeCloneVAppParams, params, newvapp.VApp); err != nil {
+		return VApp{}, Task{}, fmt.Errorf("error cloning vApp: %s", err)
+	}
+
+	if newvapp.VApp.Tasks == nil || len(newvapp.VApp.Tasks.Task) == 0 {
+		return VApp{}, Task{}, fmt.Errorf("error cloning vApp: no task returned")
+	}
+
+	task := NewTask(v.c)
+	task.Task = newvapp.VApp.Tasks.Task[0]
+
+	// The request was successful
+	return *newvapp, *task, nil
+
+}
+
+// CaptureOptions controls the vApp template created by Capture
+type CaptureOptions struct {
+	// CustomizeOnInstantiate runs guest customization on the VMs of vApps
+	// instantiated from the template.
+	CustomizeOnInstantiate bool
+	// TargetCatalogItem when set is the HREF of an existing catalog item
+	// whose vApp template gets replaced by the capture.
+	TargetCatalogItem string
+}
+
+// Capture captures this powered off vApp as a new vApp template named name
+// in catalog. It refreshes the vApp first and fails when it isn't powered
+// off. It returns the new vApp template along with the task tracking the
+// capture.
+func (v *VApp) Capture(catalog Catalog, name, description string, opts CaptureOptions) (VAppTemplate, Task, error) {
+
+	if v.VApp.HREF == "" || catalog.Catalog == nil || catalog.Catalog.HREF == "" {
+		return VAppTemplate{}, Task{}, fmt.Errorf("can't capture vApp, objects passed are not valid")
+	}
+
+	status, err := v.GetState()
+	if err != nil {
+		return VAppTemplate{}, Task{}, err
+	}
+	if status != types.VAppStatusPoweredOff {
+		return VAppTemplate{}, Task{}, fmt.Errorf("can't capture vApp in state %s, it needs to be powered off", status)
+	}
+
+	s, err := url.ParseRequestURI(catalog.Catalog.HREF)
+	if err != nil {
+		return VAppTemplate{}, Task{}, fmt.Errorf("error parsing catalog href: %s", err)
+	}
+	s.Path += "/action/captureVApp"
+
+	params := &types.CaptureVAppParams{
+		Xmlns:       types.NsVCloud,
+		Ovf:         types.NsOvf,
+		Name:        name,
+		Description: description,
+		Source: &types.Reference{
+			HREF: v.VApp.HREF,
+			Name: v.VApp.Name,
+			Type: types.MimeVApp,
+		},
+		CustomizationSection: &types.CustomizationSection{
+			Info:                   "VApp template customization section",
+			CustomizeOnInstantiate: opts.CustomizeOnInstantiate,
+		},
+	}
+
+	if opts.TargetCatalogItem != "" {
+		params.TargetCatalogItem = &types.Reference{
+			HREF: opts.TargetCatalogItem,
+			Type: types.MimeCatalogItem,
+		}
+	}
+
+	vapptemplate := NewVAppTemplate(v.c)
+
+	if err = executeRequest(v.c, types.HTTPPost, s, types.MimeCaptureVAppParams, params, vapptemplate.VAppTemplate); err != nil {
+		return VAppTemplate{}, Task{}, fmt.Errorf("error capturing vApp: %s", err)
+	}
+
+	if vapptemplate.VAppTemplate.Tasks == nil || len(vapptemplate.VAppTemplate.Tasks.Task) == 0 {
+		return VAppTemplate{}, Task{}, fmt.Errorf("error capturing vApp: no task returned")
+	}
+
+	task := NewTask(v.c)
+	task.Task = vapptemplate.VAppTemplate.Tasks.Task[0]
+
+	// The request was successful
+	return *vapptemplate, *task, nil
+
+}
+
+// PowerOn powers this vApp on, it fails without a request when the last
+// known state of the vApp doesn't allow it
 func (v *VApp) PowerOn() (Task, error) {
 
+	if !v.CanPerform(PowerActionPowerOn) {
+		return Task{}, fmt.Errorf("can't power on vApp in state %s", types.VAppStatus(v.VApp.Status))
+	}
+
 	s, _ := url.ParseRequestURI(v.VApp.HREF)
 	s.Path += "/power/action/powerOn"
 
@@ -176,9 +372,14 @@ func (v *VApp) PowerOn() (Task, error) {
 
 }
 
-// PowerOff powers this vApp off
+// PowerOff powers this vApp off, it fails without a request when the last
+// known state of the vApp doesn't allow it
 func (v *VApp) PowerOff() (Task, error) {
 
+	if !v.CanPerform(PowerActionPowerOff) {
+		return Task{}, fmt.Errorf("can't power off vApp in state %s", types.VAppStatus(v.VApp.Status))
+	}
+
 	s, _ := url.ParseRequestURI(v.VApp.HREF)
 	s.Path += "/power/action/powerOff"
 
@@ -272,9 +473,14 @@ func (v *VApp) Suspend() (Task, error) {
 
 }
 
-// Shutdown shuts this vApp down
+// Shutdown shuts this vApp down, it fails without a request when the last
+// known state of the vApp doesn't allow it
 func (v *VApp) Shutdown() (Task, error) {
 
+	if !v.CanPerform(PowerActionShutdown) {
+		return Task{}, fmt.Errorf("can't shut down vApp in state %s", types.VAppStatus(v.VApp.Status))
+	}
+
 	s, _ := url.ParseRequestURI(v.VApp.HREF)
 	s.Path += "/power/action/shutdown"
 
@@ -296,81 +502,123 @@ func (v *VApp) Shutdown() (Task, error) {
 
 }
 
-// Undeploy removes the deployment for this vApp
-func (v *VApp) Undeploy() (Task, error) {
+// DeployOptions are the options for a deploy request, they map to the
+// attributes of DeployVAppParams.
+type DeployOptions struct {
+	// PowerOn powers on the vApp or VM once it's deployed.
+	PowerOn bool
+	// DeploymentLeaseSeconds is the lease in seconds for the deployment, 0
+	// uses the organization default.
+	DeploymentLeaseSeconds int
+	// ForceCustomization forces guest customization on deployment.
+	ForceCustomization bool
+}
 
-	vu := &types.UndeployVAppParams{
-		Xmlns:               "http://www.vmware.com/vcloud/v1.5",
-		UndeployPowerAction: "powerOff",
+// UndeployOptions are the options for an undeploy request, they map to the
+// elements of UndeployVAppParams.
+type UndeployOptions struct {
+	// PowerAction is the action applied to the VMs when undeploying. One of
+	// the types.UndeployPowerAction* constants. Empty leaves the element out
+	// and vCloud powers the VMs off, only types.UndeployPowerActionDefault
+	// uses the actions of the vApp's startup section.
+	PowerAction string
+	// SaveState suspends the VMs before undeploying. It's deprecated since
+	// API version 1.5 in favour of types.UndeployPowerActionSuspend.
+	SaveState bool
+}
+
+func deploy(c Client, href string, opts DeployOptions) (Task, error) {
+
+	vu := &types.DeployVAppParams{
+		Xmlns:                  types.NsVCloud,
+		PowerOn:                opts.PowerOn,
+		DeploymentLeaseSeconds: opts.DeploymentLeaseSeconds,
+		ForceCustomization:     opts.ForceCustomization,
 	}
 
-	output, err := xml.MarshalIndent(vu, "  ", "    ")
+	s, err := url.ParseRequestURI(href)
 	if err != nil {
-		fmt.Printf("error: %v\n", err)
+		return Task{}, fmt.Errorf("error parsing entity href: %s", err)
 	}
+	s.Path += "/action/deploy"
 
-	debug := os.Getenv("GOVCLOUDAIR_DEBUG")
+	return executeTaskRequest(c, types.HTTPPost, s, types.MimeDeployVAppParams, vu)
+}
 
-	if debug == "true" {
-		fmt.Printf("\n\nXML DEBUG: %s\n\n", string(output))
-	}
+func powerAction(c Client, href string, action PowerAction) (Task, error) {
 
-	b := bytes.NewBufferString(xml.Header + string(output))
+	s, err := url.ParseRequestURI(href)
+	if err != nil {
+		return Task{}, fmt.Errorf("error parsing entity href: %s", err)
+	}
+	s.Path += "/power/action/" + string(action)
 
-	s, _ := url.ParseRequestURI(v.VApp.HREF)
-	s.Path += "/action/undeploy"
+	return executeTaskRequest(c, types.HTTPPost, s, "", nil)
+}
 
-	req := v.c.NewRequest(map[string]string{}, "POST", s, b)
+func undeploy(c Client, href string, opts UndeployOptions) (Task, error) {
 
-	req.Header.Add("Content-Type", "application/vnd.vmware.vcloud.undeployVAppParams+xml")
+	vu := &types.UndeployVAppParams{
+		Xmlns:               types.NsVCloud,
+		SaveState:           opts.SaveState,
+		UndeployPowerAction: opts.PowerAction,
+	}
 
-	resp, err := checkResp(v.c.DoHTTP(req))
+	s, err := url.ParseRequestURI(href)
 	if err != nil {
-		return Task{}, fmt.Errorf("error undeploy vApp: %s", err)
+		return Task{}, fmt.Errorf("error parsing entity href: %s", err)
 	}
+	s.Path += "/action/undeploy"
 
-	task := NewTask(v.c)
+	return executeTaskRequest(c, types.HTTPPost, s, types.MimeUndeployVAppParams, vu)
+}
 
-	if err = decodeBody(resp, task.Task); err != nil {
-		return Task{}, fmt.Errorf("error decoding Task response: %s", err)
+// Undeploy removes the deployment for this vApp, powering off its VMs
+func (v *VApp) Undeploy() (Task, error) {
+	return v.UndeployWithOptions(UndeployOptions{PowerAction: types.UndeployPowerActionPowerOff})
+}
+
+// UndeployWithOptions removes the deployment for this vApp using the provided options
+func (v *VApp) UndeployWithOptions(opts UndeployOptions) (Task, error) {
+
+	task, err := undeploy(v.c, v.VApp.HREF, opts)
+	if err != nil {
+		return Task{}, fmt.Errorf("error undeploying vApp: %s", err)
 	}
 
 	// The request was successful
-	return *task, nil
+	return task, nil
 
 }
 
-// Deploy this vApp
+// Deploy this vApp without powering it on
 func (v *VApp) Deploy() (Task, error) {
+	return v.DeployWithOptions(DeployOptions{})
+}
 
-	vu := &types.DeployVAppParams{
-		Xmlns:   "http://www.vmware.com/vcloud/v1.5",
-		PowerOn: false,
-	}
+// DeployWithOptions deploys this vApp using the provided options
+func (v *VApp) DeployWithOptions(opts DeployOptions) (Task, error) {
 
-	output, err := xml.MarshalIndent(vu, "  ", "    ")
+	task, err := deploy(v.c, v.VApp.HREF, opts)
 	if err != nil {
-		fmt.Printf("error: %v\n", err)
+		return Task{}, fmt.Errorf("error deploying vApp: %s", err)
 	}
 
-	debug := os.Getenv("GOVCLOUDAIR_DEBUG")
+	// The request was successful
+	return task, nil
 
-	if debug == "true" {
-		fmt.Printf("\n\nXML DEBUG: %s\n\n", string(output))
-	}
+}
 
-	b := bytes.NewBufferString(xml.Header + string(output))
+// Delete this vApp
+func (v *VApp) Delete() (Task, error) {
 
 	s, _ := url.ParseRequestURI(v.VApp.HREF)
-	s.Path += "/action/deploy"
-
-	req := v.c.NewRequest(map[string]string{}, "POST", s, b)
 
-	req.Header.Add("Content-Type", "application/vnd.vmware.vcloud.deployVAppParams+xml")
+	req := v.c.NewRequest(map[string]string{}, "DELETE", s, nil)
 
 	resp, err := checkResp(v.c.DoHTTP(req))
 	if err != nil {
-		return Task{}, fmt.Errorf("error undeploy vApp: %s", err)
+		return Task{}, fmt.Errorf("error deleting vApp: %s", err)
 	}
 
 	task := NewTask(v.c)
@@ -384,27 +632,111 @@ func (v *VApp) Deploy() (Task, error) {
 
 }
 
-// Delete this vApp
-func (v *VApp) Delete() (Task, error) {
+// CreateSnapshot creates a snapshot of all the VMs in this vApp, replacing
+// any existing one. When memory is true the snapshot includes the memory of
+// powered on VMs, when quiesce is true the guest file systems are quiesced
+// first (requires VMware tools).
+func (v *VApp) CreateSnapshot(name, description string, memory, quiesce bool) (Task, error) {
+	return createSnapshot(v.c, v.VApp.HREF, name, description, memory, quiesce)
+}
 
-	s, _ := url.ParseRequestURI(v.VApp.HREF)
+// RevertToCurrentSnapshot reverts all the VMs in this vApp to their current snapshot
+func (v *VApp) RevertToCurrentSnapshot() (Task, error) {
+	return revertToCurrentSnapshot(v.c, v.VApp.HREF)
+}
 
-	req := v.c.NewRequest(map[string]string{}, "DELETE", s, nil)
+// RemoveAllSnapshots removes all the snapshots of the VMs in this vApp
+func (v *VApp) RemoveAllSnapshots() (Task, error) {
+	return removeAllSnapshots(v.c, v.VApp.HREF)
+}
+
+// GetSnapshotSection retrieves the snapshot section of this vApp
+func (v *VApp) GetSnapshotSection() (*types.SnapshotSection, error) {
+	return getSnapshotSection(v.c, v.VApp.HREF)
+}
+
+// GetLeaseSettings retrieves the runtime and storage lease settings of this vApp
+func (v *VApp) GetLeaseSettings() (*types.LeaseSettingsSection, error) {
+
+	s, err := url.ParseRequestURI(v.VApp.HREF)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing vApp href: %s", err)
+	}
+	s.Path += "/leaseSettingsSection/"
+
+	req := v.c.NewRequest(map[string]string{}, types.HTTPGet, s, nil)
 
 	resp, err := checkResp(v.c.DoHTTP(req))
 	if err != nil {
-		return Task{}, fmt.Errorf("error deleting vApp: %s", err)
+		return nil, fmt.Errorf("error retrieving lease settings: %s", err)
 	}
 
-	task := NewTask(v.c)
+	section := new(types.LeaseSettingsSection)
 
-	if err = decodeBody(resp, task.Task); err != nil {
-		return Task{}, fmt.Errorf("error decoding Task response: %s", err)
+	if err = decodeBody(resp, section); err != nil {
+		return nil, fmt.Errorf("error decoding lease settings response: %s", err)
 	}
 
 	// The request was successful
-	return *task, nil
+	return section, nil
+}
+
+// SetLeaseSettings updates the runtime and storage leases of this vApp, a
+// value of 0 means the lease never expires. Setting the leases to their
+// current values renews them.
+func (v *VApp) SetLeaseSettings(deploymentLeaseSeconds, storageLeaseSeconds int) (Task, error) {
+
+	s, err := url.ParseRequestURI(v.VApp.HREF)
+	if err != nil {
+		return Task{}, fmt.Errorf("error parsing vApp href: %s", err)
+	}
+	s.Path += "/leaseSettingsSection/"
+
+	section := &types.LeaseSettingsSection{
+		Ovf:                      types.NsOvf,
+		Xmlns:                    types.NsVCloud,
+		Info:                     "Lease settings section",
+		DeploymentLeaseInSeconds: &deploymentLeaseSeconds,
+		StorageLeaseInSeconds:    &storageLeaseSeconds,
+	}
+
+	task, err := executeTaskRequest(v.c, types.HTTPPut, s, types.MimeLeaseSettingsSection, section)
+	if err != nil {
+		return Task{}, fmt.Errorf("error updating lease settings: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
+
+// LeaseExpiration returns the earliest of the runtime and storage lease
+// expirations of this vApp, it returns false when neither lease expires.
+func (v *VApp) LeaseExpiration() (time.Time, bool, error) {
+
+	var earliest time.Time
+	found := false
+
+	if v.VApp.LeaseSettingsSection == nil {
+		return earliest, false, nil
+	}
 
+	for _, exp := range []string{v.VApp.LeaseSettingsSection.DeploymentLeaseExpiration, v.VApp.LeaseSettingsSection.StorageLeaseExpiration} {
+		if exp == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, exp)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("error parsing lease expiration: %s", err)
+		}
+
+		if !found || t.Before(earliest) {
+			earliest = t
+			found = true
+		}
+	}
+
+	return earliest, found, nil
 }
 
 // RunCustomizationScript runs a customization script on the vApp
@@ -469,13 +801,14 @@ func (v *VApp) RunCustomizationScript(computername, script string) (Task, error)
 
 }
 
-// GetStatus gets the status for this vApp
+// GetStatus gets the name of the status for this vApp, GetState gets the
+// typed status
 func (v *VApp) GetStatus() (string, error) {
-	err := v.Refresh()
+	status, err := v.GetState()
 	if err != nil {
-		return "", fmt.Errorf("error refreshing vapp: %v", err)
+		return "", err
 	}
-	return types.VAppStatuses[v.VApp.Status], nil
+	return status.String(), nil
 }
 
 // ChangeCPUcount change the cpu count for this vApp
@@ -6,10 +6,12 @@ package govcloudair
 
 import (
 	"encoding/xml"
+	"net/http"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
 )
 
 func Test_ComposeVApp(t *testing.T) {
@@ -94,6 +96,15 @@ func Test_PowerOff(t *testing.T) {
 	ctx, err := setupTestContext(authHandler(testHandler(vappResponses, cc)))
 	if assert.NoError(t, err) {
 		xmlTxt := strings.Replace(vappExample, "http://localhost:4444", ctx.Server.URL, -1)
+		if assert.NoError(t, xml.Unmarshal([]byte(xmlTxt), ctx.VApp.VApp)) {
+			// a powered off vApp can't be powered off
+			_, err := ctx.VApp.PowerOff()
+			assert.Error(t, err)
+			assert.Equal(t, 0, cc.Pop())
+		}
+
+		xmlTxt = strings.Replace(poweredOnVAppExample, "http://localhost:4444", ctx.Server.URL, -1)
+		ctx.VApp.VApp = &types.VApp{}
 		if assert.NoError(t, xml.Unmarshal([]byte(xmlTxt), ctx.VApp.VApp)) {
 			task, err := ctx.VApp.PowerOff()
 			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
@@ -150,6 +161,15 @@ func Test_Shutdown(t *testing.T) {
 	ctx, err := setupTestContext(authHandler(testHandler(vappResponses, cc)))
 	if assert.NoError(t, err) {
 		xmlTxt := strings.Replace(vappExample, "http://localhost:4444", ctx.Server.URL, -1)
+		if assert.NoError(t, xml.Unmarshal([]byte(xmlTxt), ctx.VApp.VApp)) {
+			// a powered off vApp can't be shut down
+			_, err := ctx.VApp.Shutdown()
+			assert.Error(t, err)
+			assert.Equal(t, 0, cc.Pop())
+		}
+
+		xmlTxt = strings.Replace(poweredOnVAppExample, "http://localhost:4444", ctx.Server.URL, -1)
+		ctx.VApp.VApp = &types.VApp{}
 		if assert.NoError(t, xml.Unmarshal([]byte(xmlTxt), ctx.VApp.VApp)) {
 			task, err := ctx.VApp.Shutdown()
 			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
@@ -187,6 +207,69 @@ func Test_Deploy(t *testing.T) {
 	}
 }
 
+func Test_DeployWithOptions(t *testing.T) {
+	cc := new(callCounter)
+	var params types.DeployVAppParams
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/vApp/vapp-00000000-0000-0000-0000-000000000000/action/deploy" {
+			xml.NewDecoder(r.Body).Decode(&params)
+		}
+		testHandler(vappResponses, cc).ServeHTTP(rw, r)
+	})
+
+	ctx, err := setupTestContext(authHandler(handler))
+	if assert.NoError(t, err) {
+		xmlTxt := strings.Replace(vappExample, "http://localhost:4444", ctx.Server.URL, -1)
+		if assert.NoError(t, xml.Unmarshal([]byte(xmlTxt), ctx.VApp.VApp)) {
+			task, err := ctx.VApp.DeployWithOptions(DeployOptions{PowerOn: true, DeploymentLeaseSeconds: 3600, ForceCustomization: true})
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Equal(t, "success", task.Task.Status)
+				assert.True(t, params.PowerOn)
+				assert.Equal(t, 3600, params.DeploymentLeaseSeconds)
+				assert.True(t, params.ForceCustomization)
+			}
+		}
+	}
+}
+
+func Test_UndeployWithOptions(t *testing.T) {
+	cc := new(callCounter)
+	var params types.UndeployVAppParams
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/vApp/vapp-00000000-0000-0000-0000-000000000000/action/undeploy" {
+			xml.NewDecoder(r.Body).Decode(&params)
+		}
+		testHandler(vappResponses, cc).ServeHTTP(rw, r)
+	})
+
+	ctx, err := setupTestContext(authHandler(handler))
+	if assert.NoError(t, err) {
+		xmlTxt := strings.Replace(vappExample, "http://localhost:4444", ctx.Server.URL, -1)
+		if assert.NoError(t, xml.Unmarshal([]byte(xmlTxt), ctx.VApp.VApp)) {
+			task, err := ctx.VApp.UndeployWithOptions(UndeployOptions{PowerAction: types.UndeployPowerActionShutdown})
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Equal(t, "success", task.Task.Status)
+				assert.Equal(t, "shutdown", params.UndeployPowerAction)
+				assert.False(t, params.SaveState)
+			}
+
+			params = types.UndeployVAppParams{}
+			_, err = ctx.VApp.UndeployWithOptions(UndeployOptions{SaveState: true})
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.True(t, params.SaveState)
+				assert.Empty(t, params.UndeployPowerAction)
+			}
+
+			params = types.UndeployVAppParams{}
+			_, err = ctx.VApp.Undeploy()
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Equal(t, "powerOff", params.UndeployPowerAction)
+				assert.False(t, params.SaveState)
+			}
+		}
+	}
+}
+
 func Test_Delete(t *testing.T) {
 	cc := new(callCounter)
 	ctx, err := setupTestContext(authHandler(testHandler(vappResponses, cc)))
@@ -201,6 +284,174 @@ func Test_Delete(t *testing.T) {
 	}
 }
 
+func Test_Snapshots(t *testing.T) {
+	cc := new(callCounter)
+	responses := map[string]testResponse{
+		"/api/vApp/vapp-00000000-0000-0000-0000-000000000000/action/createSnapshot":          {200, nil, taskExample},
+		"/api/vApp/vapp-00000000-0000-0000-0000-000000000000/action/revertToCurrentSnapshot": {200, nil, taskExample},
+		"/api/vApp/vapp-00000000-0000-0000-0000-000000000000/action/removeAllSnapshots":      {200, nil, taskExample},
+		"/api/vApp/vapp-00000000-0000-0000-0000-000000000000/snapshotSection":                {200, nil, snapshotSectionExample},
+	}
+	ctx, err := setupTestContext(authHandler(testHandler(responses, cc)))
+	if assert.NoError(t, err) {
+		xmlTxt := strings.Replace(vappExample, "http://localhost:4444", ctx.Server.URL, -1)
+		if assert.NoError(t, xml.Unmarshal([]byte(xmlTxt), ctx.VApp.VApp)) {
+			if assert.NotNil(t, ctx.VApp.VApp.SnapshotSection) {
+				assert.Empty(t, ctx.VApp.VApp.SnapshotSection.Snapshot)
+			}
+
+			task, err := ctx.VApp.CreateSnapshot("snapshot", "description", false, true)
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Equal(t, "success", task.Task.Status)
+			}
+
+			task, err = ctx.VApp.RevertToCurrentSnapshot()
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Equal(t, "success", task.Task.Status)
+			}
+
+			task, err = ctx.VApp.RemoveAllSnapshots()
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Equal(t, "success", task.Task.Status)
+			}
+
+			section, err := ctx.VApp.GetSnapshotSection()
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Len(t, section.Snapshot, 1)
+			}
+		}
+	}
+}
+
+func Test_LeaseSettings(t *testing.T) {
+	cc := new(callCounter)
+	var section types.LeaseSettingsSection
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			xml.NewDecoder(r.Body).Decode(&section)
+			testHandler(map[string]testResponse{
+				"/api/vApp/vapp-00000000-0000-0000-0000-000000000000/leaseSettingsSection/": {200, nil, taskExample},
+			}, cc).ServeHTTP(rw, r)
+			return
+		}
+		testHandler(map[string]testResponse{
+			"/api/vApp/vapp-00000000-0000-0000-0000-000000000000/leaseSettingsSection/": {200, nil, leaseSettingsSectionExample},
+		}, cc).ServeHTTP(rw, r)
+	})
+
+	ctx, err := setupTestContext(authHandler(handler))
+	if assert.NoError(t, err) {
+		xmlTxt := strings.Replace(vappExample, "http://localhost:4444", ctx.Server.URL, -1)
+		if assert.NoError(t, xml.Unmarshal([]byte(xmlTxt), ctx.VApp.VApp)) {
+			if assert.NotNil(t, ctx.VApp.VApp.LeaseSettingsSection) {
+				_, ok, err := ctx.VApp.LeaseExpiration()
+				assert.NoError(t, err)
+				assert.False(t, ok)
+			}
+
+			lease, err := ctx.VApp.GetLeaseSettings()
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				if assert.NotNil(t, lease.DeploymentLeaseInSeconds) && assert.NotNil(t, lease.StorageLeaseInSeconds) {
+					assert.Equal(t, 604800, *lease.DeploymentLeaseInSeconds)
+					assert.Equal(t, 2592000, *lease.StorageLeaseInSeconds)
+				}
+				assert.Equal(t, "2014-09-12T10:40:09.943+02:00", lease.DeploymentLeaseExpiration)
+			}
+
+			task, err := ctx.VApp.SetLeaseSettings(0, 3600)
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Equal(t, "success", task.Task.Status)
+				if assert.NotNil(t, section.DeploymentLeaseInSeconds) && assert.NotNil(t, section.StorageLeaseInSeconds) {
+					assert.Equal(t, 0, *section.DeploymentLeaseInSeconds)
+					assert.Equal(t, 3600, *section.StorageLeaseInSeconds)
+				}
+			}
+		}
+	}
+}
+
+func Test_CloneAndMove(t *testing.T) {
+	cc := new(callCounter)
+	var params types.CloneVAppParams
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/vdc/00000000-0000-0000-0000-000000000000/action/cloneVApp" {
+			xml.NewDecoder(r.Body).Decode(&params)
+		}
+		testHandler(map[string]testResponse{
+			"/api/vdc/00000000-0000-0000-0000-000000000000/action/cloneVApp": {201, nil, instantiatedvappExample},
+		}, cc).ServeHTTP(rw, r)
+	})
+
+	ctx, err := setupTestContext(authHandler(handler))
+	if assert.NoError(t, err) {
+		xmlTxt := strings.Replace(vappExample, "http://localhost:4444", ctx.Server.URL, -1)
+		if assert.NoError(t, xml.Unmarshal([]byte(xmlTxt), ctx.VApp.VApp)) {
+			clone, task, err := ctx.VApp.Clone(*ctx.VDC, "myVApp", "cloned", CloneOptions{PowerOn: true})
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Equal(t, "myVApp", clone.VApp.Name)
+				assert.Equal(t, "running", task.Task.Status)
+				assert.Equal(t, "myVApp", params.Name)
+				assert.Equal(t, ctx.VApp.VApp.HREF, params.Source.HREF)
+				assert.True(t, params.PowerOn)
+				assert.False(t, params.IsSourceDelete)
+			}
+
+			_, _, err = ctx.VApp.Move(*ctx.VDC)
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Equal(t, "Test API GO4", params.Name)
+				assert.True(t, params.IsSourceDelete)
+			}
+		}
+	}
+}
+
+func Test_Capture(t *testing.T) {
+	cc := new(callCounter)
+	var params types.CaptureVAppParams
+	vapp := vappExample
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/catalog/e8a20fdf-8a78-440c-ac71-0420db59f854/action/captureVApp" {
+			xml.NewDecoder(r.Body).Decode(&params)
+		}
+		testHandler(map[string]testResponse{
+			"/api/vApp/vapp-00000000-0000-0000-0000-000000000000":                  {200, nil, vapp},
+			"/api/catalog/e8a20fdf-8a78-440c-ac71-0420db59f854/action/captureVApp": {201, nil, capturedvapptemplateExample},
+		}, cc).ServeHTTP(rw, r)
+	})
+
+	ctx, err := setupTestContext(authHandler(handler))
+	if assert.NoError(t, err) {
+		catalog := NewCatalog(ctx.Client)
+		catalog.Catalog.HREF = ctx.Server.URL + "/api/catalog/e8a20fdf-8a78-440c-ac71-0420db59f854"
+
+		xmlTxt := strings.Replace(vappExample, "http://localhost:4444", ctx.Server.URL, -1)
+		if assert.NoError(t, xml.Unmarshal([]byte(xmlTxt), ctx.VApp.VApp)) {
+			vapptemplate, task, err := ctx.VApp.Capture(*catalog, "golden", "golden image", CaptureOptions{CustomizeOnInstantiate: true})
+			if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+				assert.Equal(t, "golden", vapptemplate.VAppTemplate.Name)
+				assert.Equal(t, "running", task.Task.Status)
+				assert.Equal(t, "golden", params.Name)
+				assert.Equal(t, ctx.VApp.VApp.HREF, params.Source.HREF)
+				if assert.NotNil(t, params.CustomizationSection) {
+					assert.True(t, params.CustomizationSection.CustomizeOnInstantiate)
+				}
+				assert.Nil(t, params.TargetCatalogItem)
+			}
+
+			// The state is refreshed rather than taken from the cached vApp
+			vapp = poweredOnVAppExample
+			_, _, err = ctx.VApp.Capture(*catalog, "golden", "golden image", CaptureOptions{})
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+
+			vapp = strings.Replace(vappExample, `status="8"`, `status="0"`, 1)
+			_, _, err = ctx.VApp.Capture(*catalog, "golden", "golden image", CaptureOptions{})
+			assert.Error(t, err)
+			assert.Equal(t, 1, cc.Pop())
+		}
+	}
+}
+
 func Test_RunCustomizationScript(t *testing.T) {
 	cc := new(callCounter)
 	responses := map[string]testResponse{
@@ -645,3 +896,14 @@ var vappExample = `
 	  </Children>
 	</VApp>
 	`
+
+var leaseSettingsSectionExample = `
+<?xml version="1.0" encoding="UTF-8"?>
+<LeaseSettingsSection xmlns="http://www.vmware.com/vcloud/v1.5" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" href="http://localhost:4444/api/vApp/vapp-00000000-0000-0000-0000-000000000000/leaseSettingsSection/" type="application/vnd.vmware.vcloud.leaseSettingsSection+xml" ovf:required="false">
+  <ovf:Info>Lease settings section</ovf:Info>
+  <Link href="http://localhost:4444/api/vApp/vapp-00000000-0000-0000-0000-000000000000/leaseSettingsSection/" rel="edit" type="application/vnd.vmware.vcloud.leaseSettingsSection+xml"/>
+  <DeploymentLeaseInSeconds>604800</DeploymentLeaseInSeconds>
+  <StorageLeaseInSeconds>2592000</StorageLeaseInSeconds>
+  <DeploymentLeaseExpiration>2014-09-12T10:40:09.943+02:00</DeploymentLeaseExpiration>
+</LeaseSettingsSection>
+`
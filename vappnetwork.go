@@ -0,0 +1,302 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"fmt"
+	"net/url"
+
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// IsolatedVAppNetwork returns the configuration of a vApp network named name
+// that is only reachable from the VMs of the vApp
+func IsolatedVAppNetwork(name string, scope *types.IPScope) *types.VAppNetworkConfiguration {
+	return &types.VAppNetworkConfiguration{
+		NetworkName: name,
+		Configuration: &types.NetworkConfiguration{
+			IPScopes:  &types.IPScopes{IPScope: []*types.IPScope{scope}},
+			FenceMode: types.FenceModeIsolated,
+		},
+	}
+}
+
+// BridgedVAppNetwork returns the configuration of a vApp network connected
+// directly to an org vdc network, with the name of that network
+func BridgedVAppNetwork(parent OrgVDCNetwork) *types.VAppNetworkConfiguration {
+	return &types.VAppNetworkConfiguration{
+		NetworkName: parent.OrgVDCNetwork.Name,
+		Configuration: &types.NetworkConfiguration{
+			ParentNetwork: &types.Reference{
+				HREF: parent.OrgVDCNetwork.HREF,
+				Name: parent.OrgVDCNetwork.Name,
+				Type: parent.OrgVDCNetwork.Type,
+			},
+			FenceMode: types.FenceModeBridged,
+		},
+	}
+}
+
+// FencedVAppNetwork returns the configuration of a vApp network that fences
+// the VMs of the vApp off an org vdc network. The vApp network has the IP
+// scope of the org vdc network and reaches it through IP translation, so
+// that several copies of a vApp can run with the same addresses. The
+// translated addresses are kept across deployments.
+func FencedVAppNetwork(parent OrgVDCNetwork) (*types.VAppNetworkConfiguration, error) {
+
+	parentScope := networkIPScope(parent.OrgVDCNetwork.Configuration)
+	if parentScope == nil {
+		return nil, fmt.Errorf("org vdc network %s has no IP scope", parent.OrgVDCNetwork.Name)
+	}
+
+	scope := *parentScope
+	scope.IsInherited = false
+	scope.AllocatedIPAddresses, scope.SubAllocations = nil, nil
+
+	config := BridgedVAppNetwork(parent)
+	config.Configuration.IPScopes = &types.IPScopes{IPScope: []*types.IPScope{&scope}}
+	config.Configuration.FenceMode = types.FenceModeNAT
+	config.Configuration.RetainNetInfoAcrossDeployments = true
+	config.Configuration.Features = &types.NetworkFeatures{
+		NatService: &types.NatService{
+			IsEnabled: true,
+			NatType:   "ipTranslation",
+			Policy:    "allowTrafficIn",
+		},
+	}
+
+	return config, nil
+}
+
+// validateVAppNetwork checks that the parent network, IP scopes and features
+// of config match its fence mode
+func validateVAppNetwork(config *types.VAppNetworkConfiguration) error {
+
+	if config.NetworkName == "" {
+		return fmt.Errorf("vApp network needs a name")
+	}
+
+	network := config.Configuration
+	if network == nil {
+		return fmt.Errorf("vApp network %s has no configuration", config.NetworkName)
+	}
+
+	switch network.FenceMode {
+	case types.FenceModeBridged:
+		if network.ParentNetwork == nil {
+			return fmt.Errorf("bridged vApp network %s needs a parent network", config.NetworkName)
+		}
+		if network.Features != nil {
+			return fmt.Errorf("bridged vApp network %s can't have network features", config.NetworkName)
+		}
+		// The IP scope is inherited from the parent network
+		return nil
+	case types.FenceModeNAT:
+		if network.ParentNetwork == nil {
+			return fmt.Errorf("NAT-routed vApp network %s needs a parent network", config.NetworkName)
+		}
+	case types.FenceModeIsolated:
+		if network.ParentNetwork != nil {
+			return fmt.Errorf("isolated vApp network %s can't have a parent network", config.NetworkName)
+		}
+		if features := network.Features; features != nil {
+			if features.FirewallService != nil || features.NatService != nil || features.LoadBalancerService != nil || features.StaticRoutingService != nil {
+				return fmt.Errorf("isolated vApp network %s only supports DHCP", config.NetworkName)
+			}
+		}
+	default:
+		return fmt.Errorf("vApp network %s has unknown fence mode %s", config.NetworkName, network.FenceMode)
+	}
+
+	scope := networkIPScope(network)
+	if scope == nil {
+		return fmt.Errorf("vApp network %s needs an IP scope", config.NetworkName)
+	}
+	for _, s := range network.IPScopes.IPScope {
+		if s.IsInherited {
+			continue
+		}
+		if err := validateIPScope(s); err != nil {
+			return fmt.Errorf("vApp network %s: %s", config.NetworkName, err)
+		}
+	}
+
+	if network.Features != nil && network.Features.DhcpService != nil && network.Features.DhcpService.IPRange != nil {
+		r := network.Features.DhcpService.IPRange
+		from, ok1 := ipToUint32(r.StartAddress)
+		to, ok2 := ipToUint32(r.EndAddress)
+		gw, _ := ipToUint32(scope.Gateway)
+		mask, _ := ipToUint32(scope.Netmask)
+		if !ok1 || !ok2 || to < from || from&mask != gw&mask || to&mask != gw&mask {
+			return fmt.Errorf("DHCP range %s-%s of vApp network %s is outside of its subnet", r.StartAddress, r.EndAddress, config.NetworkName)
+		}
+		if scope.IPRanges != nil && overlapsRanges(from, to, scope.IPRanges.IPRange) {
+			return fmt.Errorf("DHCP range %s-%s of vApp network %s overlaps its static pools", r.StartAddress, r.EndAddress, config.NetworkName)
+		}
+	}
+
+	return nil
+}
+
+// GetNetworkConfigSection retrieves the vApp networks of this vApp
+func (v *VApp) GetNetworkConfigSection() (*types.NetworkConfigSection, error) {
+
+	s, err := url.ParseRequestURI(v.VApp.HREF)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing vApp href: %s", err)
+	}
+	s.Path += "/networkConfigSection/"
+
+	req := v.c.NewRequest(map[string]string{}, types.HTTPGet, s, nil)
+
+	resp, err := checkResp(v.c.DoHTTP(req))
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving network config section: %s", err)
+	}
+
+	section := new(types.NetworkConfigSection)
+
+	if err = decodeBody(resp, section); err != nil {
+		return nil, fmt.Errorf("error decoding network config section response: %s", err)
+	}
+
+	// The request was successful
+	return section, nil
+}
+
+// SetNetworkConfigSection replaces the vApp networks of this vApp with the
+// networks of section. vApp networks no VM is connected to are left out by
+// vCloud.
+func (v *VApp) SetNetworkConfigSection(section *types.NetworkConfigSection) (Task, error) {
+
+	names := make(map[string]bool)
+	for _, config := range section.NetworkConfig {
+		if err := validateVAppNetwork(config); err != nil {
+			return Task{}, err
+		}
+		if names[config.NetworkName] {
+			return Task{}, fmt.Errorf("vApp network %s is defined twice", config.NetworkName)
+		}
+		names[config.NetworkName] = true
+	}
+
+	s, err := url.ParseRequestURI(v.VApp.HREF)
+	if err != nil {
+		return Task{}, fmt.Errorf("error parsing vApp href: %s", err)
+	}
+	s.Path += "/networkConfigSection/"
+
+	params := &types.NetworkConfigSection{
+		Ovf:   types.NsOvf,
+		Xmlns: types.NsVCloud,
+		Info:  "Configuration parameters for logical networks",
+	}
+	for _, config := range section.NetworkConfig {
+		c := *config
+		c.Link = nil
+		params.NetworkConfig = append(params.NetworkConfig, &c)
+	}
+
+	task, err := executeTaskRequest(v.c, types.HTTPPut, s, types.MimeNetworkConfigSection, params)
+	if err != nil {
+		return Task{}, fmt.Errorf("error updating network config section: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
+
+// VAppNetworks retrieves the vApp networks of this vApp
+func (v *VApp) VAppNetworks() ([]*types.VAppNetworkConfiguration, error) {
+
+	section, err := v.GetNetworkConfigSection()
+	if err != nil {
+		return nil, err
+	}
+
+	return section.NetworkConfig, nil
+}
+
+// modifyVAppNetworks retrieves the vApp networks of this vApp, lets mutate
+// change them and submits them
+func (v *VApp) modifyVAppNetworks(mutate func(section *types.NetworkConfigSection) error) (Task, error) {
+
+	section, err := v.GetNetworkConfigSection()
+	if err != nil {
+		return Task{}, err
+	}
+
+	if err := mutate(section); err != nil {
+		return Task{}, err
+	}
+
+	return v.SetNetworkConfigSection(section)
+}
+
+// findVAppNetworkIndex returns the index of the vApp network named name in
+// section, or -1
+func findVAppNetworkIndex(section *types.NetworkConfigSection, name string) int {
+
+	for i, config := range section.NetworkConfig {
+		if config.NetworkName == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// AddVAppNetwork adds the vApp network config to this vApp
+func (v *VApp) AddVAppNetwork(config *types.VAppNetworkConfiguration) (Task, error) {
+
+	if err := validateVAppNetwork(config); err != nil {
+		return Task{}, err
+	}
+
+	return v.modifyVAppNetworks(func(section *types.NetworkConfigSection) error {
+
+		if findVAppNetworkIndex(section, config.NetworkName) != -1 {
+			return fmt.Errorf("vApp network %s already exists", config.NetworkName)
+		}
+
+		section.NetworkConfig = append(section.NetworkConfig, config)
+		return nil
+	})
+}
+
+// UpdateVAppNetwork replaces the vApp network of this vApp with the name of
+// config
+func (v *VApp) UpdateVAppNetwork(config *types.VAppNetworkConfiguration) (Task, error) {
+
+	if err := validateVAppNetwork(config); err != nil {
+		return Task{}, err
+	}
+
+	return v.modifyVAppNetworks(func(section *types.NetworkConfigSection) error {
+
+		i := findVAppNetworkIndex(section, config.NetworkName)
+		if i == -1 {
+			return fmt.Errorf("can't find vApp network: %s", config.NetworkName)
+		}
+
+		section.NetworkConfig[i] = config
+		return nil
+	})
+}
+
+// RemoveVAppNetwork removes the vApp network named name from this vApp
+func (v *VApp) RemoveVAppNetwork(name string) (Task, error) {
+
+	return v.modifyVAppNetworks(func(section *types.NetworkConfigSection) error {
+
+		i := findVAppNetworkIndex(section, name)
+		if i == -1 {
+			return fmt.Errorf("can't find vApp network: %s", name)
+		}
+
+		section.NetworkConfig = append(section.NetworkConfig[:i], section.NetworkConfig[i+1:]...)
+		return nil
+	})
+}
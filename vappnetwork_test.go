@@ -0,0 +1,178 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+func Test_VAppNetworks(t *testing.T) {
+	cc := new(callCounter)
+	submitted := new(types.NetworkConfigSection)
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body := networkConfigSectionExample
+		if r.Method == "PUT" {
+			*submitted = types.NetworkConfigSection{}
+			xml.NewDecoder(r.Body).Decode(submitted)
+			body = taskExample
+		}
+		testHandler(map[string]testResponse{
+			"/api/vApp/vapp-00000000-0000-0000-0000-000000000000/networkConfigSection/": {200, nil, body},
+		}, cc).ServeHTTP(rw, r)
+	})
+
+	ctx, err := setupTestContext(authHandler(handler))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	xmlTxt := strings.Replace(vappExample, "http://localhost:4444", ctx.Server.URL, -1)
+	if !assert.NoError(t, xml.Unmarshal([]byte(xmlTxt), ctx.VApp.VApp)) {
+		return
+	}
+	if assert.NotNil(t, ctx.VApp.VApp.NetworkConfigSection) {
+		assert.Len(t, ctx.VApp.VApp.NetworkConfigSection.NetworkConfig, 2)
+	}
+
+	networks, err := ctx.VApp.VAppNetworks()
+	if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) && assert.Len(t, networks, 2) {
+		assert.Equal(t, "M916272752-5793-default-isolated", networks[0].NetworkName)
+		assert.Equal(t, types.FenceModeBridged, networks[0].Configuration.FenceMode)
+		assert.True(t, networks[0].Configuration.IPScopes.IPScope[0].IsInherited)
+		assert.Equal(t, types.FenceModeIsolated, networks[1].Configuration.FenceMode)
+	}
+
+	backend := IsolatedVAppNetwork("backend", &types.IPScope{
+		Gateway:   "10.10.0.1",
+		Netmask:   "255.255.255.0",
+		IsEnabled: true,
+		IPRanges:  &types.IPRanges{IPRange: []*types.IPRange{{StartAddress: "10.10.0.10", EndAddress: "10.10.0.50"}}},
+	})
+	backend.Configuration.Features = &types.NetworkFeatures{
+		DhcpService: &types.DhcpService{
+			IsEnabled: true,
+			IPRange:   &types.IPRange{StartAddress: "10.10.0.100", EndAddress: "10.10.0.150"},
+		},
+	}
+
+	_, err = ctx.VApp.AddVAppNetwork(backend)
+	if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) && assert.Len(t, submitted.NetworkConfig, 3) {
+		assert.Equal(t, types.NsVCloud, submitted.Xmlns)
+		assert.Nil(t, submitted.NetworkConfig[0].Link)
+		added := submitted.NetworkConfig[2]
+		assert.Equal(t, "backend", added.NetworkName)
+		assert.Equal(t, "10.10.0.1", added.Configuration.IPScopes.IPScope[0].Gateway)
+		assert.Equal(t, "10.10.0.150", added.Configuration.Features.DhcpService.IPRange.EndAddress)
+	}
+
+	_, err = ctx.VApp.AddVAppNetwork(IsolatedVAppNetwork("none", backend.Configuration.IPScopes.IPScope[0]))
+	assert.Error(t, err)
+	assert.Equal(t, 1, cc.Pop())
+
+	// DHCP addresses can't come from the static pools
+	backend.Configuration.Features.DhcpService.IPRange.StartAddress = "10.10.0.40"
+	_, err = ctx.VApp.AddVAppNetwork(backend)
+	assert.Error(t, err)
+
+	// nor hold a whole static pool
+	backend.Configuration.Features.DhcpService.IPRange = &types.IPRange{StartAddress: "10.10.0.5", EndAddress: "10.10.0.150"}
+	_, err = ctx.VApp.AddVAppNetwork(backend)
+	assert.Error(t, err)
+
+	backend.Configuration.Features = &types.NetworkFeatures{NatService: &types.NatService{IsEnabled: true}}
+	_, err = ctx.VApp.AddVAppNetwork(backend)
+	assert.Error(t, err)
+	assert.Equal(t, 0, cc.Pop())
+
+	parent := NewOrgVDCNetwork(ctx.Client)
+	if !assert.NoError(t, xml.Unmarshal([]byte(strings.TrimSpace(orgvdcnetExample)), parent.OrgVDCNetwork)) {
+		return
+	}
+
+	fenced, err := FencedVAppNetwork(*parent)
+	if assert.NoError(t, err) {
+		_, err = ctx.VApp.AddVAppNetwork(fenced)
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) && assert.Len(t, submitted.NetworkConfig, 3) {
+			added := submitted.NetworkConfig[2]
+			assert.Equal(t, "networkName", added.NetworkName)
+			assert.Equal(t, types.FenceModeNAT, added.Configuration.FenceMode)
+			assert.True(t, added.Configuration.RetainNetInfoAcrossDeployments)
+			assert.Equal(t, parent.OrgVDCNetwork.HREF, added.Configuration.ParentNetwork.HREF)
+			assert.Equal(t, "192.168.109.1", added.Configuration.IPScopes.IPScope[0].Gateway)
+			assert.Equal(t, "ipTranslation", added.Configuration.Features.NatService.NatType)
+		}
+	}
+
+	none := IsolatedVAppNetwork("none", &types.IPScope{Gateway: "196.254.254.254", Netmask: "255.255.0.0", DNS1: "196.254.254.253"})
+	_, err = ctx.VApp.UpdateVAppNetwork(none)
+	if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) && assert.Len(t, submitted.NetworkConfig, 2) {
+		assert.Equal(t, "196.254.254.253", submitted.NetworkConfig[1].Configuration.IPScopes.IPScope[0].DNS1)
+	}
+
+	_, err = ctx.VApp.RemoveVAppNetwork("M916272752-5793-default-isolated")
+	if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) && assert.Len(t, submitted.NetworkConfig, 1) {
+		assert.Equal(t, "none", submitted.NetworkConfig[0].NetworkName)
+	}
+
+	_, err = ctx.VApp.RemoveVAppNetwork("INVALID")
+	assert.Error(t, err)
+	assert.Equal(t, 1, cc.Pop())
+
+	_, err = ctx.VApp.SetNetworkConfigSection(&types.NetworkConfigSection{NetworkConfig: []*types.VAppNetworkConfiguration{none, none}})
+	assert.Error(t, err)
+	assert.Equal(t, 0, cc.Pop())
+}
+
+var networkConfigSectionExample = `
+	<NetworkConfigSection xmlns="http://www.vmware.com/vcloud/v1.5" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" href="http://localhost:4444/api/vApp/vapp-00000000-0000-0000-0000-000000000000/networkConfigSection/" ovf:required="false" type="application/vnd.vmware.vcloud.networkConfigSection+xml">
+	  <ovf:Info>The configuration parameters for logical networks</ovf:Info>
+	  <Link href="http://localhost:4444/api/vApp/vapp-00000000-0000-0000-0000-000000000000/networkConfigSection/" rel="edit" type="application/vnd.vmware.vcloud.networkConfigSection+xml"/>
+	  <NetworkConfig networkName="M916272752-5793-default-isolated">
+	    <Link href="http://localhost:4444/api/admin/network/e68434e9-a9ae-47d8-b809-743e70307085/action/reset" rel="repair"/>
+	    <Description>This isolated network was created with Create VDC.</Description>
+	    <Configuration>
+	      <IpScopes>
+	        <IpScope>
+	          <IsInherited>true</IsInherited>
+	          <Gateway>192.168.99.1</Gateway>
+	          <Netmask>255.255.255.0</Netmask>
+	          <IsEnabled>true</IsEnabled>
+	          <IpRanges>
+	            <IpRange>
+	              <StartAddress>192.168.99.2</StartAddress>
+	              <EndAddress>192.168.99.100</EndAddress>
+	            </IpRange>
+	          </IpRanges>
+	        </IpScope>
+	      </IpScopes>
+	      <ParentNetwork href="http://localhost:4444/api/admin/network/8d0cbfe2-25b3-4a1f-b608-5ffeabc7a53d" id="8d0cbfe2-25b3-4a1f-b608-5ffeabc7a53d" name="M916272752-5793-default-isolated"/>
+	      <FenceMode>bridged</FenceMode>
+	      <RetainNetInfoAcrossDeployments>false</RetainNetInfoAcrossDeployments>
+	    </Configuration>
+	    <IsDeployed>false</IsDeployed>
+	  </NetworkConfig>
+	  <NetworkConfig networkName="none">
+	    <Description>This is a special place-holder used for disconnected network interfaces.</Description>
+	    <Configuration>
+	      <IpScopes>
+	        <IpScope>
+	          <IsInherited>false</IsInherited>
+	          <Gateway>196.254.254.254</Gateway>
+	          <Netmask>255.255.0.0</Netmask>
+	          <Dns1>196.254.254.254</Dns1>
+	        </IpScope>
+	      </IpScopes>
+	      <FenceMode>isolated</FenceMode>
+	    </Configuration>
+	    <IsDeployed>false</IsDeployed>
+	  </NetworkConfig>
+	</NetworkConfigSection>
+	`
@@ -0,0 +1,224 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// PowerAction is an operation that changes the power or deployment state of a vApp or VM
+type PowerAction string
+
+// The power actions known to the state machine
+const (
+	PowerActionPowerOn  PowerAction = "powerOn"
+	PowerActionPowerOff PowerAction = "powerOff"
+	PowerActionReboot   PowerAction = "reboot"
+	PowerActionReset    PowerAction = "reset"
+	PowerActionSuspend  PowerAction = "suspend"
+	PowerActionShutdown PowerAction = "shutdown"
+	PowerActionDeploy   PowerAction = "deploy"
+	PowerActionUndeploy PowerAction = "undeploy"
+	PowerActionDelete   PowerAction = "delete"
+)
+
+// powerTransitions lists the actions vCloud accepts for an entity in a given
+// status, regardless of whether the entity is deployed.
+var powerTransitions = map[types.VAppStatus][]PowerAction{
+	types.VAppStatusFailedCreation:        {PowerActionDelete},
+	types.VAppStatusResolved:              {PowerActionPowerOn, PowerActionDeploy},
+	types.VAppStatusDeployed:              {PowerActionPowerOn, PowerActionUndeploy},
+	types.VAppStatusSuspended:             {PowerActionPowerOn, PowerActionPowerOff, PowerActionUndeploy},
+	types.VAppStatusPoweredOn:             {PowerActionPowerOff, PowerActionReboot, PowerActionReset, PowerActionSuspend, PowerActionShutdown, PowerActionUndeploy},
+	types.VAppStatusPoweredOff:            {PowerActionPowerOn, PowerActionDeploy},
+	types.VAppStatusMixed:                 {PowerActionPowerOn, PowerActionPowerOff, PowerActionSuspend, PowerActionShutdown, PowerActionUndeploy},
+	types.VAppStatusVAppUndeployed:        {PowerActionPowerOn, PowerActionDeploy},
+	types.VAppStatusVAppPartiallyDeployed: {PowerActionPowerOn, PowerActionPowerOff, PowerActionSuspend, PowerActionShutdown, PowerActionUndeploy},
+}
+
+// CanPerform reports whether action is a valid transition for an entity in
+// the given status. A powered off entity can only be deleted once it's
+// undeployed, and undeployed only while it's deployed.
+func CanPerform(status types.VAppStatus, deployed bool, action PowerAction) bool {
+	switch action {
+	case PowerActionDelete:
+		if status == types.VAppStatusFailedCreation {
+			return true
+		}
+		return !deployed && (status == types.VAppStatusResolved || status == types.VAppStatusPoweredOff || status == types.VAppStatusVAppUndeployed)
+	case PowerActionUndeploy:
+		if deployed && status == types.VAppStatusPoweredOff {
+			return true
+		}
+	}
+
+	for _, a := range powerTransitions[status] {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// GetState gets the typed status for this vApp
+func (v *VApp) GetState() (types.VAppStatus, error) {
+	err := v.Refresh()
+	if err != nil {
+		return types.VAppStatusUnknown, fmt.Errorf("error refreshing vapp: %v", err)
+	}
+	return types.VAppStatus(v.VApp.Status), nil
+}
+
+// CanPerform reports whether action is a valid transition for the last known state of this vApp
+func (v *VApp) CanPerform(action PowerAction) bool {
+	return CanPerform(types.VAppStatus(v.VApp.Status), v.VApp.Deployed, action)
+}
+
+// powerStateful is the state machine shared by vApps and VMs
+type powerStateful interface {
+	GetState() (types.VAppStatus, error)
+	PowerOn() (Task, error)
+	PowerOff() (Task, error)
+}
+
+func ensurePoweredOn(e powerStateful) error {
+
+	status, err := e.GetState()
+	if err != nil {
+		return err
+	}
+
+	if status == types.VAppStatusPoweredOn {
+		return nil
+	}
+
+	task, err := e.PowerOn()
+	if err != nil {
+		return err
+	}
+
+	return task.WaitTaskCompletion()
+}
+
+func ensurePoweredOff(e powerStateful) error {
+
+	status, err := e.GetState()
+	if err != nil {
+		return err
+	}
+
+	switch status {
+	case types.VAppStatusResolved, types.VAppStatusDeployed, types.VAppStatusPoweredOff, types.VAppStatusVAppUndeployed:
+		return nil
+	}
+
+	task, err := e.PowerOff()
+	if err != nil {
+		return err
+	}
+
+	return task.WaitTaskCompletion()
+}
+
+// EnsurePoweredOn powers this vApp on and waits for it, it does nothing when
+// the vApp is already powered on.
+func (v *VApp) EnsurePoweredOn() error {
+	return ensurePoweredOn(v)
+}
+
+// EnsurePoweredOff powers this vApp off and waits for it, it does nothing
+// when the vApp is already powered off, including deployed with its VMs
+// powered off.
+func (v *VApp) EnsurePoweredOff() error {
+	return ensurePoweredOff(v)
+}
+
+// EnsureDeleted deletes this vApp and waits for it, undeploying it first when
+// needed. It does nothing when the vApp no longer exists.
+func (v *VApp) EnsureDeleted() error {
+
+	if v.VApp.HREF == "" {
+		return fmt.Errorf("cannot delete, Object is empty")
+	}
+
+	u, _ := url.ParseRequestURI(v.VApp.HREF)
+
+	req := v.c.NewRequest(map[string]string{}, "GET", u, nil)
+
+	resp, err := v.c.DoHTTP(req)
+	if err != nil {
+		return fmt.Errorf("error retrieving vApp: %s", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil
+	}
+
+	resp, err = checkResp(resp, nil)
+	if err != nil {
+		return fmt.Errorf("error retrieving vApp: %s", err)
+	}
+
+	vapp := &types.VApp{}
+
+	if err = decodeBody(resp, vapp); err != nil {
+		return fmt.Errorf("error decoding vApp response: %s", err)
+	}
+
+	v.VApp = vapp
+
+	if !v.CanPerform(PowerActionDelete) {
+		if !v.CanPerform(PowerActionUndeploy) {
+			return fmt.Errorf("can't delete vApp in state %s", types.VAppStatus(v.VApp.Status))
+		}
+
+		task, err := v.Undeploy()
+		if err != nil {
+			return err
+		}
+
+		if err = task.WaitTaskCompletion(); err != nil {
+			return err
+		}
+	}
+
+	task, err := v.Delete()
+	if err != nil {
+		return err
+	}
+
+	return task.WaitTaskCompletion()
+}
+
+// GetState gets the typed status for this VM
+func (v *VM) GetState() (types.VAppStatus, error) {
+	err := v.Refresh()
+	if err != nil {
+		return types.VAppStatusUnknown, fmt.Errorf("error refreshing VM: %v", err)
+	}
+	return types.VAppStatus(v.VM.Status), nil
+}
+
+// CanPerform reports whether action is a valid transition for the last known state of this VM
+func (v *VM) CanPerform(action PowerAction) bool {
+	return CanPerform(types.VAppStatus(v.VM.Status), v.VM.Deployed, action)
+}
+
+// EnsurePoweredOn powers this VM on and waits for it, it does nothing when
+// the VM is already powered on.
+func (v *VM) EnsurePoweredOn() error {
+	return ensurePoweredOn(v)
+}
+
+// EnsurePoweredOff powers this VM off and waits for it, it does nothing when
+// the VM is already powered off.
+func (v *VM) EnsurePoweredOff() error {
+	return ensurePoweredOff(v)
+}
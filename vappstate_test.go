@@ -0,0 +1,239 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+func Test_CanPerform(t *testing.T) {
+	assert.True(t, CanPerform(types.VAppStatusPoweredOff, false, PowerActionPowerOn))
+	assert.True(t, CanPerform(types.VAppStatusPoweredOff, false, PowerActionDelete))
+	assert.False(t, CanPerform(types.VAppStatusPoweredOff, false, PowerActionPowerOff))
+	assert.False(t, CanPerform(types.VAppStatusPoweredOff, false, PowerActionUndeploy))
+
+	assert.True(t, CanPerform(types.VAppStatusPoweredOff, true, PowerActionUndeploy))
+	assert.False(t, CanPerform(types.VAppStatusPoweredOff, true, PowerActionDelete))
+
+	assert.True(t, CanPerform(types.VAppStatusPoweredOn, true, PowerActionShutdown))
+	assert.True(t, CanPerform(types.VAppStatusPoweredOn, true, PowerActionUndeploy))
+	assert.False(t, CanPerform(types.VAppStatusPoweredOn, true, PowerActionPowerOn))
+	assert.False(t, CanPerform(types.VAppStatusPoweredOn, true, PowerActionDelete))
+
+	assert.True(t, CanPerform(types.VAppStatusSuspended, true, PowerActionPowerOn))
+	assert.True(t, CanPerform(types.VAppStatusFailedCreation, false, PowerActionDelete))
+	assert.False(t, CanPerform(types.VAppStatusUnresolved, false, PowerActionPowerOn))
+
+	assert.Equal(t, "POWERED_ON", types.VAppStatusPoweredOn.String())
+	assert.Equal(t, "VAppStatus(42)", types.VAppStatus(42).String())
+}
+
+// poweredOnVAppExample is vappExample deployed and powered on
+var poweredOnVAppExample = strings.Replace(strings.Replace(vappExample, `status="8"`, `status="4"`, 1), `deployed="false"`, `deployed="true"`, 1)
+
+func vappStateHandler(vapp string, cc *callCounter) http.Handler {
+	responses := map[string]testResponse{
+		"/api/vApp/vapp-00000000-0000-0000-0000-000000000000/power/action/powerOn":  {200, nil, taskExample},
+		"/api/vApp/vapp-00000000-0000-0000-0000-000000000000/power/action/powerOff": {200, nil, taskExample},
+		"/api/vApp/vapp-00000000-0000-0000-0000-000000000000/action/undeploy":       {200, nil, taskExample},
+		"/api/vApp/vapp-00000000-0000-0000-0000-000000000000":                       {200, nil, vapp},
+		"/api/task/1b8f926c-eff5-4bea-9b13-4e49bdd50c05":                            {200, nil, taskExample},
+	}
+	deletes := map[string]testResponse{
+		"/api/vApp/vapp-00000000-0000-0000-0000-000000000000": {200, nil, taskExample},
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			testHandler(deletes, cc).ServeHTTP(rw, r)
+			return
+		}
+		testHandler(responses, cc).ServeHTTP(rw, r)
+	})
+}
+
+func Test_EnsurePoweredOn(t *testing.T) {
+	cc := new(callCounter)
+	ctx, err := setupTestContext(authHandler(vappStateHandler(vappExample, cc)))
+	if assert.NoError(t, err) {
+		ctx.VApp.VApp.HREF = ctx.Server.URL + "/api/vApp/vapp-00000000-0000-0000-0000-000000000000"
+
+		// refresh, power on, wait for the task
+		if assert.NoError(t, ctx.VApp.EnsurePoweredOn()) {
+			assert.Equal(t, 3, cc.Pop())
+		}
+	}
+
+	cc = new(callCounter)
+	ctx, err = setupTestContext(authHandler(vappStateHandler(poweredOnVAppExample, cc)))
+	if assert.NoError(t, err) {
+		ctx.VApp.VApp.HREF = ctx.Server.URL + "/api/vApp/vapp-00000000-0000-0000-0000-000000000000"
+
+		// already powered on, only the refresh
+		if assert.NoError(t, ctx.VApp.EnsurePoweredOn()) {
+			assert.Equal(t, 1, cc.Pop())
+		}
+	}
+}
+
+func Test_EnsurePoweredOff(t *testing.T) {
+	cc := new(callCounter)
+	ctx, err := setupTestContext(authHandler(vappStateHandler(vappExample, cc)))
+	if assert.NoError(t, err) {
+		ctx.VApp.VApp.HREF = ctx.Server.URL + "/api/vApp/vapp-00000000-0000-0000-0000-000000000000"
+
+		// already powered off, only the refresh
+		if assert.NoError(t, ctx.VApp.EnsurePoweredOff()) {
+			assert.Equal(t, 1, cc.Pop())
+		}
+	}
+
+	cc = new(callCounter)
+	ctx, err = setupTestContext(authHandler(vappStateHandler(poweredOnVAppExample, cc)))
+	if assert.NoError(t, err) {
+		ctx.VApp.VApp.HREF = ctx.Server.URL + "/api/vApp/vapp-00000000-0000-0000-0000-000000000000"
+
+		// refresh, power off, wait for the task
+		if assert.NoError(t, ctx.VApp.EnsurePoweredOff()) {
+			assert.Equal(t, 3, cc.Pop())
+		}
+	}
+
+	cc = new(callCounter)
+	ctx, err = setupTestContext(authHandler(vappStateHandler(strings.Replace(poweredOnVAppExample, `status="4"`, `status="2"`, 1), cc)))
+	if assert.NoError(t, err) {
+		ctx.VApp.VApp.HREF = ctx.Server.URL + "/api/vApp/vapp-00000000-0000-0000-0000-000000000000"
+
+		// deployed with its VMs off, only the refresh
+		if assert.NoError(t, ctx.VApp.EnsurePoweredOff()) {
+			assert.Equal(t, 1, cc.Pop())
+		}
+	}
+}
+
+func Test_EnsureDeleted(t *testing.T) {
+	cc := new(callCounter)
+	ctx, err := setupTestContext(authHandler(vappStateHandler(vappExample, cc)))
+	if assert.NoError(t, err) {
+		ctx.VApp.VApp.HREF = ctx.Server.URL + "/api/vApp/vapp-00000000-0000-0000-0000-000000000000"
+
+		// refresh, delete, wait for the task
+		if assert.NoError(t, ctx.VApp.EnsureDeleted()) {
+			assert.Equal(t, 3, cc.Pop())
+		}
+	}
+
+	cc = new(callCounter)
+	ctx, err = setupTestContext(authHandler(vappStateHandler(poweredOnVAppExample, cc)))
+	if assert.NoError(t, err) {
+		ctx.VApp.VApp.HREF = ctx.Server.URL + "/api/vApp/vapp-00000000-0000-0000-0000-000000000000"
+
+		// refresh, undeploy, wait, delete, wait
+		if assert.NoError(t, ctx.VApp.EnsureDeleted()) {
+			assert.Equal(t, 5, cc.Pop())
+		}
+	}
+
+	cc = new(callCounter)
+	ctx, err = setupTestContext(authHandler(testHandler(map[string]testResponse{}, cc)))
+	if assert.NoError(t, err) {
+		ctx.VApp.VApp.HREF = ctx.Server.URL + "/api/vApp/vapp-00000000-0000-0000-0000-000000000000"
+
+		// the vApp is gone already
+		if assert.NoError(t, ctx.VApp.EnsureDeleted()) {
+			assert.Equal(t, 0, cc.Pop())
+		}
+	}
+
+	cc = new(callCounter)
+	ctx, err = setupTestContext(authHandler(testHandler(map[string]testResponse{
+		"/api/vApp/vapp-00000000-0000-0000-0000-000000000000": {403, nil, ""},
+	}, cc)))
+	if assert.NoError(t, err) {
+		ctx.VApp.VApp.HREF = ctx.Server.URL + "/api/vApp/vapp-00000000-0000-0000-0000-000000000000"
+
+		// forbidden isn't gone
+		assert.Error(t, ctx.VApp.EnsureDeleted())
+		assert.Equal(t, 1, cc.Pop())
+	}
+}
+
+// poweredOnVMExample is vmExample deployed and powered on
+var poweredOnVMExample = strings.Replace(strings.Replace(vmExample, `status="8"`, `status="4"`, 1), `deployed="false"`, `deployed="true"`, 1)
+
+func vmStateHandler(vm string, cc *callCounter) http.Handler {
+	return testHandler(map[string]testResponse{
+		"/api/vApp/vm-00000000-0000-0000-0000-000000000000/power/action/powerOn":  {200, nil, taskExample},
+		"/api/vApp/vm-00000000-0000-0000-0000-000000000000/power/action/powerOff": {200, nil, taskExample},
+		"/api/vApp/vm-00000000-0000-0000-0000-000000000000":                       {200, nil, vm},
+		"/api/task/1b8f926c-eff5-4bea-9b13-4e49bdd50c05":                          {200, nil, taskExample},
+	}, cc)
+}
+
+func Test_VMEnsurePoweredOn(t *testing.T) {
+	cc := new(callCounter)
+	ctx, err := setupTestContext(authHandler(vmStateHandler(vmExample, cc)))
+	if assert.NoError(t, err) {
+		vm := NewVM(ctx.Client)
+		vm.VM.HREF = ctx.Server.URL + "/api/vApp/vm-00000000-0000-0000-0000-000000000000"
+
+		// refresh, power on, wait for the task
+		if assert.NoError(t, vm.EnsurePoweredOn()) {
+			assert.Equal(t, 3, cc.Pop())
+		}
+
+		// a powered off VM can't be shut down
+		_, err = vm.Shutdown()
+		assert.Error(t, err)
+		assert.Equal(t, 0, cc.Pop())
+	}
+
+	cc = new(callCounter)
+	ctx, err = setupTestContext(authHandler(vmStateHandler(poweredOnVMExample, cc)))
+	if assert.NoError(t, err) {
+		vm := NewVM(ctx.Client)
+		vm.VM.HREF = ctx.Server.URL + "/api/vApp/vm-00000000-0000-0000-0000-000000000000"
+
+		// already powered on, only the refresh
+		if assert.NoError(t, vm.EnsurePoweredOn()) {
+			assert.Equal(t, 1, cc.Pop())
+		}
+
+		_, err = vm.PowerOn()
+		assert.Error(t, err)
+		assert.Equal(t, 0, cc.Pop())
+	}
+}
+
+func Test_VMEnsurePoweredOff(t *testing.T) {
+	cc := new(callCounter)
+	ctx, err := setupTestContext(authHandler(vmStateHandler(vmExample, cc)))
+	if assert.NoError(t, err) {
+		vm := NewVM(ctx.Client)
+		vm.VM.HREF = ctx.Server.URL + "/api/vApp/vm-00000000-0000-0000-0000-000000000000"
+
+		// already powered off, only the refresh
+		if assert.NoError(t, vm.EnsurePoweredOff()) {
+			assert.Equal(t, 1, cc.Pop())
+		}
+	}
+
+	cc = new(callCounter)
+	ctx, err = setupTestContext(authHandler(vmStateHandler(poweredOnVMExample, cc)))
+	if assert.NoError(t, err) {
+		vm := NewVM(ctx.Client)
+		vm.VM.HREF = ctx.Server.URL + "/api/vApp/vm-00000000-0000-0000-0000-000000000000"
+
+		// refresh, power off, wait for the task
+		if assert.NoError(t, vm.EnsurePoweredOff()) {
+			assert.Equal(t, 3, cc.Pop())
+			assert.True(t, vm.CanPerform(PowerActionShutdown))
+		}
+	}
+}
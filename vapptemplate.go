@@ -5,6 +5,9 @@
 package govcloudair
 
 import (
+	"fmt"
+	"net/url"
+
 	types "github.com/vmware/govcloudair/types/v56"
 )
 
@@ -21,3 +24,234 @@ func NewVAppTemplate(c Client) *VAppTemplate {
 		c:            c,
 	}
 }
+
+// Refresh retrieves this vApp template again
+func (v *VAppTemplate) Refresh() error {
+
+	if v.VAppTemplate.HREF == "" {
+		return fmt.Errorf("cannot refresh, Object is empty")
+	}
+
+	u, _ := url.ParseRequestURI(v.VAppTemplate.HREF)
+
+	req := v.c.NewRequest(map[string]string{}, types.HTTPGet, u, nil)
+
+	resp, err := checkResp(v.c.DoHTTP(req))
+	if err != nil {
+		return fmt.Errorf("error retrieving vApp template: %s", err)
+	}
+
+	// Empty struct before a new unmarshal, otherwise we end up with duplicate
+	// elements in slices.
+	v.VAppTemplate = &types.VAppTemplate{}
+
+	if err = decodeBody(resp, v.VAppTemplate); err != nil {
+		return fmt.Errorf("error decoding vApp template response: %s", err)
+	}
+
+	// The request was successful
+	return nil
+}
+
+// VAppTemplateVM summarizes a virtual machine of a vApp template
+type VAppTemplateVM struct {
+	Name string
+	HREF string
+	// CPUs is the number of virtual CPUs and MemoryMB the memory size in
+	// megabytes.
+	CPUs     int64
+	MemoryMB int64
+	Disks    []VAppTemplateDisk
+	// Networks lists the network adapters of the VM, in slot order.
+	Networks []*types.NetworkConnection
+}
+
+// VAppTemplateDisk summarizes a hard disk of a vApp template VM
+type VAppTemplateDisk struct {
+	Name       string
+	SizeMB     int64
+	BusType    string
+	BusSubType string
+}
+
+// VMs summarizes the hardware and network connections of the virtual
+// machines of this vApp template, as of its last Refresh
+func (v *VAppTemplate) VMs() []VAppTemplateVM {
+
+	if v.VAppTemplate.Children == nil {
+		return nil
+	}
+
+	vms := make([]VAppTemplateVM, 0, len(v.VAppTemplate.Children.VM))
+	for _, child := range v.VAppTemplate.Children.VM {
+		vm := VAppTemplateVM{Name: child.Name, HREF: child.HREF}
+
+		if child.VirtualHardwareSection != nil {
+			for _, item := range child.VirtualHardwareSection.Item {
+				switch item.ResourceType {
+				case types.ResourceTypeProcessor:
+					vm.CPUs = item.VirtualQuantity
+				case types.ResourceTypeMemory:
+					vm.MemoryMB = item.VirtualQuantity
+				case types.ResourceTypeDisk:
+					disk := VAppTemplateDisk{Name: item.ElementName}
+					if len(item.HostResource) > 0 {
+						disk.SizeMB = item.HostResource[0].Capacity
+						disk.BusType = item.HostResource[0].BusType
+						disk.BusSubType = item.HostResource[0].BusSubType
+					}
+					vm.Disks = append(vm.Disks, disk)
+				}
+			}
+		}
+
+		if child.NetworkConnectionSection != nil {
+			vm.Networks = child.NetworkConnectionSection.NetworkConnection
+		}
+
+		vms = append(vms, vm)
+	}
+
+	return vms
+}
+
+// sectionURL returns the URL of the named section of this vApp template
+func (v *VAppTemplate) sectionURL(section string) (*url.URL, error) {
+
+	s, err := url.ParseRequestURI(v.VAppTemplate.HREF)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing vApp template href: %s", err)
+	}
+	s.Path += "/" + section + "/"
+
+	return s, nil
+}
+
+// GetCustomizationSection retrieves the customization settings of this vApp
+// template
+func (v *VAppTemplate) GetCustomizationSection() (*types.CustomizationSection, error) {
+
+	s, err := v.sectionURL("customizationSection")
+	if err != nil {
+		return nil, err
+	}
+
+	req := v.c.NewRequest(map[string]string{}, types.HTTPGet, s, nil)
+
+	resp, err := checkResp(v.c.DoHTTP(req))
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving customization section: %s", err)
+	}
+
+	section := new(types.CustomizationSection)
+
+	if err = decodeBody(resp, section); err != nil {
+		return nil, fmt.Errorf("error decoding customization section response: %s", err)
+	}
+
+	// The request was successful
+	return section, nil
+}
+
+// SetCustomizationSection updates the customization settings of this vApp
+// template. When customizeOnInstantiate is true the VMs of vApps instantiated
+// from this template are customized, goldMaster flags this template as a
+// gold master.
+func (v *VAppTemplate) SetCustomizationSection(customizeOnInstantiate, goldMaster bool) (Task, error) {
+
+	s, err := v.sectionURL("customizationSection")
+	if err != nil {
+		return Task{}, err
+	}
+
+	section := &types.CustomizationSection{
+		Ovf:                    types.NsOvf,
+		Xmlns:                  types.NsVCloud,
+		Info:                   "VApp template customization section",
+		GoldMaster:             goldMaster,
+		CustomizeOnInstantiate: customizeOnInstantiate,
+	}
+
+	task, err := executeTaskRequest(v.c, types.HTTPPut, s, types.MimeCustomizationSection, section)
+	if err != nil {
+		return Task{}, fmt.Errorf("error updating customization section: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
+
+// GetLeaseSettings retrieves the storage lease settings of this vApp template
+func (v *VAppTemplate) GetLeaseSettings() (*types.LeaseSettingsSection, error) {
+
+	s, err := v.sectionURL("leaseSettingsSection")
+	if err != nil {
+		return nil, err
+	}
+
+	req := v.c.NewRequest(map[string]string{}, types.HTTPGet, s, nil)
+
+	resp, err := checkResp(v.c.DoHTTP(req))
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving lease settings: %s", err)
+	}
+
+	section := new(types.LeaseSettingsSection)
+
+	if err = decodeBody(resp, section); err != nil {
+		return nil, fmt.Errorf("error decoding lease settings response: %s", err)
+	}
+
+	// The request was successful
+	return section, nil
+}
+
+// SetLeaseSettings updates the storage lease of this vApp template, a value
+// of 0 means the lease never expires. vApp templates have no runtime lease,
+// so the request leaves it out.
+func (v *VAppTemplate) SetLeaseSettings(storageLeaseSeconds int) (Task, error) {
+
+	s, err := v.sectionURL("leaseSettingsSection")
+	if err != nil {
+		return Task{}, err
+	}
+
+	section := &types.LeaseSettingsSection{
+		Ovf:                   types.NsOvf,
+		Xmlns:                 types.NsVCloud,
+		Info:                  "Lease settings section",
+		StorageLeaseInSeconds: &storageLeaseSeconds,
+	}
+
+	task, err := executeTaskRequest(v.c, types.HTTPPut, s, types.MimeLeaseSettingsSection, section)
+	if err != nil {
+		return Task{}, fmt.Errorf("error updating lease settings: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
+
+// Delete deletes this vApp template, along with the catalog item referring
+// to it
+func (v *VAppTemplate) Delete() (Task, error) {
+
+	var s *url.URL
+	var err error
+	if l := v.VAppTemplate.Link.Find(func(l *types.Link) bool { return l.Rel == types.RelRemove }); l != nil {
+		s, err = url.ParseRequestURI(l.HREF)
+	} else {
+		s, err = url.ParseRequestURI(v.VAppTemplate.HREF)
+	}
+	if err != nil {
+		return Task{}, fmt.Errorf("error parsing vApp template href: %s", err)
+	}
+
+	task, err := executeTaskRequest(v.c, types.HTTPDelete, s, "", nil)
+	if err != nil {
+		return Task{}, fmt.Errorf("error deleting vApp template: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
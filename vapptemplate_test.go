@@ -4,6 +4,128 @@
 
 package govcloudair
 
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+func Test_VAppTemplateVMs(t *testing.T) {
+
+	vapptemplate := NewVAppTemplate(nil)
+	assert.Empty(t, vapptemplate.VMs())
+
+	if !assert.NoError(t, xml.Unmarshal([]byte(strings.TrimSpace(vapptemplateExample)), vapptemplate.VAppTemplate)) {
+		return
+	}
+
+	vms := vapptemplate.VMs()
+	if assert.Len(t, vms, 1) {
+		vm := vms[0]
+		assert.Equal(t, "CentOS64-32bit", vm.Name)
+		assert.Equal(t, "http://localhost:4444/api/vAppTemplate/vm-3a3934d2-1f3f-4782-a911-f143da27e88c", vm.HREF)
+		assert.Equal(t, int64(1), vm.CPUs)
+		assert.Equal(t, int64(1024), vm.MemoryMB)
+		assert.Equal(t, []VAppTemplateDisk{{Name: "Hard disk 1", SizeMB: 20480, BusType: types.DiskBusTypeSCSI, BusSubType: types.DiskBusSubTypeLsiLogic}}, vm.Disks)
+		if assert.Len(t, vm.Networks, 1) {
+			assert.Equal(t, "none", vm.Networks[0].Network)
+			assert.Equal(t, "00:50:56:02:00:39", vm.Networks[0].MACAddress)
+		}
+	}
+}
+
+func Test_VAppTemplateSettings(t *testing.T) {
+	cc := new(callCounter)
+	var customization types.CustomizationSection
+	var lease types.LeaseSettingsSection
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "PUT":
+			if strings.HasSuffix(r.URL.Path, "/customizationSection/") {
+				xml.NewDecoder(r.Body).Decode(&customization)
+			} else {
+				xml.NewDecoder(r.Body).Decode(&lease)
+			}
+			fallthrough
+		case "DELETE":
+			testHandler(map[string]testResponse{
+				"/api/vAppTemplate/vappTemplate-40cb9721-5f1a-44f9-b5c3-98c5f518c4f5/customizationSection/": {202, nil, taskExample},
+				"/api/vAppTemplate/vappTemplate-40cb9721-5f1a-44f9-b5c3-98c5f518c4f5/leaseSettingsSection/": {202, nil, taskExample},
+				"/api/vAppTemplate/vappTemplate-40cb9721-5f1a-44f9-b5c3-98c5f518c4f5":                       {202, nil, taskExample},
+			}, cc).ServeHTTP(rw, r)
+			return
+		}
+		testHandler(map[string]testResponse{
+			"/api/vAppTemplate/vappTemplate-40cb9721-5f1a-44f9-b5c3-98c5f518c4f5/customizationSection/": {200, nil, customizationSectionExample},
+			"/api/vAppTemplate/vappTemplate-40cb9721-5f1a-44f9-b5c3-98c5f518c4f5/leaseSettingsSection/": {200, nil, templateLeaseSettingsSectionExample},
+		}, cc).ServeHTTP(rw, r)
+	})
+
+	ctx, err := setupTestContext(authHandler(handler))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	vapptemplate := NewVAppTemplate(ctx.Client)
+	vapptemplate.VAppTemplate.HREF = ctx.Server.URL + "/api/vAppTemplate/vappTemplate-40cb9721-5f1a-44f9-b5c3-98c5f518c4f5"
+
+	section, err := vapptemplate.GetCustomizationSection()
+	if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+		assert.True(t, section.CustomizeOnInstantiate)
+		assert.False(t, section.GoldMaster)
+	}
+
+	task, err := vapptemplate.SetCustomizationSection(false, true)
+	if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+		assert.Equal(t, "success", task.Task.Status)
+		assert.False(t, customization.CustomizeOnInstantiate)
+		assert.True(t, customization.GoldMaster)
+	}
+
+	settings, err := vapptemplate.GetLeaseSettings()
+	if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+		if assert.NotNil(t, settings.StorageLeaseInSeconds) {
+			assert.Equal(t, 7776000, *settings.StorageLeaseInSeconds)
+		}
+		assert.Equal(t, "2015-02-08T10:40:09.943+01:00", settings.StorageLeaseExpiration)
+	}
+
+	_, err = vapptemplate.SetLeaseSettings(3600)
+	if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+		if assert.NotNil(t, lease.StorageLeaseInSeconds) {
+			assert.Equal(t, 3600, *lease.StorageLeaseInSeconds)
+		}
+		assert.Nil(t, lease.DeploymentLeaseInSeconds)
+	}
+
+	_, err = vapptemplate.Delete()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, cc.Pop())
+}
+
+var customizationSectionExample = `
+<?xml version="1.0" encoding="UTF-8"?>
+<CustomizationSection xmlns="http://www.vmware.com/vcloud/v1.5" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" goldMaster="false" href="http://localhost:4444/api/vAppTemplate/vappTemplate-40cb9721-5f1a-44f9-b5c3-98c5f518c4f5/customizationSection/" type="application/vnd.vmware.vcloud.customizationSection+xml" ovf:required="false">
+    <ovf:Info>VApp template customization section</ovf:Info>
+    <CustomizeOnInstantiate>true</CustomizeOnInstantiate>
+    <Link rel="edit" href="http://localhost:4444/api/vAppTemplate/vappTemplate-40cb9721-5f1a-44f9-b5c3-98c5f518c4f5/customizationSection/" type="application/vnd.vmware.vcloud.customizationSection+xml"/>
+</CustomizationSection>
+`
+
+var templateLeaseSettingsSectionExample = `
+<?xml version="1.0" encoding="UTF-8"?>
+<LeaseSettingsSection xmlns="http://www.vmware.com/vcloud/v1.5" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" href="http://localhost:4444/api/vAppTemplate/vappTemplate-40cb9721-5f1a-44f9-b5c3-98c5f518c4f5/leaseSettingsSection/" type="application/vnd.vmware.vcloud.leaseSettingsSection+xml" ovf:required="false">
+    <ovf:Info>Lease settings section</ovf:Info>
+    <Link rel="edit" href="http://localhost:4444/api/vAppTemplate/vappTemplate-40cb9721-5f1a-44f9-b5c3-98c5f518c4f5/leaseSettingsSection/" type="application/vnd.vmware.vcloud.leaseSettingsSection+xml"/>
+    <StorageLeaseExpiration>2015-02-08T10:40:09.943+01:00</StorageLeaseExpiration>
+    <StorageLeaseInSeconds>7776000</StorageLeaseInSeconds>
+</LeaseSettingsSection>
+`
+
 var vapptemplateExample = `
 <?xml version="1.0" encoding="UTF-8"?>
 <VAppTemplate xmlns="http://www.vmware.com/vcloud/v1.5" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vmw="http://www.vmware.com/schema/ovf" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" goldMaster="false" ovfDescriptorUploaded="true" status="8" name="CentOS64-32bit" id="urn:vcloud:vapptemplate:40cb9721-5f1a-44f9-b5c3-98c5f518c4f5" href="http://localhost:4444/api/vAppTemplate/vappTemplate-40cb9721-5f1a-44f9-b5c3-98c5f518c4f5" type="application/vnd.vmware.vcloud.vAppTemplate+xml" xsi:schemaLocation="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2.22.0/CIM_VirtualSystemSettingData.xsd http://www.vmware.com/schema/ovf http://www.vmware.com/schema/ovf http://schemas.dmtf.org/ovf/envelope/1 http://schemas.dmtf.org/ovf/envelope/1/dsp8023_1.1.0.xsd http://www.vmware.com/vcloud/v1.5 http://10.6.32.3/api/v1.5/schema/master.xsd http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2.22.0/CIM_ResourceAllocationSettingData.xsd">
@@ -181,3 +303,18 @@ var vapptemplateExample = `
 </VAppTemplate>
 
 	`
+
+var capturedvapptemplateExample = `
+<?xml version="1.0" encoding="UTF-8"?>
+<VAppTemplate xmlns="http://www.vmware.com/vcloud/v1.5" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" goldMaster="false" ovfDescriptorUploaded="true" status="0" name="golden" id="urn:vcloud:vapptemplate:50cb9721-5f1a-44f9-b5c3-98c5f518c4f5" href="http://localhost:4444/api/vAppTemplate/vappTemplate-50cb9721-5f1a-44f9-b5c3-98c5f518c4f5" type="application/vnd.vmware.vcloud.vAppTemplate+xml">
+    <Link rel="remove" href="http://localhost:4444/api/vAppTemplate/vappTemplate-50cb9721-5f1a-44f9-b5c3-98c5f518c4f5"/>
+    <Description>golden image</Description>
+    <Tasks>
+        <Task cancelRequested="false" href="http://localhost:4444/api/task/1b8f926c-eff5-4bea-9b13-4e49bdd50c05" id="urn:vcloud:task:1b8f926c-eff5-4bea-9b13-4e49bdd50c05" name="task" operation="Capturing Virtual Application Template golden(50cb9721-5f1a-44f9-b5c3-98c5f518c4f5)" operationName="vdcCaptureTemplate" serviceNamespace="com.vmware.vcloud" startTime="2014-11-10T09:51:28.717Z" status="running" type="application/vnd.vmware.vcloud.task+xml">
+            <Owner href="http://localhost:4444/api/vAppTemplate/vappTemplate-50cb9721-5f1a-44f9-b5c3-98c5f518c4f5" name="golden" type="application/vnd.vmware.vcloud.vAppTemplate+xml"/>
+            <Progress>1</Progress>
+        </Task>
+    </Tasks>
+    <DateCreated>2014-11-10T09:51:28.000Z</DateCreated>
+</VAppTemplate>
+`
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
 	types "github.com/vmware/govcloudair/types/v56"
 )
@@ -285,3 +286,56 @@ func (v *Vdc) FindVAppByID(vappid string) (VApp, error) {
 	return VApp{}, fmt.Errorf("can't find vApp")
 
 }
+
+// FindVAppsWithExpiringLease finds the vApps in this VDC whose runtime or
+// storage lease expires within the given window from now. Expired leases are
+// included.
+func (v *Vdc) FindVAppsWithExpiringLease(window time.Duration) ([]VApp, error) {
+
+	err := v.Refresh()
+	if err != nil {
+		return nil, fmt.Errorf("error refreshing vdc: %s", err)
+	}
+
+	deadline := time.Now().Add(window)
+	vapps := []VApp{}
+
+	for _, resents := range v.Vdc.ResourceEntities {
+		for _, resent := range resents.ResourceEntity {
+
+			if resent.Type != types.MimeVApp {
+				continue
+			}
+
+			u, err := url.ParseRequestURI(resent.HREF)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding vdc response: %s", err)
+			}
+
+			// Querying the VApp
+			req := v.c.NewRequest(map[string]string{}, "GET", u, nil)
+
+			resp, err := checkResp(v.c.DoHTTP(req))
+			if err != nil {
+				return nil, fmt.Errorf("error retrieving vApp: %s", err)
+			}
+
+			newvapp := NewVApp(v.c)
+
+			if err = decodeBody(resp, newvapp.VApp); err != nil {
+				return nil, fmt.Errorf("error decoding vApp response: %s", err)
+			}
+
+			expiration, ok, err := newvapp.LeaseExpiration()
+			if err != nil {
+				return nil, fmt.Errorf("error reading lease of vApp %s: %s", newvapp.VApp.Name, err)
+			}
+
+			if ok && expiration.Before(deadline) {
+				vapps = append(vapps, *newvapp)
+			}
+		}
+	}
+
+	return vapps, nil
+}
@@ -5,7 +5,9 @@
 package govcloudair
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -124,6 +126,43 @@ func Test_FindVApp(t *testing.T) {
 	}
 }
 
+func Test_FindVAppsWithExpiringLease(t *testing.T) {
+	cc := new(callCounter)
+	responses := map[string]testResponse{
+		"/api/vdc/00000000-0000-0000-0000-000000000000":       {200, nil, vdcExample},
+		"/api/vApp/vapp-00000000-0000-0000-0000-000000000000": {200, nil, vappExample},
+	}
+	ctx, err := setupTestContext(authHandler(testHandler(responses, cc)))
+	if assert.NoError(t, err) {
+		// the leases of vappExample never expire
+		vapps, err := ctx.VDC.FindVAppsWithExpiringLease(24 * time.Hour)
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+			assert.Empty(t, vapps)
+		}
+	}
+
+	expiring := strings.Replace(vappExample, "<StorageLeaseInSeconds>0</StorageLeaseInSeconds>",
+		"<StorageLeaseInSeconds>3600</StorageLeaseInSeconds>\n\t    <StorageLeaseExpiration>"+time.Now().Add(time.Hour).Format(time.RFC3339)+"</StorageLeaseExpiration>", 1)
+
+	cc = new(callCounter)
+	responses = map[string]testResponse{
+		"/api/vdc/00000000-0000-0000-0000-000000000000":       {200, nil, vdcExample},
+		"/api/vApp/vapp-00000000-0000-0000-0000-000000000000": {200, nil, expiring},
+	}
+	ctx, err = setupTestContext(authHandler(testHandler(responses, cc)))
+	if assert.NoError(t, err) {
+		vapps, err := ctx.VDC.FindVAppsWithExpiringLease(30 * time.Minute)
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+			assert.Empty(t, vapps)
+		}
+
+		vapps, err = ctx.VDC.FindVAppsWithExpiringLease(24 * time.Hour)
+		if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) && assert.Len(t, vapps, 1) {
+			assert.Equal(t, "Test API GO4", vapps[0].VApp.Name)
+		}
+	}
+}
+
 var vdcExample = `
 	<?xml version="1.0" ?>
 	<Vdc href="http://localhost:4444/api/vdc/00000000-0000-0000-0000-000000000000" id="urn:vcloud:vdc:00000000-0000-0000-0000-000000000000" name="M916272752-5793" status="1" type="application/vnd.vmware.vcloud.vdc+xml" xmlns="http://www.vmware.com/vcloud/v1.5" xmlns:xsi="http://www.w3.org/2001/XMLSchema-in stance" xsi:schemaLocation="http://www.vmware.com/vcloud/v1.5 http://10.6.32.3/api/v1.5/schema/master.xsd">
@@ -0,0 +1,164 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"fmt"
+	"net/url"
+
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+// VM a virtual machine client
+type VM struct {
+	VM *types.VM
+	c  Client
+}
+
+// NewVM creates a new VM client
+func NewVM(c Client) *VM {
+	return &VM{
+		VM: new(types.VM),
+		c:  c,
+	}
+}
+
+// Refresh refreshes this VM
+func (v *VM) Refresh() error {
+
+	if v.VM.HREF == "" {
+		return fmt.Errorf("cannot refresh, Object is empty")
+	}
+
+	u, _ := url.ParseRequestURI(v.VM.HREF)
+
+	req := v.c.NewRequest(map[string]string{}, "GET", u, nil)
+
+	resp, err := checkResp(v.c.DoHTTP(req))
+	if err != nil {
+		return fmt.Errorf("error retrieving VM: %s", err)
+	}
+
+	// Empty struct before a new unmarshal, otherwise we end up with duplicate
+	// elements in slices.
+	v.VM = &types.VM{}
+
+	if err = decodeBody(resp, v.VM); err != nil {
+		return fmt.Errorf("error decoding VM response: %s", err)
+	}
+
+	// The request was successful
+	return nil
+}
+
+// Undeploy removes the deployment for this VM, powering it off
+func (v *VM) Undeploy() (Task, error) {
+	return v.UndeployWithOptions(UndeployOptions{PowerAction: types.UndeployPowerActionPowerOff})
+}
+
+// UndeployWithOptions removes the deployment for this VM using the provided options
+func (v *VM) UndeployWithOptions(opts UndeployOptions) (Task, error) {
+
+	task, err := undeploy(v.c, v.VM.HREF, opts)
+	if err != nil {
+		return Task{}, fmt.Errorf("error undeploying VM: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+
+}
+
+// Deploy this VM without powering it on
+func (v *VM) Deploy() (Task, error) {
+	return v.DeployWithOptions(DeployOptions{})
+}
+
+// DeployWithOptions deploys this VM using the provided options
+func (v *VM) DeployWithOptions(opts DeployOptions) (Task, error) {
+
+	task, err := deploy(v.c, v.VM.HREF, opts)
+	if err != nil {
+		return Task{}, fmt.Errorf("error deploying VM: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+
+}
+
+// PowerOn powers this VM on, it fails without a request when the last known
+// state of the VM doesn't allow it
+func (v *VM) PowerOn() (Task, error) {
+
+	if !v.CanPerform(PowerActionPowerOn) {
+		return Task{}, fmt.Errorf("can't power on VM in state %s", types.VAppStatus(v.VM.Status))
+	}
+
+	task, err := powerAction(v.c, v.VM.HREF, PowerActionPowerOn)
+	if err != nil {
+		return Task{}, fmt.Errorf("error powering on VM: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
+
+// PowerOff powers this VM off, it fails without a request when the last
+// known state of the VM doesn't allow it
+func (v *VM) PowerOff() (Task, error) {
+
+	if !v.CanPerform(PowerActionPowerOff) {
+		return Task{}, fmt.Errorf("can't power off VM in state %s", types.VAppStatus(v.VM.Status))
+	}
+
+	task, err := powerAction(v.c, v.VM.HREF, PowerActionPowerOff)
+	if err != nil {
+		return Task{}, fmt.Errorf("error powering off VM: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
+
+// Shutdown shuts the guest of this VM down, it fails without a request when
+// the last known state of the VM doesn't allow it
+func (v *VM) Shutdown() (Task, error) {
+
+	if !v.CanPerform(PowerActionShutdown) {
+		return Task{}, fmt.Errorf("can't shut down VM in state %s", types.VAppStatus(v.VM.Status))
+	}
+
+	task, err := powerAction(v.c, v.VM.HREF, PowerActionShutdown)
+	if err != nil {
+		return Task{}, fmt.Errorf("error shutting down VM: %s", err)
+	}
+
+	// The request was successful
+	return task, nil
+}
+
+// CreateSnapshot creates a snapshot of this VM, replacing any existing one.
+// When memory is true the snapshot includes the memory of a powered on VM,
+// when quiesce is true the guest file system is quiesced first (requires
+// VMware tools).
+func (v *VM) CreateSnapshot(name, description string, memory, quiesce bool) (Task, error) {
+	return createSnapshot(v.c, v.VM.HREF, name, description, memory, quiesce)
+}
+
+// RevertToCurrentSnapshot reverts this VM to its current snapshot
+func (v *VM) RevertToCurrentSnapshot() (Task, error) {
+	return revertToCurrentSnapshot(v.c, v.VM.HREF)
+}
+
+// RemoveAllSnapshots removes all the snapshots of this VM
+func (v *VM) RemoveAllSnapshots() (Task, error) {
+	return removeAllSnapshots(v.c, v.VM.HREF)
+}
+
+// GetSnapshotSection retrieves the snapshot section of this VM
+func (v *VM) GetSnapshotSection() (*types.SnapshotSection, error) {
+	return getSnapshotSection(v.c, v.VM.HREF)
+}
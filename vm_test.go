@@ -0,0 +1,278 @@
+/*
+ * Copyright 2014 VMware, Inc.  All rights reserved.  Licensed under the Apache v2 License.
+ */
+
+package govcloudair
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	types "github.com/vmware/govcloudair/types/v56"
+)
+
+var vmResponses = map[string]testResponse{
+	"/api/vApp/vapp-00000000-0000-0000-0000-000000000000":                              {200, nil, vappExample},
+	"/api/vApp/vm-00000000-0000-0000-0000-000000000000":                                {200, nil, vmExample},
+	"/api/vApp/vm-00000000-0000-0000-0000-000000000000/action/deploy":                  {200, nil, taskExample},
+	"/api/vApp/vm-00000000-0000-0000-0000-000000000000/action/undeploy":                {200, nil, taskExample},
+	"/api/vApp/vm-00000000-0000-0000-0000-000000000000/action/createSnapshot":          {200, nil, taskExample},
+	"/api/vApp/vm-00000000-0000-0000-0000-000000000000/action/revertToCurrentSnapshot": {200, nil, taskExample},
+	"/api/vApp/vm-00000000-0000-0000-0000-000000000000/action/removeAllSnapshots":      {200, nil, taskExample},
+	"/api/vApp/vm-00000000-0000-0000-0000-000000000000/snapshotSection":                {200, nil, snapshotSectionExample},
+}
+
+func Test_FindVMByName(t *testing.T) {
+	cc := new(callCounter)
+	ctx, err := setupTestContext(authHandler(testHandler(vmResponses, cc)))
+	if assert.NoError(t, err) {
+		xmlTxt := strings.Replace(vappExample, "http://localhost:4444", ctx.Server.URL, -1)
+		if assert.NoError(t, xml.Unmarshal([]byte(xmlTxt), ctx.VApp.VApp)) {
+			vm, err := ctx.VApp.FindVMByName("CentOS64-32bit")
+			if assert.NoError(t, err) && assert.Equal(t, 2, cc.Pop()) {
+				assert.Equal(t, "CentOS64-32bit", vm.VM.Name)
+				assert.Equal(t, ctx.Server.URL+"/api/vApp/vm-00000000-0000-0000-0000-000000000000", vm.VM.HREF)
+
+				if assert.NoError(t, vm.Refresh()) {
+					assert.Equal(t, 1, cc.Pop())
+				}
+			}
+
+			_, err = ctx.VApp.FindVMByName("INVALID")
+			assert.Error(t, err)
+		}
+	}
+}
+
+func Test_VMDeployUndeploy(t *testing.T) {
+	cc := new(callCounter)
+	ctx, err := setupTestContext(authHandler(testHandler(vmResponses, cc)))
+	if assert.NoError(t, err) {
+		vm := NewVM(ctx.Client)
+		xmlTxt := strings.Replace(vmExample, "http://localhost:4444", ctx.Server.URL, -1)
+		if assert.NoError(t, xml.Unmarshal([]byte(xmlTxt), vm.VM)) {
+			task, err := vm.DeployWithOptions(DeployOptions{PowerOn: true})
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Equal(t, "success", task.Task.Status)
+			}
+
+			task, err = vm.UndeployWithOptions(UndeployOptions{PowerAction: types.UndeployPowerActionSuspend})
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Equal(t, "success", task.Task.Status)
+			}
+		}
+	}
+}
+
+func Test_VMSnapshots(t *testing.T) {
+	cc := new(callCounter)
+	ctx, err := setupTestContext(authHandler(testHandler(vmResponses, cc)))
+	if assert.NoError(t, err) {
+		vm := NewVM(ctx.Client)
+		xmlTxt := strings.Replace(vmExample, "http://localhost:4444", ctx.Server.URL, -1)
+		if assert.NoError(t, xml.Unmarshal([]byte(xmlTxt), vm.VM)) {
+			task, err := vm.CreateSnapshot("snapshot", "description", true, false)
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Equal(t, "success", task.Task.Status)
+			}
+
+			task, err = vm.RevertToCurrentSnapshot()
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Equal(t, "success", task.Task.Status)
+			}
+
+			task, err = vm.RemoveAllSnapshots()
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				assert.Equal(t, "success", task.Task.Status)
+			}
+
+			section, err := vm.GetSnapshotSection()
+			if assert.NoError(t, err) && assert.Equal(t, 1, cc.Pop()) {
+				if assert.Len(t, section.Snapshot, 1) {
+					assert.Equal(t, "2015-06-16T09:12:45.123Z", section.Snapshot[0].Created)
+					assert.True(t, section.Snapshot[0].PoweredOn)
+					assert.Equal(t, int64(2147483648), section.Snapshot[0].Size)
+				}
+			}
+		}
+	}
+}
+
+var snapshotSectionExample = `
+	<?xml version="1.0" ?>
+	<SnapshotSection href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/snapshotSection" ovf:required="false" type="application/vnd.vmware.vcloud.snapshotSection+xml" xmlns="http://www.vmware.com/vcloud/v1.5" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1">
+	  <ovf:Info>Snapshot information section</ovf:Info>
+	  <Snapshot created="2015-06-16T09:12:45.123Z" poweredOn="true" size="2147483648"/>
+	</SnapshotSection>
+	`
+
+var vmExample = `
+	<?xml version="1.0" ?>
+	<Vm deployed="false" href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000" id="urn:vcloud:vm:00000000-0000-0000-0000-000000000000" name="CentOS64-32bit" needsCustomization="true" nestedHypervisorEnabled="false" status="8" type="application/vnd.vmware.vcloud.vm+xml" xmlns="http://www.vmware.com/vcloud/v1.5" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vmw="http://www.vmware.com/schema/ovf" xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+	  <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/power/action/powerOn" rel="power:powerOn"/>
+	  <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/action/deploy" rel="deploy" type="application/vnd.vmware.vcloud.deployVAppParams+xml"/>
+	  <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000" rel="edit" type="application/vnd.vmware.vcloud.vm+xml"/>
+	  <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000" rel="remove"/>
+	  <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/metadata" rel="down" type="application/vnd.vmware.vcloud.metadata+xml"/>
+	  <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/productSections/" rel="down" type="application/vnd.vmware.vcloud.productSections+xml"/>
+	  <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/metrics/historic" rel="down" type="application/vnd.vmware.vcloud.metrics.historicUsageSpec+xml"/>
+	  <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/metrics/historic" rel="metrics" type="application/vnd.vmware.vcloud.metrics.historicUsageSpec+xml"/>
+	  <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/screen" rel="screen:thumbnail"/>
+	  <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/media/action/insertMedia" rel="media:insertMedia" type="application/vnd.vmware.vcloud.mediaInsertOrEjectParams+xml"/>
+	  <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/media/action/ejectMedia" rel="media:ejectMedia" type="application/vnd.vmware.vcloud.mediaInsertOrEjectParams+xml"/>
+	  <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/disk/action/attach" rel="disk:attach" type="application/vnd.vmware.vcloud.diskAttachOrDetachParams+xml"/>
+	  <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/disk/action/detach" rel="disk:detach" type="application/vnd.vmware.vcloud.diskAttachOrDetachParams+xml"/>
+	  <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/action/upgradeHardwareVersion" rel="upgrade"/>
+	  <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/action/enableNestedHypervisor" rel="enable"/>
+	  <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/action/customizeAtNextPowerOn" rel="customizeAtNextPowerOn"/>
+	  <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/action/createSnapshot" rel="snapshot:create" type="application/vnd.vmware.vcloud.createSnapshotParams+xml"/>
+	  <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/action/reconfigureVm" name="CentOS64-32bit" rel="reconfigureVm" type="application/vnd.vmware.vcloud.vm+xml"/>
+	  <Link href="http://localhost:4444/api/vApp/vapp-00000000-0000-0000-0000-000000000000" rel="up" type="application/vnd.vmware.vcloud.vApp+xml"/>
+	  <Description>id: cts-6.4-32bit</Description>
+	  <ovf:VirtualHardwareSection ovf:transport="" vcloud:href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/virtualHardwareSection/" vcloud:type="application/vnd.vmware.vcloud.virtualHardwareSection+xml" xmlns:vcloud="http://www.vmware.com/vcloud/v1.5">
+	    <ovf:Info>Virtual hardware requirements</ovf:Info>
+	    <ovf:System>
+	      <vssd:ElementName>Virtual Hardware Family</vssd:ElementName>
+	      <vssd:InstanceID>0</vssd:InstanceID>
+	      <vssd:VirtualSystemIdentifier>CentOS64-32bit</vssd:VirtualSystemIdentifier>
+	      <vssd:VirtualSystemType>vmx-09</vssd:VirtualSystemType>
+	    </ovf:System>
+	    <ovf:Item>
+	      <rasd:Address>00:50:56:02:0b:36</rasd:Address>
+	      <rasd:AddressOnParent>0</rasd:AddressOnParent>
+	      <rasd:AutomaticAllocation>false</rasd:AutomaticAllocation>
+	      <rasd:Connection vcloud:ipAddressingMode="NONE" vcloud:primaryNetworkConnection="true">none</rasd:Connection>
+	      <rasd:Description>E1000 ethernet adapter on &quot;none&quot;</rasd:Description>
+	      <rasd:ElementName>Network adapter 0</rasd:ElementName>
+	      <rasd:InstanceID>1</rasd:InstanceID>
+	      <rasd:ResourceSubType>E1000</rasd:ResourceSubType>
+	      <rasd:ResourceType>10</rasd:ResourceType>
+	    </ovf:Item>
+	    <ovf:Item>
+	      <rasd:Address>0</rasd:Address>
+	      <rasd:Description>SCSI Controller</rasd:Description>
+	      <rasd:ElementName>SCSI Controller 0</rasd:ElementName>
+	      <rasd:InstanceID>2</rasd:InstanceID>
+	      <rasd:ResourceSubType>lsilogic</rasd:ResourceSubType>
+	      <rasd:ResourceType>6</rasd:ResourceType>
+	    </ovf:Item>
+	    <ovf:Item>
+	      <rasd:AddressOnParent>0</rasd:AddressOnParent>
+	      <rasd:Description>Hard disk</rasd:Description>
+	      <rasd:ElementName>Hard disk 1</rasd:ElementName>
+	      <rasd:HostResource vcloud:busSubType="lsilogic" vcloud:busType="6" vcloud:capacity="20480" vcloud:storageProfileHref="http://localhost:4444/api/vdcStorageProfile/816409e1-6207-4a1f-bd45-947cd03d6452" vcloud:storageProfileOverrideVmDefault="false"/>
+	      <rasd:InstanceID>2000</rasd:InstanceID>
+	      <rasd:Parent>2</rasd:Parent>
+	      <rasd:ResourceType>17</rasd:ResourceType>
+	    </ovf:Item>
+	    <ovf:Item>
+	      <rasd:Address>1</rasd:Address>
+	      <rasd:Description>IDE Controller</rasd:Description>
+	      <rasd:ElementName>IDE Controller 1</rasd:ElementName>
+	      <rasd:InstanceID>3</rasd:InstanceID>
+	      <rasd:ResourceType>5</rasd:ResourceType>
+	    </ovf:Item>
+	    <ovf:Item>
+	      <rasd:AddressOnParent>0</rasd:AddressOnParent>
+	      <rasd:AutomaticAllocation>false</rasd:AutomaticAllocation>
+	      <rasd:Description>CD/DVD Drive</rasd:Description>
+	      <rasd:ElementName>CD/DVD Drive 1</rasd:ElementName>
+	      <rasd:HostResource/>
+	      <rasd:InstanceID>3002</rasd:InstanceID>
+	      <rasd:Parent>3</rasd:Parent>
+	      <rasd:ResourceType>15</rasd:ResourceType>
+	    </ovf:Item>
+	    <ovf:Item>
+	      <rasd:AddressOnParent>0</rasd:AddressOnParent>
+	      <rasd:AutomaticAllocation>false</rasd:AutomaticAllocation>
+	      <rasd:Description>Floppy Drive</rasd:Description>
+	      <rasd:ElementName>Floppy Drive 1</rasd:ElementName>
+	      <rasd:HostResource/>
+	      <rasd:InstanceID>8000</rasd:InstanceID>
+	      <rasd:ResourceType>14</rasd:ResourceType>
+	    </ovf:Item>
+	    <ovf:Item vcloud:href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/virtualHardwareSection/cpu" vcloud:type="application/vnd.vmware.vcloud.rasdItem+xml">
+	      <rasd:AllocationUnits>hertz * 10^6</rasd:AllocationUnits>
+	      <rasd:Description>Number of Virtual CPUs</rasd:Description>
+	      <rasd:ElementName>1 virtual CPU(s)</rasd:ElementName>
+	      <rasd:InstanceID>4</rasd:InstanceID>
+	      <rasd:Reservation>0</rasd:Reservation>
+	      <rasd:ResourceType>3</rasd:ResourceType>
+	      <rasd:VirtualQuantity>1</rasd:VirtualQuantity>
+	      <rasd:Weight>0</rasd:Weight>
+	      <vmw:CoresPerSocket ovf:required="false">1</vmw:CoresPerSocket>
+	      <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/virtualHardwareSection/cpu" rel="edit" type="application/vnd.vmware.vcloud.rasdItem+xml"/>
+	    </ovf:Item>
+	    <ovf:Item vcloud:href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/virtualHardwareSection/memory" vcloud:type="application/vnd.vmware.vcloud.rasdItem+xml">
+	      <rasd:AllocationUnits>byte * 2^20</rasd:AllocationUnits>
+	      <rasd:Description>Memory Size</rasd:Description>
+	      <rasd:ElementName>1024 MB of memory</rasd:ElementName>
+	      <rasd:InstanceID>5</rasd:InstanceID>
+	      <rasd:Reservation>0</rasd:Reservation>
+	      <rasd:ResourceType>4</rasd:ResourceType>
+	      <rasd:VirtualQuantity>1024</rasd:VirtualQuantity>
+	      <rasd:Weight>0</rasd:Weight>
+	      <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/virtualHardwareSection/memory" rel="edit" type="application/vnd.vmware.vcloud.rasdItem+xml"/>
+	    </ovf:Item>
+	    <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/virtualHardwareSection/" rel="edit" type="application/vnd.vmware.vcloud.virtualHardwareSection+xml"/>
+	    <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/virtualHardwareSection/cpu" rel="down" type="application/vnd.vmware.vcloud.rasdItem+xml"/>
+	    <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/virtualHardwareSection/cpu" rel="edit" type="application/vnd.vmware.vcloud.rasdItem+xml"/>
+	    <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/virtualHardwareSection/memory" rel="down" type="application/vnd.vmware.vcloud.rasdItem+xml"/>
+	    <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/virtualHardwareSection/memory" rel="edit" type="application/vnd.vmware.vcloud.rasdItem+xml"/>
+	    <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/virtualHardwareSection/disks" rel="down" type="application/vnd.vmware.vcloud.rasdItemsList+xml"/>
+	    <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/virtualHardwareSection/disks" rel="edit" type="application/vnd.vmware.vcloud.rasdItemsList+xml"/>
+	    <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/virtualHardwareSection/media" rel="down" type="application/vnd.vmware.vcloud.rasdItemsList+xml"/>
+	    <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/virtualHardwareSection/networkCards" rel="down" type="application/vnd.vmware.vcloud.rasdItemsList+xml"/>
+	    <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/virtualHardwareSection/networkCards" rel="edit" type="application/vnd.vmware.vcloud.rasdItemsList+xml"/>
+	    <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/virtualHardwareSection/serialPorts" rel="down" type="application/vnd.vmware.vcloud.rasdItemsList+xml"/>
+	    <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/virtualHardwareSection/serialPorts" rel="edit" type="application/vnd.vmware.vcloud.rasdItemsList+xml"/>
+	  </ovf:VirtualHardwareSection>
+	  <ovf:OperatingSystemSection ovf:id="36" vcloud:href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/operatingSystemSection/" vcloud:type="application/vnd.vmware.vcloud.operatingSystemSection+xml" vmw:osType="centosGuest" xmlns:vcloud="http://www.vmware.com/vcloud/v1.5">
+	    <ovf:Info>Specifies the operating system installed</ovf:Info>
+	    <ovf:Description>CentOS 4/5/6 (32-bit)</ovf:Description>
+	    <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/operatingSystemSection/" rel="edit" type="application/vnd.vmware.vcloud.operatingSystemSection+xml"/>
+	  </ovf:OperatingSystemSection>
+	  <NetworkConnectionSection href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/networkConnectionSection/" ovf:required="false" type="application/vnd.vmware.vcloud.networkConnectionSection+xml">
+	    <ovf:Info>Specifies the available VM network connections</ovf:Info>
+	    <PrimaryNetworkConnectionIndex>0</PrimaryNetworkConnectionIndex>
+	    <NetworkConnection needsCustomization="true" network="none">
+	      <NetworkConnectionIndex>0</NetworkConnectionIndex>
+	      <IsConnected>false</IsConnected>
+	      <MACAddress>00:50:56:02:0b:36</MACAddress>
+	      <IpAddressAllocationMode>NONE</IpAddressAllocationMode>
+	    </NetworkConnection>
+	    <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/networkConnectionSection/" rel="edit" type="application/vnd.vmware.vcloud.networkConnectionSection+xml"/>
+	  </NetworkConnectionSection>
+	  <GuestCustomizationSection href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/guestCustomizationSection/" ovf:required="false" type="application/vnd.vmware.vcloud.guestCustomizationSection+xml">
+	    <ovf:Info>Specifies Guest OS Customization Settings</ovf:Info>
+	    <Enabled>true</Enabled>
+	    <ChangeSid>false</ChangeSid>
+	    <VirtualMachineId>00000000-0000-0000-0000-000000000000</VirtualMachineId>
+	    <JoinDomainEnabled>false</JoinDomainEnabled>
+	    <UseOrgSettings>false</UseOrgSettings>
+	    <AdminPasswordEnabled>true</AdminPasswordEnabled>
+	    <AdminPasswordAuto>true</AdminPasswordAuto>
+	    <AdminAutoLogonEnabled>false</AdminAutoLogonEnabled>
+	    <AdminAutoLogonCount>0</AdminAutoLogonCount>
+	    <ResetPasswordRequired>true</ResetPasswordRequired>
+	    <ComputerName>cts-6.4-32bit</ComputerName>
+	    <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/guestCustomizationSection/" rel="edit" type="application/vnd.vmware.vcloud.guestCustomizationSection+xml"/>
+	  </GuestCustomizationSection>
+	  <RuntimeInfoSection vcloud:href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/runtimeInfoSection" vcloud:type="application/vnd.vmware.vcloud.virtualHardwareSection+xml" xmlns:vcloud="http://www.vmware.com/vcloud/v1.5">
+	    <ovf:Info>Specifies Runtime info</ovf:Info>
+	    <VMWareTools version="9283"/>
+	  </RuntimeInfoSection>
+	  <SnapshotSection href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/snapshotSection" ovf:required="false" type="application/vnd.vmware.vcloud.snapshotSection+xml">
+	    <ovf:Info>Snapshot information section</ovf:Info>
+	  </SnapshotSection>
+	  <VAppScopedLocalId>CentOS64-32bit</VAppScopedLocalId>
+	  <VmCapabilities href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/vmCapabilities/" type="application/vnd.vmware.vcloud.vmCapabilitiesSection+xml">
+	    <Link href="http://localhost:4444/api/vApp/vm-00000000-0000-0000-0000-000000000000/vmCapabilities/" rel="edit" type="application/vnd.vmware.vcloud.vmCapabilitiesSection+xml"/>
+	    <MemoryHotAddEnabled>false</MemoryHotAddEnabled>
+	    <CpuHotAddEnabled>false</CpuHotAddEnabled>
+	  </VmCapabilities>
+	  <StorageProfile href="http://localhost:4444/api/vdcStorageProfile/816409e1-6207-4a1f-bd45-947cd03d6452" name="SSD-Accelerated" type="application/vnd.vmware.vcloud.vdcStorageProfile+xml"/>
+	</Vm>
+	`